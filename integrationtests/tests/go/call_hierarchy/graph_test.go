@@ -0,0 +1,91 @@
+package callhierarchy_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/integrationtests/tests/common"
+	"github.com/isaacphi/mcp-language-server/integrationtests/tests/go/internal"
+	"github.com/isaacphi/mcp-language-server/internal/tools"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
+)
+
+func TestCallGraph(t *testing.T) {
+	suite := internal.GetTestSuite(t)
+
+	ctx, cancel := context.WithTimeout(suite.Context, 10*time.Second)
+	defer cancel()
+
+	// Tell the standard library apart from the workspace, and show
+	// workspace files by their relative paths as the server does
+	utilities.SetWorkspacePaths(suite.WorkspaceDir, true, nil)
+	defer utilities.SetWorkspacePaths("", false, nil)
+
+	tests := []struct {
+		name         string
+		symbolName   string
+		incoming     bool
+		options      tools.CallHierarchyOptions
+		expectedText string
+		snapshotName string
+	}{
+		{
+			name:         "Callees two levels deep without external functions",
+			symbolName:   "ConsumerFunction",
+			options:      tools.CallHierarchyOptions{MaxDepth: 2, ExcludeExternal: true},
+			expectedText: "Excluded calls: 2",
+			snapshotName: "outgoing-depth-2",
+		},
+		{
+			name:         "Callers with call sites",
+			symbolName:   "HelperFunction",
+			incoming:     true,
+			options:      tools.CallHierarchyOptions{MaxDepth: 1, CallSites: true, ContextLines: 1},
+			expectedText: "message := HelperFunction()",
+			snapshotName: "incoming-call-sites",
+		},
+		{
+			name:         "Callees as DOT",
+			symbolName:   "ConsumerFunction",
+			options:      tools.CallHierarchyOptions{MaxDepth: 2, ExcludeExternal: true, Format: tools.CallGraphDot},
+			expectedText: "n1 -> n3;",
+			snapshotName: "outgoing-dot",
+		},
+		{
+			name:         "Callers as Mermaid",
+			symbolName:   "HelperFunction",
+			incoming:     true,
+			options:      tools.CallHierarchyOptions{MaxDepth: 3, Format: tools.CallGraphMermaid},
+			expectedText: "n2 --> n1",
+			snapshotName: "incoming-mermaid",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			query := tools.ParseSymbolQuery(tc.symbolName)
+			var result *tools.CallHierarchyResult
+			var err error
+			if tc.incoming {
+				result, err = tools.GetCallersResult(ctx, suite.Client, query, tc.options)
+			} else {
+				result, err = tools.GetCalleesResult(ctx, suite.Client, query, tc.options)
+			}
+			if err != nil {
+				t.Fatalf("Failed to get call graph: %v", err)
+			}
+
+			text := result.Text()
+			if !strings.Contains(text, tc.expectedText) {
+				t.Errorf("Call graph does not contain expected text: %s", tc.expectedText)
+			}
+			if tc.options.ExcludeExternal && strings.Contains(text, "Println") {
+				t.Errorf("Call graph contains calls to the standard library")
+			}
+
+			common.SnapshotTest(t, "go", "call_hierarchy", tc.snapshotName, text)
+		})
+	}
+}
@@ -3,6 +3,7 @@ package codelens_test
 import (
 	"context"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"testing"
 	"time"
@@ -12,22 +13,32 @@ import (
 	"github.com/isaacphi/mcp-language-server/internal/tools"
 )
 
+// lensIDPattern matches a lens header followed by its title in get_codelens output
+var lensIDPattern = regexp.MustCompile(`\[([0-9a-f-]+)\] Location: Lines \d+-\d+\n    Title: (.*)\n`)
+
+// findLensID returns the ID of the lens with the given title
+func findLensID(t *testing.T, output string, title string) string {
+	t.Helper()
+	for _, match := range lensIDPattern.FindAllStringSubmatch(output, -1) {
+		if match[2] == title {
+			return match[1]
+		}
+	}
+	t.Fatalf("No code lens titled %q in output: %s", title, output)
+	return ""
+}
+
 // TestCodeLens tests the codelens functionality with the Go language server
 func TestCodeLens(t *testing.T) {
-	t.Skip("Remove this line to run codelens tool tests")
-
 	// Test GetCodeLens with a file that should have codelenses
 	t.Run("GetCodeLens", func(t *testing.T) {
 		suite := internal.GetTestSuite(t)
 
-		ctx, cancel := context.WithTimeout(suite.Context, 5*time.Second)
+		ctx, cancel := context.WithTimeout(suite.Context, 10*time.Second)
 		defer cancel()
 
 		// The go.mod fixture already has an unused dependency
 
-		// Wait for LSP to process the file
-		time.Sleep(2 * time.Second)
-
 		// Test GetCodeLens
 		filePath := filepath.Join(suite.WorkspaceDir, "go.mod")
 		result, err := tools.GetCodeLens(ctx, suite.Client, filePath)
@@ -45,6 +56,15 @@ func TestCodeLens(t *testing.T) {
 			t.Errorf("Expected 'tidy' code lens but got: %s", result)
 		}
 
+		// Lens IDs must not change between calls
+		again, err := tools.GetCodeLens(ctx, suite.Client, filePath)
+		if err != nil {
+			t.Fatalf("Second GetCodeLens failed: %v", err)
+		}
+		if findLensID(t, result, "Run go mod tidy") != findLensID(t, again, "Run go mod tidy") {
+			t.Errorf("Lens IDs changed between calls:\n%s\n%s", result, again)
+		}
+
 		common.SnapshotTest(t, "go", "codelens", "get", result)
 	})
 
@@ -56,35 +76,24 @@ func TestCodeLens(t *testing.T) {
 		defer cancel()
 
 		// The go.mod fixture already has an unused dependency
-		// Wait for LSP to process the file
-		time.Sleep(2 * time.Second)
 
-		// First get the code lenses to find the right index
+		// First get the code lenses to find the right ID
 		filePath := filepath.Join(suite.WorkspaceDir, "go.mod")
 		result, err := tools.GetCodeLens(ctx, suite.Client, filePath)
 		if err != nil {
 			t.Fatalf("GetCodeLens failed: %v", err)
 		}
 
-		// Make sure we have a code lens with "tidy" in it
-		if !strings.Contains(strings.ToLower(result), "tidy") {
-			t.Fatalf("Expected 'tidy' code lens but none found: %s", result)
-		}
+		lensID := findLensID(t, result, "Run go mod tidy")
 
-		// Typically, the tidy lens should be index 2 (1-based) for gopls, but let's log for debugging
-		t.Logf("Code lenses: %s", result)
-
-		// Execute the code lens (use index 2 which should be the tidy lens)
-		execResult, err := tools.ExecuteCodeLens(ctx, suite.Client, filePath, 2)
+		// Execute the tidy lens
+		execResult, err := tools.ExecuteCodeLens(ctx, suite.Client, filePath, lensID)
 		if err != nil {
 			t.Fatalf("ExecuteCodeLens failed: %v", err)
 		}
 
 		t.Logf("ExecuteCodeLens result: %s", execResult)
 
-		// Wait for LSP to update the file
-		time.Sleep(3 * time.Second)
-
 		// Check if the file was updated (dependency should be removed)
 		updatedContent, err := suite.ReadFile("go.mod")
 		if err != nil {
@@ -98,4 +107,20 @@ func TestCodeLens(t *testing.T) {
 
 		common.SnapshotTest(t, "go", "codelens", "execute", execResult)
 	})
+
+	t.Run("UnknownLensID", func(t *testing.T) {
+		suite := internal.GetTestSuite(t)
+
+		ctx, cancel := context.WithTimeout(suite.Context, 10*time.Second)
+		defer cancel()
+
+		filePath := filepath.Join(suite.WorkspaceDir, "go.mod")
+		_, err := tools.ExecuteCodeLens(ctx, suite.Client, filePath, "00000000")
+		if err == nil {
+			t.Fatalf("Expected an error for an unknown lens ID")
+		}
+		if !strings.Contains(err.Error(), "get_codelens") {
+			t.Errorf("Expected error to point at get_codelens but got: %v", err)
+		}
+	})
 }
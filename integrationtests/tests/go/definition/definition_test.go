@@ -98,3 +98,77 @@ func TestReadDefinition(t *testing.T) {
 		})
 	}
 }
+
+// TestReadDefinitionResult tests the structured result behind the definition tool
+func TestReadDefinitionResult(t *testing.T) {
+	suite := internal.GetTestSuite(t)
+
+	ctx, cancel := context.WithTimeout(suite.Context, 10*time.Second)
+	defer cancel()
+
+	result, err := tools.ReadDefinitionResult(ctx, suite.Client, tools.ParseSymbolQuery("TestStruct.Method"))
+	if err != nil {
+		t.Fatalf("Failed to read definition: %v", err)
+	}
+
+	if len(result.Definitions) != 1 {
+		t.Fatalf("Expected 1 definition, got %d: %+v", len(result.Definitions), result.Definitions)
+	}
+
+	definition := result.Definitions[0]
+	if definition.Kind != "Method" {
+		t.Errorf("Expected kind Method, got %q", definition.Kind)
+	}
+	if !strings.HasSuffix(definition.Location.File, "clean.go") {
+		t.Errorf("Expected definition in clean.go, got %s", definition.Location.File)
+	}
+	if definition.Location.Range.Start.Line < 1 || definition.Location.Range.End.Line < definition.Location.Range.Start.Line {
+		t.Errorf("Unexpected range %s", definition.Location.Range)
+	}
+	if !strings.HasPrefix(definition.Source, "func (t *TestStruct) Method()") {
+		t.Errorf("Unexpected source: %s", definition.Source)
+	}
+}
+
+// TestReadDefinitionAmbiguous tests that an ambiguous name lists candidates
+// whose IDs pick a single symbol
+func TestReadDefinitionAmbiguous(t *testing.T) {
+	suite := internal.GetTestSuite(t)
+
+	ctx, cancel := context.WithTimeout(suite.Context, 10*time.Second)
+	defer cancel()
+
+	result, err := tools.ReadDefinitionResult(ctx, suite.Client, tools.ParseSymbolQuery("Method"))
+	if err != nil {
+		t.Fatalf("Failed to read definition: %v", err)
+	}
+	if len(result.Definitions) != 0 {
+		t.Errorf("Expected no definitions for an ambiguous name, got %d", len(result.Definitions))
+	}
+
+	var id string
+	for _, candidate := range result.Candidates {
+		if candidate.Name == "SharedStruct.Method" {
+			id = candidate.ID
+		}
+	}
+	if id == "" {
+		t.Fatalf("Expected SharedStruct.Method among the candidates: %+v", result.Candidates)
+	}
+	if !strings.Contains(result.Text(), "["+id+"] Method SharedStruct.Method") {
+		t.Errorf("Expected the candidate in the text output:\n%s", result.Text())
+	}
+
+	query := tools.ParseSymbolQuery("Method")
+	query.ID = id
+	result, err = tools.ReadDefinitionResult(ctx, suite.Client, query)
+	if err != nil {
+		t.Fatalf("Failed to read definition by ID: %v", err)
+	}
+	if len(result.Candidates) != 0 || len(result.Definitions) != 1 {
+		t.Fatalf("Expected a single definition, got %+v", result)
+	}
+	if !strings.HasPrefix(result.Definitions[0].Source, "func (s *SharedStruct) Method()") {
+		t.Errorf("Unexpected source: %s", result.Definitions[0].Source)
+	}
+}
@@ -183,3 +183,40 @@ func HelperFunction(value int) string {
 		common.SnapshotTest(t, "go", "diagnostics", "dependency", result)
 	})
 }
+
+// TestDiagnosticsResult tests the structured result behind the diagnostics tool
+func TestDiagnosticsResult(t *testing.T) {
+	suite := internal.GetTestSuite(t)
+
+	// Wait for diagnostics to be generated
+	time.Sleep(2 * time.Second)
+
+	ctx, cancel := context.WithTimeout(suite.Context, 5*time.Second)
+	defer cancel()
+
+	filePath := filepath.Join(suite.WorkspaceDir, "main.go")
+	result, err := tools.GetDiagnosticsForFileResult(ctx, suite.Client, filePath, 2, false)
+	if err != nil {
+		t.Fatalf("GetDiagnosticsForFileResult failed: %v", err)
+	}
+
+	if result.Snippet != "" {
+		t.Errorf("Expected no snippet without line numbers, got: %s", result.Snippet)
+	}
+
+	found := false
+	for _, diag := range result.Diagnostics {
+		if strings.Contains(diag.Message, "unreachable") {
+			found = true
+			if diag.Severity != "WARNING" {
+				t.Errorf("Expected unreachable code to be a warning, got %s", diag.Severity)
+			}
+			if diag.Range.Start.Line != 8 {
+				t.Errorf("Expected unreachable code on line 8, got %s", diag.Range)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("Expected an unreachable code diagnostic, got: %+v", result.Diagnostics)
+	}
+}
@@ -0,0 +1,63 @@
+package document_symbols_test
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/integrationtests/tests/common"
+	"github.com/isaacphi/mcp-language-server/integrationtests/tests/go/internal"
+	"github.com/isaacphi/mcp-language-server/internal/tools"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
+)
+
+// TestDocumentSymbols tests the symbol outline with the Go language server
+func TestDocumentSymbols(t *testing.T) {
+	suite := internal.GetTestSuite(t)
+
+	ctx, cancel := context.WithTimeout(suite.Context, 10*time.Second)
+	defer cancel()
+
+	filePath := filepath.Join(suite.WorkspaceDir, "types.go")
+	result, err := tools.GetDocumentSymbols(ctx, suite.Client, filePath)
+	if err != nil {
+		t.Fatalf("GetDocumentSymbols failed: %v", err)
+	}
+
+	for _, expected := range []string{"Struct SharedStruct", "Method (*SharedStruct).Method", "Interface SharedInterface"} {
+		if !strings.Contains(result, expected) {
+			t.Errorf("Expected %q in outline but got: %s", expected, result)
+		}
+	}
+
+	common.SnapshotTest(t, "go", "document_symbols", "types", result)
+}
+
+// TestDocumentSymbolsRelativePaths tests workspace-relative paths in input
+// and output
+func TestDocumentSymbolsRelativePaths(t *testing.T) {
+	suite := internal.GetTestSuite(t)
+
+	ctx, cancel := context.WithTimeout(suite.Context, 10*time.Second)
+	defer cancel()
+
+	utilities.SetWorkspacePaths(suite.WorkspaceDir, true, nil)
+	defer utilities.SetWorkspacePaths("", false, nil)
+
+	result, err := tools.GetDocumentSymbols(ctx, suite.Client, "types.go")
+	if err != nil {
+		t.Fatalf("GetDocumentSymbols failed: %v", err)
+	}
+
+	if !strings.HasPrefix(result, "types.go") {
+		t.Errorf("Expected the outline to start with the relative path but got: %s", result)
+	}
+	if strings.Contains(result, suite.WorkspaceDir) {
+		t.Errorf("Expected no absolute workspace paths but got: %s", result)
+	}
+	if !strings.Contains(result, "Struct SharedStruct") {
+		t.Errorf("Expected SharedStruct in outline but got: %s", result)
+	}
+}
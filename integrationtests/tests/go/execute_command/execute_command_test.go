@@ -0,0 +1,161 @@
+package execute_command_test
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/integrationtests/tests/go/internal"
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/tools"
+)
+
+// TestExecuteCommand tests listing and running workspace/executeCommand commands with gopls
+func TestExecuteCommand(t *testing.T) {
+	t.Run("ListCommands", func(t *testing.T) {
+		suite := internal.GetTestSuite(t)
+
+		result, err := tools.ListCommands(suite.Client)
+		if err != nil {
+			t.Fatalf("ListCommands failed: %v", err)
+		}
+
+		if !strings.Contains(result, "gopls.tidy") {
+			t.Errorf("Expected gopls.tidy in command list but got: %s", result)
+		}
+	})
+
+	t.Run("UnsupportedCommand", func(t *testing.T) {
+		suite := internal.GetTestSuite(t)
+
+		ctx, cancel := context.WithTimeout(suite.Context, 5*time.Second)
+		defer cancel()
+
+		_, err := tools.ExecuteCommand(ctx, suite.Client, "gopls.does_not_exist", nil)
+		if err == nil {
+			t.Fatalf("Expected an error for an unsupported command")
+		}
+
+		if !strings.Contains(err.Error(), "list_commands") {
+			t.Errorf("Expected error to point at list_commands but got: %v", err)
+		}
+	})
+
+	// gopls.tidy edits go.mod through workspace/applyEdit, which should be reported
+	t.Run("Tidy", func(t *testing.T) {
+		suite := internal.GetTestSuite(t)
+
+		ctx, cancel := context.WithTimeout(suite.Context, 10*time.Second)
+		defer cancel()
+
+		goModURI := "file://" + filepath.Join(suite.WorkspaceDir, "go.mod")
+		arg, err := json.Marshal(map[string]any{"URIs": []string{goModURI}})
+		if err != nil {
+			t.Fatalf("Failed to marshal arguments: %v", err)
+		}
+
+		result, err := tools.ExecuteCommand(ctx, suite.Client, "gopls.tidy", []json.RawMessage{arg})
+		if err != nil {
+			t.Fatalf("ExecuteCommand failed: %v", err)
+		}
+
+		if !strings.Contains(result, "Executed command: gopls.tidy") {
+			t.Errorf("Expected command confirmation but got: %s", result)
+		}
+
+		if !strings.Contains(result, "Workspace edits requested by the server: 1") {
+			t.Errorf("Expected the go.mod edit to be reported but got: %s", result)
+		}
+
+		updatedContent, err := suite.ReadFile("go.mod")
+		if err != nil {
+			t.Fatalf("Failed to read updated go.mod: %v", err)
+		}
+
+		if strings.Contains(updatedContent, "github.com/stretchr/testify") {
+			t.Errorf("Expected dependency to be removed, but it's still there:\n%s", updatedContent)
+		}
+	})
+
+	// With the queue policy the go.mod edit waits for approval
+	t.Run("TidyQueued", func(t *testing.T) {
+		suite := internal.GetTestSuite(t)
+		suite.Client.SetApplyEditPolicy(lsp.ApplyEditQueue, suite.WorkspaceDir)
+
+		ctx, cancel := context.WithTimeout(suite.Context, 10*time.Second)
+		defer cancel()
+
+		goModURI := "file://" + filepath.Join(suite.WorkspaceDir, "go.mod")
+		arg, err := json.Marshal(map[string]any{"URIs": []string{goModURI}})
+		if err != nil {
+			t.Fatalf("Failed to marshal arguments: %v", err)
+		}
+
+		// gopls reports the command as failed when its edit is not applied
+		_, err = tools.ExecuteCommand(ctx, suite.Client, "gopls.tidy", []json.RawMessage{arg})
+		if err == nil || !strings.Contains(err.Error(), "not applied: queued for approval as pending edit 1") {
+			t.Errorf("Expected the go.mod edit to be queued but got: %v", err)
+		}
+
+		content, err := suite.ReadFile("go.mod")
+		if err != nil {
+			t.Fatalf("Failed to read go.mod: %v", err)
+		}
+		if !strings.Contains(content, "github.com/stretchr/testify") {
+			t.Errorf("go.mod was edited before the edit was approved:\n%s", content)
+		}
+
+		pending, err := tools.PendingEdits(suite.Client, "list", 0)
+		if err != nil {
+			t.Fatalf("PendingEdits failed: %v", err)
+		}
+		if !strings.Contains(pending, "Pending edit 1") || !strings.Contains(pending, "go.mod") {
+			t.Errorf("Expected the queued go.mod edit in the list but got: %s", pending)
+		}
+
+		if _, err := tools.PendingEdits(suite.Client, "approve", 1); err != nil {
+			t.Fatalf("Approving the edit failed: %v", err)
+		}
+
+		content, err = suite.ReadFile("go.mod")
+		if err != nil {
+			t.Fatalf("Failed to read go.mod: %v", err)
+		}
+		if strings.Contains(content, "github.com/stretchr/testify") {
+			t.Errorf("Expected the approved edit to remove the dependency:\n%s", content)
+		}
+		if len(suite.Client.PendingEdits()) != 0 {
+			t.Errorf("Expected no pending edits after approval")
+		}
+	})
+
+	t.Run("TidyDenied", func(t *testing.T) {
+		suite := internal.GetTestSuite(t)
+		suite.Client.SetApplyEditPolicy(lsp.ApplyEditDeny, suite.WorkspaceDir)
+
+		ctx, cancel := context.WithTimeout(suite.Context, 10*time.Second)
+		defer cancel()
+
+		goModURI := "file://" + filepath.Join(suite.WorkspaceDir, "go.mod")
+		arg, err := json.Marshal(map[string]any{"URIs": []string{goModURI}})
+		if err != nil {
+			t.Fatalf("Failed to marshal arguments: %v", err)
+		}
+
+		_, err = tools.ExecuteCommand(ctx, suite.Client, "gopls.tidy", []json.RawMessage{arg})
+		if err == nil || !strings.Contains(err.Error(), "not applied: edits requested by the language server are disabled") {
+			t.Errorf("Expected the go.mod edit to be rejected but got: %v", err)
+		}
+
+		content, err := suite.ReadFile("go.mod")
+		if err != nil {
+			t.Fatalf("Failed to read go.mod: %v", err)
+		}
+		if !strings.Contains(content, "github.com/stretchr/testify") {
+			t.Errorf("go.mod was edited despite the deny policy:\n%s", content)
+		}
+	})
+}
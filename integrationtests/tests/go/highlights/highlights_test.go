@@ -0,0 +1,75 @@
+package highlights_test
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/integrationtests/tests/common"
+	"github.com/isaacphi/mcp-language-server/integrationtests/tests/go/internal"
+	"github.com/isaacphi/mcp-language-server/internal/tools"
+)
+
+// TestHighlights tests the occurrences of a symbol within its file
+func TestHighlights(t *testing.T) {
+	tests := []struct {
+		name          string
+		file          string
+		line          int
+		column        int
+		expectedKinds []string
+		snapshotName  string
+	}{
+		{
+			name:          "LocalVariable",
+			file:          "consumer.go",
+			line:          7,
+			column:        2,
+			expectedKinds: []string{"write", "read"},
+			snapshotName:  "local-variable",
+		},
+		{
+			name:          "StructPointer",
+			file:          "consumer.go",
+			line:          11,
+			column:        2,
+			expectedKinds: []string{"write", "read", "read", "read"},
+			snapshotName:  "struct-pointer",
+		},
+		{
+			name:          "NoSymbol",
+			file:          "consumer.go",
+			line:          3,
+			column:        1,
+			expectedKinds: nil,
+			snapshotName:  "no-symbol",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			suite := internal.GetTestSuite(t)
+
+			ctx, cancel := context.WithTimeout(suite.Context, 5*time.Second)
+			defer cancel()
+
+			filePath := filepath.Join(suite.WorkspaceDir, tt.file)
+			result, err := tools.GetHighlightsResult(ctx, suite.Client, filePath, tt.line, tt.column)
+			if err != nil {
+				t.Fatalf("Failed to get highlights: %v", err)
+			}
+
+			var kinds []string
+			for _, highlight := range result.Highlights {
+				kinds = append(kinds, highlight.Kind)
+			}
+			if strings.Join(kinds, ",") != strings.Join(tt.expectedKinds, ",") {
+				t.Errorf("Expected kinds %v, got %v", tt.expectedKinds, kinds)
+			}
+
+			common.SnapshotTest(t, "go", "highlights", tt.snapshotName, result.Text())
+		})
+	}
+}
@@ -124,3 +124,68 @@ func TestHover(t *testing.T) {
 		})
 	}
 }
+
+// TestSymbolHover tests looking up hover information by symbol name
+func TestSymbolHover(t *testing.T) {
+	tests := []struct {
+		name         string
+		symbolName   string
+		expectedText string
+	}{
+		{
+			name:         "Function",
+			symbolName:   "FooBar",
+			expectedText: "FooBar is a simple function for testing",
+		},
+		{
+			name:         "Method",
+			symbolName:   "SharedStruct.Method",
+			expectedText: "Method is a method of SharedStruct",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			suite := internal.GetTestSuite(t)
+
+			ctx, cancel := context.WithTimeout(suite.Context, 5*time.Second)
+			defer cancel()
+
+			result, err := tools.GetSymbolHover(ctx, suite.Client, tt.symbolName)
+			if err != nil {
+				t.Fatalf("GetSymbolHover failed: %v", err)
+			}
+
+			if !strings.Contains(result, tt.expectedText) {
+				t.Errorf("Expected hover info to contain %q but got: %s", tt.expectedText, result)
+			}
+		})
+	}
+
+	t.Run("Ambiguous", func(t *testing.T) {
+		suite := internal.GetTestSuite(t)
+
+		ctx, cancel := context.WithTimeout(suite.Context, 5*time.Second)
+		defer cancel()
+
+		result, err := tools.GetSymbolHover(ctx, suite.Client, "Method")
+		if err != nil {
+			t.Fatalf("GetSymbolHover failed: %v", err)
+		}
+		if !strings.Contains(result, "Method is ambiguous") || !strings.Contains(result, "SharedStruct.Method") {
+			t.Errorf("Expected the candidates for an ambiguous name but got: %s", result)
+		}
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		suite := internal.GetTestSuite(t)
+
+		ctx, cancel := context.WithTimeout(suite.Context, 5*time.Second)
+		defer cancel()
+
+		_, err := tools.GetSymbolHover(ctx, suite.Client, "NonExistentSymbol")
+		if err == nil {
+			t.Errorf("Expected an error for a missing symbol")
+		}
+	})
+}
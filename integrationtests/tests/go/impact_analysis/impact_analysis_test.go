@@ -0,0 +1,86 @@
+package impactanalysis_test
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/integrationtests/tests/common"
+	"github.com/isaacphi/mcp-language-server/integrationtests/tests/go/internal"
+	"github.com/isaacphi/mcp-language-server/internal/tools"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
+)
+
+// testFile calls HelperFunction directly and SharedStruct.Process through
+// ConsumerFunction
+const testFile = `package main
+
+import "testing"
+
+func TestHelper(t *testing.T) {
+	if HelperFunction() == "" {
+		t.Fail()
+	}
+	ConsumerFunction()
+}
+`
+
+func TestImpactAnalysis(t *testing.T) {
+	suite := internal.GetTestSuite(t)
+
+	ctx, cancel := context.WithTimeout(suite.Context, 10*time.Second)
+	defer cancel()
+
+	utilities.SetWorkspacePaths(suite.WorkspaceDir, true, nil)
+	defer utilities.SetWorkspacePaths("", false, nil)
+
+	if err := suite.WriteFile("helper_test.go", testFile); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+	if err := suite.Client.OpenFile(ctx, filepath.Join(suite.WorkspaceDir, "helper_test.go")); err != nil {
+		t.Fatalf("Failed to open test file: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		symbolName   string
+		expectedText string
+		snapshotName string
+	}{
+		{
+			name:         "Function called by a test",
+			symbolName:   "HelperFunction",
+			expectedText: "helper_test.go (reference, caller: TestHelper)",
+			snapshotName: "function",
+		},
+		{
+			name:         "Method called by a test through another function",
+			symbolName:   "SharedStruct.Process",
+			expectedText: "Distance 2:",
+			snapshotName: "method",
+		},
+		{
+			name:         "Interface with implementations",
+			symbolName:   "SharedInterface",
+			expectedText: "subtype: CustomImplementor",
+			snapshotName: "interface",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := tools.AnalyzeImpact(ctx, suite.Client, tc.symbolName, 3)
+			if err != nil {
+				t.Fatalf("Failed to analyze impact: %v", err)
+			}
+
+			if !strings.Contains(result, tc.expectedText) {
+				t.Errorf("Impact analysis does not contain expected text: %s", tc.expectedText)
+			}
+
+			common.SnapshotTest(t, "go", "impact_analysis", tc.snapshotName, result)
+		})
+	}
+}
@@ -2,6 +2,7 @@ package references_test
 
 import (
 	"context"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -132,3 +133,65 @@ func countFilesInResult(result string) int {
 
 	return len(fileMap)
 }
+
+// TestFindReferencesOptions tests classifying and filtering references
+func TestFindReferencesOptions(t *testing.T) {
+	suite := internal.GetTestSuite(t)
+
+	ctx, cancel := context.WithTimeout(suite.Context, 10*time.Second)
+	defer cancel()
+
+	kinds := func(result *tools.ReferencesResult) map[string][]string {
+		byFile := make(map[string][]string)
+		for _, file := range result.Files {
+			name := file.File[strings.LastIndex(file.File, "/")+1:]
+			for _, ref := range file.References {
+				byFile[name] = append(byFile[name], ref.Kind)
+			}
+		}
+		return byFile
+	}
+
+	t.Run("Classify", func(t *testing.T) {
+		result, err := tools.FindReferencesResult(ctx, suite.Client, tools.ParseSymbolQuery("SharedStruct"), tools.ReferencesOptions{
+			IncludeDeclaration: true,
+			Include:            []string{"types.go"},
+			Classify:           true,
+		})
+		if err != nil {
+			t.Fatalf("Failed to find references: %v", err)
+		}
+
+		expected := map[string][]string{"types.go": {"declaration", "type-use", "type-use", "type-use"}}
+		if got := kinds(result); !reflect.DeepEqual(got, expected) {
+			t.Errorf("Expected %v, got %v", expected, got)
+		}
+	})
+
+	t.Run("Kinds", func(t *testing.T) {
+		result, err := tools.FindReferencesResult(ctx, suite.Client, tools.ParseSymbolQuery("SharedStruct.Name"), tools.ReferencesOptions{
+			Exclude: []string{"another_*.go"},
+			Kinds:   []string{"write"},
+		})
+		if err != nil {
+			t.Fatalf("Failed to find references: %v", err)
+		}
+
+		expected := map[string][]string{"consumer.go": {"write"}}
+		if got := kinds(result); !reflect.DeepEqual(got, expected) {
+			t.Errorf("Expected %v, got %v", expected, got)
+		}
+	})
+
+	t.Run("Call", func(t *testing.T) {
+		result, err := tools.FindReferencesResult(ctx, suite.Client, tools.ParseSymbolQuery("HelperFunction"), tools.ReferencesOptions{
+			Kinds: []string{"call"},
+		})
+		if err != nil {
+			t.Fatalf("Failed to find references: %v", err)
+		}
+		if len(result.Files) < 2 {
+			t.Errorf("Expected calls in at least 2 files, got %v", kinds(result))
+		}
+	})
+}
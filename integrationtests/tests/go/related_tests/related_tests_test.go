@@ -0,0 +1,101 @@
+package relatedtests_test
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/integrationtests/tests/common"
+	"github.com/isaacphi/mcp-language-server/integrationtests/tests/go/internal"
+	"github.com/isaacphi/mcp-language-server/internal/tools"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
+)
+
+// testFile calls HelperFunction directly, through ConsumerFunction and
+// through a helper that is not a test
+const testFile = `package main
+
+import "testing"
+
+func TestHelper(t *testing.T) {
+	if HelperFunction() == "" {
+		t.Fail()
+	}
+}
+
+func TestConsumer(t *testing.T) {
+	ConsumerFunction()
+}
+
+func helper() {
+	AnotherConsumer()
+}
+
+func TestAnother(t *testing.T) {
+	helper()
+}
+`
+
+func TestRelatedTests(t *testing.T) {
+	suite := internal.GetTestSuite(t)
+
+	ctx, cancel := context.WithTimeout(suite.Context, 10*time.Second)
+	defer cancel()
+
+	utilities.SetWorkspacePaths(suite.WorkspaceDir, true, nil)
+	defer utilities.SetWorkspacePaths("", false, nil)
+
+	if err := suite.WriteFile("helper_test.go", testFile); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+	if err := suite.Client.OpenFile(ctx, filepath.Join(suite.WorkspaceDir, "helper_test.go")); err != nil {
+		t.Fatalf("Failed to open test file: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		symbolName   string
+		depth        int
+		expectedText string
+		snapshotName string
+	}{
+		{
+			name:         "Tests at several distances",
+			symbolName:   "HelperFunction",
+			depth:        5,
+			expectedText: "go test . -run '^(TestHelper|TestConsumer|TestAnother)$'",
+			snapshotName: "function",
+		},
+		{
+			name:         "Tests beyond the depth are left out",
+			symbolName:   "HelperFunction",
+			depth:        2,
+			expectedText: "go test . -run '^(TestHelper|TestConsumer)$'",
+			snapshotName: "limited-depth",
+		},
+		{
+			name:         "No tests",
+			symbolName:   "FooBar",
+			depth:        5,
+			expectedText: "No tests call FooBar",
+			snapshotName: "no-tests",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := tools.FindRelatedTests(ctx, suite.Client, tc.symbolName, tc.depth)
+			if err != nil {
+				t.Fatalf("Failed to find related tests: %v", err)
+			}
+
+			if !strings.Contains(result, tc.expectedText) {
+				t.Errorf("Related tests do not contain expected text: %s", tc.expectedText)
+			}
+
+			common.SnapshotTest(t, "go", "related_tests", tc.snapshotName, result)
+		})
+	}
+}
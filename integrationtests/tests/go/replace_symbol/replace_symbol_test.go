@@ -0,0 +1,103 @@
+package replace_symbol_test
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/integrationtests/tests/common"
+	"github.com/isaacphi/mcp-language-server/integrationtests/tests/go/internal"
+	"github.com/isaacphi/mcp-language-server/internal/tools"
+)
+
+// TestReplaceSymbol tests replacing whole definitions by symbol name
+func TestReplaceSymbol(t *testing.T) {
+	t.Run("KeepsDocComment", func(t *testing.T) {
+		suite := internal.GetTestSuite(t)
+
+		ctx, cancel := context.WithTimeout(suite.Context, 10*time.Second)
+		defer cancel()
+
+		newText := "func HelperFunction() string {\n\treturn \"goodbye world\"\n}\n"
+		result, err := tools.ReplaceSymbol(ctx, suite.Client, "HelperFunction", "", newText)
+		if err != nil {
+			t.Fatalf("ReplaceSymbol failed: %v", err)
+		}
+		if !strings.Contains(result, "Lines 4-6 are now lines 4-6") {
+			t.Errorf("Expected the replaced line range in the result but got: %s", result)
+		}
+
+		content, err := suite.ReadFile("helper.go")
+		if err != nil {
+			t.Fatalf("Failed to read helper.go: %v", err)
+		}
+		common.SnapshotTest(t, "go", "replace_symbol", "keeps-doc-comment", content)
+	})
+
+	t.Run("ReplacesDocComment", func(t *testing.T) {
+		suite := internal.GetTestSuite(t)
+
+		ctx, cancel := context.WithTimeout(suite.Context, 10*time.Second)
+		defer cancel()
+
+		filePath := filepath.Join(suite.WorkspaceDir, "types.go")
+		newText := "// Method returns the name with a label\nfunc (s *SharedStruct) Method() string {\n\treturn \"Name: \" + s.Name\n}"
+		_, err := tools.ReplaceSymbol(ctx, suite.Client, "SharedStruct.Method", filePath, newText)
+		if err != nil {
+			t.Fatalf("ReplaceSymbol failed: %v", err)
+		}
+
+		content, err := suite.ReadFile("types.go")
+		if err != nil {
+			t.Fatalf("Failed to read types.go: %v", err)
+		}
+		if strings.Contains(content, "// Method is a method of SharedStruct") {
+			t.Errorf("Expected the old doc comment to be replaced")
+		}
+		if !strings.Contains(content, "}\n\n// Method returns the name with a label\nfunc (s *SharedStruct) Method() string {\n\treturn \"Name: \" + s.Name\n}\n\n// SharedInterface") {
+			t.Errorf("Expected the new definition in place of the old one but got:\n%s", content)
+		}
+	})
+
+	t.Run("Ambiguous", func(t *testing.T) {
+		suite := internal.GetTestSuite(t)
+
+		ctx, cancel := context.WithTimeout(suite.Context, 10*time.Second)
+		defer cancel()
+
+		before, err := suite.ReadFile("types.go")
+		if err != nil {
+			t.Fatalf("Failed to read types.go: %v", err)
+		}
+
+		_, err = tools.ReplaceSymbol(ctx, suite.Client, "Method", "", "func Method() {}")
+		if err == nil {
+			t.Fatalf("Expected an error for an ambiguous symbol")
+		}
+		if !strings.Contains(err.Error(), "resolves to") {
+			t.Errorf("Expected the candidate locations in the error but got: %v", err)
+		}
+
+		after, err := suite.ReadFile("types.go")
+		if err != nil {
+			t.Fatalf("Failed to read types.go: %v", err)
+		}
+		if before != after {
+			t.Errorf("File was modified despite the ambiguous symbol")
+		}
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		suite := internal.GetTestSuite(t)
+
+		ctx, cancel := context.WithTimeout(suite.Context, 10*time.Second)
+		defer cancel()
+
+		_, err := tools.ReplaceSymbol(ctx, suite.Client, "NotARealSymbol", "", "func NotARealSymbol() {}")
+		if err == nil || !strings.Contains(err.Error(), "not found") {
+			t.Errorf("Expected a not found error but got: %v", err)
+		}
+	})
+}
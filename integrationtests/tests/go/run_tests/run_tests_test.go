@@ -0,0 +1,120 @@
+package runtests_test
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/integrationtests/tests/common"
+	"github.com/isaacphi/mcp-language-server/integrationtests/tests/go/internal"
+	"github.com/isaacphi/mcp-language-server/internal/tools"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
+)
+
+// The tests go in a package of their own, as the main package of the
+// workspace does not build
+const calcFile = `package calc
+
+// Scale multiplies a value by a factor
+func Scale(value, factor int) int {
+	return value * factor
+}
+`
+
+const calcTestFile = `package calc
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestScale(t *testing.T) {
+	if Scale(2, 3) != 6 {
+		t.Fail()
+	}
+}
+
+func TestScaleByZero(t *testing.T) {
+	if got := Scale(2, 0); got != 2 {
+		t.Errorf("Scale(2, 0) = %d, want 2", got)
+	}
+}
+
+func ExampleScale() {
+	fmt.Println(Scale(2, 3))
+	// Output: 6
+}
+`
+
+func TestRunTests(t *testing.T) {
+	suite := internal.GetTestSuite(t)
+
+	ctx, cancel := context.WithTimeout(suite.Context, 120*time.Second)
+	defer cancel()
+
+	utilities.SetWorkspacePaths(suite.WorkspaceDir, true, nil)
+	defer utilities.SetWorkspacePaths("", false, nil)
+
+	files := map[string]string{"calc/calc.go": calcFile, "calc/calc_test.go": calcTestFile}
+	for name, content := range files {
+		if err := suite.WriteFile(name, content); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+		if err := suite.Client.OpenFile(ctx, filepath.Join(suite.WorkspaceDir, name)); err != nil {
+			t.Fatalf("Failed to open %s: %v", name, err)
+		}
+	}
+
+	tests := []struct {
+		name         string
+		filePath     string
+		symbolName   string
+		commands     map[string]string
+		expectedText string
+		snapshotName string
+	}{
+		{
+			name:         "Every test of a file",
+			filePath:     "calc/calc_test.go",
+			expectedText: "calc/calc_test.go:16: Scale(2, 0) = 0, want 2",
+			snapshotName: "file",
+		},
+		{
+			name:         "A single test",
+			symbolName:   "TestScale",
+			expectedText: "Ran 1 test for TestScale: 1 passed, 0 failed",
+			snapshotName: "test",
+		},
+		{
+			name:         "The tests calling a function",
+			symbolName:   "Scale",
+			expectedText: "FAIL TestScaleByZero (calc/calc_test.go:14)",
+			snapshotName: "function",
+		},
+		{
+			name:         "Tests without a lens use the configured command",
+			symbolName:   "ExampleScale",
+			commands:     map[string]string{"go": "go test -v {dir} -run '^({pattern})$'"},
+			expectedText: "go test -v ./calc -run '^(ExampleScale)$'",
+			snapshotName: "fallback",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			options := tools.RunTestsOptions{Commands: tc.commands, Timeout: time.Minute, Depth: 5}
+			result, err := tools.RunTests(ctx, suite.Client, tc.filePath, tc.symbolName, options)
+			if err != nil {
+				t.Fatalf("Failed to run tests: %v", err)
+			}
+
+			if !strings.Contains(result, tc.expectedText) {
+				t.Errorf("Test results do not contain expected text: %s\n%s", tc.expectedText, result)
+			}
+
+			common.SnapshotTest(t, "go", "run_tests", tc.snapshotName, result)
+		})
+	}
+}
@@ -2,6 +2,7 @@ package text_edit_test
 
 import (
 	"context"
+	"os"
 	"path/filepath"
 	"strings"
 	"testing"
@@ -10,6 +11,7 @@ import (
 	"github.com/isaacphi/mcp-language-server/integrationtests/tests/common"
 	"github.com/isaacphi/mcp-language-server/integrationtests/tests/go/internal"
 	"github.com/isaacphi/mcp-language-server/internal/tools"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
 )
 
 // TestApplyTextEdits tests the ApplyTextEdits tool with various edit scenarios
@@ -172,7 +174,7 @@ func AnotherFunction() {
 			}
 
 			// Call the ApplyTextEdits tool with the non-URL file path
-			result, err := tools.ApplyTextEdits(ctx, suite.Client, testFilePath, tc.edits)
+			result, err := tools.ApplyTextEdits(ctx, suite.Client, testFilePath, tc.edits, "")
 			if err != nil {
 				t.Fatalf("Failed to apply text edits: %v", err)
 			}
@@ -320,7 +322,7 @@ func NewFunction() {
 			}
 
 			// Call the ApplyTextEdits tool
-			result, err := tools.ApplyTextEdits(ctx, suite.Client, testFilePath, tc.edits)
+			result, err := tools.ApplyTextEdits(ctx, suite.Client, testFilePath, tc.edits, "")
 			if err != nil {
 				t.Fatalf("Failed to apply text edits: %v", err)
 			}
@@ -347,3 +349,295 @@ func NewFunction() {
 		})
 	}
 }
+
+// TestApplyTextEditsConflicts tests that edits are rejected when the file no
+// longer matches the expected hash or text
+func TestApplyTextEditsConflicts(t *testing.T) {
+	suite := internal.GetTestSuite(t)
+
+	ctx, cancel := context.WithTimeout(suite.Context, 10*time.Second)
+	defer cancel()
+
+	testFileName := "conflict_test.go"
+	testFilePath := filepath.Join(suite.WorkspaceDir, testFileName)
+
+	initialContent := `package main
+
+import "fmt"
+
+// ConflictFunction is edited by the agent and by someone else
+func ConflictFunction() {
+	fmt.Println("Original line")
+}
+`
+
+	expectedText := func(text string) *string { return &text }
+
+	t.Run("MatchingExpectations", func(t *testing.T) {
+		if err := suite.WriteFile(testFileName, initialContent); err != nil {
+			t.Fatalf("Failed to reset test file: %v", err)
+		}
+
+		hash, err := tools.FileHash(testFilePath)
+		if err != nil {
+			t.Fatalf("FileHash failed: %v", err)
+		}
+
+		edits := []tools.TextEdit{{
+			StartLine:    7,
+			EndLine:      7,
+			NewText:      `	fmt.Println("Edited line")`,
+			ExpectedText: expectedText(`	fmt.Println("Original line")`),
+		}}
+		result, err := tools.ApplyTextEdits(ctx, suite.Client, testFilePath, edits, hash)
+		if err != nil {
+			t.Fatalf("Expected edits to apply but got: %v", err)
+		}
+
+		newHash, err := tools.FileHash(testFilePath)
+		if err != nil {
+			t.Fatalf("FileHash failed: %v", err)
+		}
+		if !strings.Contains(result, "File hash: "+newHash) {
+			t.Errorf("Expected result to report the new file hash %s but got: %s", newHash, result)
+		}
+	})
+
+	t.Run("StaleHash", func(t *testing.T) {
+		if err := suite.WriteFile(testFileName, initialContent); err != nil {
+			t.Fatalf("Failed to reset test file: %v", err)
+		}
+
+		edits := []tools.TextEdit{{StartLine: 7, EndLine: 7, NewText: `	fmt.Println("Edited line")`}}
+		_, err := tools.ApplyTextEdits(ctx, suite.Client, testFilePath, edits, "0000")
+		if err == nil {
+			t.Fatalf("Expected a conflict for a stale hash")
+		}
+		if !strings.Contains(err.Error(), "File hash: expected 0000") {
+			t.Errorf("Expected a hash conflict report but got: %v", err)
+		}
+
+		content, err := suite.ReadFile(testFileName)
+		if err != nil {
+			t.Fatalf("Failed to read test file: %v", err)
+		}
+		if content != initialContent {
+			t.Errorf("File was modified despite the conflict:\n%s", content)
+		}
+	})
+
+	t.Run("ChangedLines", func(t *testing.T) {
+		// Someone inserted a line above the one the agent wants to edit
+		changedContent := strings.Replace(initialContent, "import", "// A new comment\n\nimport", 1)
+		if err := suite.WriteFile(testFileName, changedContent); err != nil {
+			t.Fatalf("Failed to reset test file: %v", err)
+		}
+
+		edits := []tools.TextEdit{{
+			StartLine:    7,
+			EndLine:      7,
+			NewText:      `	fmt.Println("Edited line")`,
+			ExpectedText: expectedText(`	fmt.Println("Original line")`),
+		}}
+		_, err := tools.ApplyTextEdits(ctx, suite.Client, testFilePath, edits, "")
+		if err == nil {
+			t.Fatalf("Expected a conflict for changed lines")
+		}
+
+		common.SnapshotTest(t, "go", "text_edit", "conflict-changed-lines", err.Error())
+
+		content, err := suite.ReadFile(testFileName)
+		if err != nil {
+			t.Fatalf("Failed to read test file: %v", err)
+		}
+		if content != changedContent {
+			t.Errorf("File was modified despite the conflict:\n%s", content)
+		}
+	})
+}
+
+// TestApplyTextEditsModes tests exact string, regex and symbol anchored edits
+func TestApplyTextEditsModes(t *testing.T) {
+	suite := internal.GetTestSuite(t)
+
+	ctx, cancel := context.WithTimeout(suite.Context, 10*time.Second)
+	defer cancel()
+
+	testFileName := "modes_test.go"
+	testFilePath := filepath.Join(suite.WorkspaceDir, testFileName)
+
+	initialContent := `package main
+
+import "fmt"
+
+// Greeter says hello
+type Greeter struct {
+	Name string
+}
+
+// Greet prints a greeting
+func (g *Greeter) Greet() {
+	fmt.Println("Hello, " + g.Name)
+	fmt.Println("Hello again")
+}
+
+// Farewell prints a goodbye
+func Farewell(name string) {
+	fmt.Println("Goodbye, " + name)
+}
+`
+
+	tests := []struct {
+		name        string
+		edits       []tools.TextEdit
+		wantErr     string
+		wantResult  string
+		wantContent []string
+	}{
+		{
+			name:        "ExactReplace",
+			edits:       []tools.TextEdit{{OldText: `fmt.Println("Hello again")`, NewText: `fmt.Println("Hi again")`}},
+			wantResult:  "Replaced 1 exact text match at line 13",
+			wantContent: []string{`	fmt.Println("Hi again")`},
+		},
+		{
+			name:    "ExactReplaceNotUnique",
+			edits:   []tools.TextEdit{{OldText: `fmt.Println("Hello`, NewText: `fmt.Println("Hi`}},
+			wantErr: "oldText matches 2 times, at lines 12, 13",
+		},
+		{
+			name:        "ExactReplaceAll",
+			edits:       []tools.TextEdit{{OldText: `fmt.Println("Hello`, NewText: `fmt.Println("Hi`, ReplaceAll: true}},
+			wantResult:  "Replaced 2 exact text matches at lines 12, 13",
+			wantContent: []string{`fmt.Println("Hi, " + g.Name)`, `fmt.Println("Hi again")`},
+		},
+		{
+			name:        "RegexCaptureGroups",
+			edits:       []tools.TextEdit{{Pattern: `"(Hello|Goodbye), " \+ (\w+(\.Name)?)`, NewText: `"$1 " + ${2} + "!"`, ReplaceAll: true}},
+			wantResult:  "Replaced 2 regex matches at lines 12, 18",
+			wantContent: []string{`fmt.Println("Hello " + g.Name + "!")`, `fmt.Println("Goodbye " + name + "!")`},
+		},
+		{
+			name:    "RegexNoMatch",
+			edits:   []tools.TextEdit{{Pattern: `Welcome\d+`, NewText: "x"}},
+			wantErr: "pattern not found in file",
+		},
+		{
+			name:        "InsertBeforeSymbol",
+			edits:       []tools.TextEdit{{Symbol: "Farewell", Position: "before", NewText: "// Wave waves\nfunc Wave() {}\n"}},
+			wantResult:  "Inserted 2 lines before Farewell (lines 16-17)",
+			wantContent: []string{"}\n\n// Wave waves\nfunc Wave() {}\n// Farewell prints a goodbye\n"},
+		},
+		{
+			name:        "InsertAfterMethod",
+			edits:       []tools.TextEdit{{Symbol: "Greeter.Greet", Position: "after", NewText: "\n// Hello returns the name\nfunc (g *Greeter) Hello() string { return g.Name }"}},
+			wantResult:  "Inserted 3 lines after Greeter.Greet (lines 15-17)",
+			wantContent: []string{"\tfmt.Println(\"Hello again\")\n}\n\n// Hello returns the name\nfunc (g *Greeter) Hello() string { return g.Name }\n\n// Farewell"},
+		},
+		{
+			name:    "SymbolNotFound",
+			edits:   []tools.TextEdit{{Symbol: "Missing", Position: "after", NewText: "x"}},
+			wantErr: "symbol Missing not found",
+		},
+		{
+			name:    "ConflictingModes",
+			edits:   []tools.TextEdit{{OldText: "Greet", Pattern: "Greet", NewText: "x"}},
+			wantErr: "only one of oldText, pattern and symbol can be set",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := suite.WriteFile(testFileName, initialContent); err != nil {
+				t.Fatalf("Failed to reset test file: %v", err)
+			}
+
+			result, err := tools.ApplyTextEdits(ctx, suite.Client, testFilePath, tc.edits, "")
+
+			content, readErr := suite.ReadFile(testFileName)
+			if readErr != nil {
+				t.Fatalf("Failed to read test file: %v", readErr)
+			}
+
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("Expected error containing %q but got: %v", tc.wantErr, err)
+				}
+				if content != initialContent {
+					t.Errorf("File was modified despite the error:\n%s", content)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("ApplyTextEdits failed: %v", err)
+			}
+			if !strings.Contains(result, tc.wantResult) {
+				t.Errorf("Expected result to contain %q but got: %s", tc.wantResult, result)
+			}
+			for _, want := range tc.wantContent {
+				if !strings.Contains(content, want) {
+					t.Errorf("Expected content to contain %q but got:\n%s", want, content)
+				}
+			}
+		})
+	}
+}
+
+// TestApplyTextEditsSandbox tests that edits are confined to the workspace
+func TestApplyTextEditsSandbox(t *testing.T) {
+	suite := internal.GetTestSuite(t)
+
+	ctx, cancel := context.WithTimeout(suite.Context, 10*time.Second)
+	defer cancel()
+
+	readOnlyRoot := t.TempDir()
+	outsideFile := filepath.Join(readOnlyRoot, "outside.go")
+	if err := os.WriteFile(outsideFile, []byte("package outside\n"), 0644); err != nil {
+		t.Fatalf("Failed to write outside file: %v", err)
+	}
+
+	sandbox, err := utilities.NewSandbox(suite.WorkspaceDir, []string{readOnlyRoot})
+	if err != nil {
+		t.Fatalf("Failed to create sandbox: %v", err)
+	}
+	utilities.SetSandbox(sandbox)
+	defer utilities.SetSandbox(nil)
+
+	edits := []tools.TextEdit{{OldText: "package outside", NewText: "package changed"}}
+
+	tests := []struct {
+		name    string
+		path    string
+		wantErr string
+	}{
+		{"ReadOnlyRoot", outsideFile, "is in the read-only root"},
+		{"OutsideRoots", "/etc/hosts", "is outside the workspace"},
+		{"ParentElements", filepath.Join(suite.WorkspaceDir, "..", "..", "etc", "hosts"), "is outside the workspace"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := tools.ApplyTextEdits(ctx, suite.Client, tc.path, edits, "")
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("Expected error containing %q but got: %v", tc.wantErr, err)
+			}
+		})
+	}
+
+	content, err := os.ReadFile(outsideFile)
+	if err != nil {
+		t.Fatalf("Failed to read outside file: %v", err)
+	}
+	if string(content) != "package outside\n" {
+		t.Errorf("File outside the workspace was modified: %q", content)
+	}
+
+	// Files in read-only roots can still be read
+	if _, err := tools.GetDiagnosticsForFile(ctx, suite.Client, outsideFile, 0, false); err != nil && strings.Contains(err.Error(), "access denied") {
+		t.Errorf("Reading a read-only root was denied: %v", err)
+	}
+	if _, err := tools.GetDiagnosticsForFile(ctx, suite.Client, "/etc/hosts", 0, false); err == nil || !strings.Contains(err.Error(), "access denied") {
+		t.Errorf("Expected reading outside the roots to be denied, got: %v", err)
+	}
+}
@@ -0,0 +1,90 @@
+package lsp
+
+import (
+	"context"
+	"sync"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// AppliedEdit is a workspace/applyEdit request received from the server
+// together with the outcome of applying it
+type AppliedEdit struct {
+	Params        protocol.ApplyWorkspaceEditParams
+	Applied       bool
+	FailureReason string
+}
+
+// ActivityRecorder captures server-initiated traffic while it is active, so
+// that tools can report what a request caused on the server side
+type ActivityRecorder struct {
+	mu       sync.Mutex
+	edits    []AppliedEdit
+	messages []protocol.ShowMessageParams
+}
+
+// StartRecording begins capturing server-initiated edits and messages for
+// the request a tool is about to send. Servers do not say which request
+// caused the traffic they initiate, so recordings never overlap: a caller
+// waits until the previous recording is stopped, and the traffic of one tool
+// call is never reported by another. Callers must call StopRecording when
+// done.
+func (c *Client) StartRecording(ctx context.Context) (*ActivityRecorder, error) {
+	select {
+	case c.recording <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	recorder := &ActivityRecorder{}
+	c.recordersMu.Lock()
+	c.recorder = recorder
+	c.recordersMu.Unlock()
+	return recorder, nil
+}
+
+// StopRecording stops capturing traffic for the given recorder and lets the
+// next recording start
+func (c *Client) StopRecording(recorder *ActivityRecorder) {
+	c.recordersMu.Lock()
+	if c.recorder != recorder {
+		c.recordersMu.Unlock()
+		return
+	}
+	c.recorder = nil
+	c.recordersMu.Unlock()
+	<-c.recording
+}
+
+// Edits returns the workspace edits captured so far
+func (r *ActivityRecorder) Edits() []AppliedEdit {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]AppliedEdit(nil), r.edits...)
+}
+
+// Messages returns the window/showMessage notifications captured so far
+func (r *ActivityRecorder) Messages() []protocol.ShowMessageParams {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]protocol.ShowMessageParams(nil), r.messages...)
+}
+
+func (c *Client) recordEdit(edit AppliedEdit) {
+	c.recordersMu.Lock()
+	defer c.recordersMu.Unlock()
+	if recorder := c.recorder; recorder != nil {
+		recorder.mu.Lock()
+		recorder.edits = append(recorder.edits, edit)
+		recorder.mu.Unlock()
+	}
+}
+
+func (c *Client) recordMessage(msg protocol.ShowMessageParams) {
+	c.recordersMu.Lock()
+	defer c.recordersMu.Unlock()
+	if recorder := c.recorder; recorder != nil {
+		recorder.mu.Lock()
+		recorder.messages = append(recorder.messages, msg)
+		recorder.mu.Unlock()
+	}
+}
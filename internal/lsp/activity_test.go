@@ -0,0 +1,53 @@
+package lsp
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+func TestRecordingsDoNotOverlap(t *testing.T) {
+	client := &Client{recording: make(chan struct{}, 1)}
+	ctx := context.Background()
+
+	first, err := client.StartRecording(ctx)
+	if err != nil {
+		t.Fatalf("StartRecording: %v", err)
+	}
+	client.recordMessage(protocol.ShowMessageParams{Message: "first"})
+
+	started := make(chan *ActivityRecorder)
+	go func() {
+		second, _ := client.StartRecording(ctx)
+		started <- second
+	}()
+	select {
+	case <-started:
+		t.Fatal("a second recording started while the first was active")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	client.StopRecording(first)
+	second := <-started
+	client.recordMessage(protocol.ShowMessageParams{Message: "second"})
+	client.StopRecording(second)
+	client.recordMessage(protocol.ShowMessageParams{Message: "unrecorded"})
+
+	if got := first.Messages(); len(got) != 1 || got[0].Message != "first" {
+		t.Errorf("first recorder got %v, want only its own message", got)
+	}
+	if got := second.Messages(); len(got) != 1 || got[0].Message != "second" {
+		t.Errorf("second recorder got %v, want only its own message", got)
+	}
+
+	// Waiting for a recording gives up with the context
+	busy, _ := client.StartRecording(ctx)
+	defer client.StopRecording(busy)
+	cancelled, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if _, err := client.StartRecording(cancelled); err == nil {
+		t.Error("StartRecording succeeded while another recording was active")
+	}
+}
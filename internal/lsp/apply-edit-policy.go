@@ -0,0 +1,160 @@
+package lsp
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
+)
+
+// ApplyEditPolicy decides what happens to workspace/applyEdit requests
+// from the language server
+type ApplyEditPolicy string
+
+const (
+	// ApplyEditAllow applies every edit, wherever it points
+	ApplyEditAllow ApplyEditPolicy = "allow"
+	// ApplyEditDeny rejects every edit
+	ApplyEditDeny ApplyEditPolicy = "deny"
+	// ApplyEditWorkspace applies edits that stay inside the workspace
+	ApplyEditWorkspace ApplyEditPolicy = "workspace"
+	// ApplyEditQueue keeps edits that stay inside the workspace until they
+	// are approved with ApprovePendingEdit
+	ApplyEditQueue ApplyEditPolicy = "queue"
+)
+
+// ParseApplyEditPolicy validates a policy name
+func ParseApplyEditPolicy(name string) (ApplyEditPolicy, error) {
+	switch policy := ApplyEditPolicy(name); policy {
+	case ApplyEditAllow, ApplyEditDeny, ApplyEditWorkspace, ApplyEditQueue:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("invalid apply edit policy: %s (must be allow, deny, workspace or queue)", name)
+	}
+}
+
+// PendingEdit is a server initiated edit waiting for approval
+type PendingEdit struct {
+	ID       int
+	Received time.Time
+	Params   protocol.ApplyWorkspaceEditParams
+}
+
+// applyEditPolicy holds the policy and the edits queued under it
+type applyEditPolicy struct {
+	mu           sync.Mutex
+	policy       ApplyEditPolicy
+	workspaceDir string
+	pending      []PendingEdit
+	nextID       int
+}
+
+// SetApplyEditPolicy sets how workspace/applyEdit requests are handled.
+// Without a policy every edit is applied.
+func (c *Client) SetApplyEditPolicy(policy ApplyEditPolicy, workspaceDir string) {
+	c.applyEdits.mu.Lock()
+	defer c.applyEdits.mu.Unlock()
+	c.applyEdits.policy = policy
+	c.applyEdits.workspaceDir = workspaceDir
+}
+
+// checkApplyEdit returns why an edit must not be applied under the current
+// policy, or an empty string
+func (c *Client) checkApplyEdit(edit protocol.WorkspaceEdit) string {
+	c.applyEdits.mu.Lock()
+	policy, workspaceDir := c.applyEdits.policy, c.applyEdits.workspaceDir
+	c.applyEdits.mu.Unlock()
+
+	switch policy {
+	case ApplyEditDeny:
+		return "edits requested by the language server are disabled"
+	case ApplyEditWorkspace, ApplyEditQueue:
+		var outside []string
+		for _, path := range utilities.WorkspaceEditPaths(edit) {
+			if err := utilities.CheckPathWithinRoot(workspaceDir, path); err != nil {
+				outside = append(outside, err.Error())
+			}
+		}
+		if len(outside) > 0 {
+			return "edit targets paths outside the workspace: " + strings.Join(outside, "; ")
+		}
+	}
+	return ""
+}
+
+// queueApplyEdit keeps an edit for approval if the policy asks for it, and
+// returns its ID
+func (c *Client) queueApplyEdit(params protocol.ApplyWorkspaceEditParams) (int, bool) {
+	c.applyEdits.mu.Lock()
+	defer c.applyEdits.mu.Unlock()
+	if c.applyEdits.policy != ApplyEditQueue {
+		return 0, false
+	}
+
+	c.applyEdits.nextID++
+	c.applyEdits.pending = append(c.applyEdits.pending, PendingEdit{
+		ID:       c.applyEdits.nextID,
+		Received: time.Now(),
+		Params:   params,
+	})
+	return c.applyEdits.nextID, true
+}
+
+// PendingEdits returns the edits waiting for approval, oldest first
+func (c *Client) PendingEdits() []PendingEdit {
+	c.applyEdits.mu.Lock()
+	defer c.applyEdits.mu.Unlock()
+	return append([]PendingEdit(nil), c.applyEdits.pending...)
+}
+
+// takePendingEdit removes a pending edit from the queue
+func (c *Client) takePendingEdit(id int) (PendingEdit, error) {
+	c.applyEdits.mu.Lock()
+	defer c.applyEdits.mu.Unlock()
+	for i, edit := range c.applyEdits.pending {
+		if edit.ID == id {
+			c.applyEdits.pending = append(c.applyEdits.pending[:i], c.applyEdits.pending[i+1:]...)
+			return edit, nil
+		}
+	}
+	return PendingEdit{}, fmt.Errorf("no pending edit with ID %d", id)
+}
+
+// requeuePendingEdit puts a taken edit back in its place in the queue
+func (c *Client) requeuePendingEdit(edit PendingEdit) {
+	c.applyEdits.mu.Lock()
+	defer c.applyEdits.mu.Unlock()
+	i := 0
+	for i < len(c.applyEdits.pending) && c.applyEdits.pending[i].ID < edit.ID {
+		i++
+	}
+	c.applyEdits.pending = slices.Insert(c.applyEdits.pending, i, edit)
+}
+
+// ApprovePendingEdit applies a queued edit to the current content of the
+// files. The edit stays queued if it can no longer be applied.
+func (c *Client) ApprovePendingEdit(id int) (PendingEdit, error) {
+	edit, err := c.takePendingEdit(id)
+	if err != nil {
+		return PendingEdit{}, err
+	}
+
+	if reason := c.checkApplyEdit(edit.Params.Edit); reason != "" {
+		c.requeuePendingEdit(edit)
+		return edit, fmt.Errorf("%s", reason)
+	}
+	if err := utilities.ApplyWorkspaceEdit(edit.Params.Edit, applyEditDescription(edit.Params)); err != nil {
+		c.requeuePendingEdit(edit)
+		return edit, err
+	}
+	return edit, nil
+}
+
+// RejectPendingEdit drops a queued edit without applying it
+func (c *Client) RejectPendingEdit(id int) (PendingEdit, error) {
+	return c.takePendingEdit(id)
+}
@@ -0,0 +1,83 @@
+package lsp
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
+)
+
+// pendingIDs returns the IDs of the queued edits in order
+func pendingIDs(client *Client) []int {
+	var ids []int
+	for _, edit := range client.PendingEdits() {
+		ids = append(ids, edit.ID)
+	}
+	return ids
+}
+
+// replaceFileEdit replaces the first line of a file with text
+func replaceFileEdit(path, text string) protocol.ApplyWorkspaceEditParams {
+	return protocol.ApplyWorkspaceEditParams{Edit: protocol.WorkspaceEdit{
+		Changes: map[protocol.DocumentUri][]protocol.TextEdit{
+			protocol.URIFromPath(path): {{
+				Range:   protocol.Range{End: protocol.Position{Line: 1}},
+				NewText: text,
+			}},
+		},
+	}}
+}
+
+func TestApprovePendingEditKeepsFailedEdits(t *testing.T) {
+	workspace := t.TempDir()
+	utilities.SetWorkspacePaths(workspace, false, nil)
+	defer utilities.SetWorkspacePaths("", false, nil)
+
+	path := filepath.Join(workspace, "main.go")
+	if err := os.WriteFile(path, []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	client := &Client{}
+	client.SetApplyEditPolicy(ApplyEditQueue, workspace)
+	for _, params := range []protocol.ApplyWorkspaceEditParams{
+		replaceFileEdit(path, "package first\n"),
+		replaceFileEdit(filepath.Join(workspace, "missing.go"), "package missing\n"),
+		replaceFileEdit(path, "package third\n"),
+	} {
+		if _, ok := client.queueApplyEdit(params); !ok {
+			t.Fatal("edit was not queued")
+		}
+	}
+
+	// The edit can no longer be applied to the files
+	if _, err := client.ApprovePendingEdit(2); err == nil {
+		t.Error("edit of a missing file was applied")
+	}
+	if ids := pendingIDs(client); !slices.Equal(ids, []int{1, 2, 3}) {
+		t.Errorf("pending edits after a failed apply = %v, want [1 2 3]", ids)
+	}
+
+	// The policy no longer allows the edit
+	client.SetApplyEditPolicy(ApplyEditDeny, workspace)
+	if _, err := client.ApprovePendingEdit(3); err == nil {
+		t.Error("edit was applied although the policy denies it")
+	}
+	if ids := pendingIDs(client); !slices.Equal(ids, []int{1, 2, 3}) {
+		t.Errorf("pending edits after a denied approval = %v, want [1 2 3]", ids)
+	}
+
+	client.SetApplyEditPolicy(ApplyEditQueue, workspace)
+	if _, err := client.ApprovePendingEdit(3); err != nil {
+		t.Fatalf("ApprovePendingEdit: %v", err)
+	}
+	if ids := pendingIDs(client); !slices.Equal(ids, []int{1, 2}) {
+		t.Errorf("pending edits after approval = %v, want [1 2]", ids)
+	}
+	if content, _ := os.ReadFile(path); string(content) != "package third\n" {
+		t.Errorf("file content = %q", content)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"io"
 	"os"
 	"os/exec"
+	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -44,6 +45,32 @@ type Client struct {
 	// Files are currently opened by the LSP
 	openFiles   map[string]*OpenFileInfo
 	openFilesMu sync.RWMutex
+
+	// Server capabilities from the initialize result
+	capabilities protocol.ServerCapabilities
+
+	// Commands registered dynamically for workspace/executeCommand
+	registeredCommands   map[string][]string
+	registeredCommandsMu sync.RWMutex
+
+	// Policy for edits requested by the server, and the edits it queued
+	applyEdits applyEditPolicy
+
+	// The recorder capturing server-initiated traffic, and a slot held
+	// while one is active so that recordings do not overlap
+	recorder    *ActivityRecorder
+	recordersMu sync.Mutex
+	recording   chan struct{}
+
+	// Work done progress reported by the server
+	progress        map[string]*WorkDoneProgress
+	progressChanged chan struct{}
+	lastProgress    time.Time
+	progressMu      sync.Mutex
+
+	// Resolved code lenses per document
+	codeLenses   map[protocol.DocumentUri]cachedCodeLenses
+	codeLensesMu sync.Mutex
 }
 
 func NewClient(command string, args ...string) (*Client, error) {
@@ -76,6 +103,12 @@ func NewClient(command string, args ...string) (*Client, error) {
 		serverRequestHandlers: make(map[string]ServerRequestHandler),
 		diagnostics:           make(map[protocol.DocumentUri][]protocol.Diagnostic),
 		openFiles:             make(map[string]*OpenFileInfo),
+		registeredCommands:    make(map[string][]string),
+		recording:             make(chan struct{}, 1),
+		progress:              make(map[string]*WorkDoneProgress),
+		progressChanged:       make(chan struct{}),
+		lastProgress:          time.Now(),
+		codeLenses:            make(map[protocol.DocumentUri]cachedCodeLenses),
 	}
 
 	// Start the LSP server process
@@ -134,6 +167,7 @@ func (c *Client) InitializeLSPClient(ctx context.Context, workspaceDir string) (
 			RootURI:  protocol.DocumentUri("file://" + workspaceDir),
 			Capabilities: protocol.ClientCapabilities{
 				Workspace: protocol.WorkspaceClientCapabilities{
+					ApplyEdit:     true,
 					Configuration: true,
 					DidChangeConfiguration: protocol.DidChangeConfigurationClientCapabilities{
 						DynamicRegistration: true,
@@ -142,6 +176,12 @@ func (c *Client) InitializeLSPClient(ctx context.Context, workspaceDir string) (
 						DynamicRegistration:    true,
 						RelativePatternSupport: true,
 					},
+					ExecuteCommand: &protocol.ExecuteCommandClientCapabilities{
+						DynamicRegistration: true,
+					},
+					CodeLens: &protocol.CodeLensWorkspaceClientCapabilities{
+						RefreshSupport: true,
+					},
 				},
 				TextDocument: protocol.TextDocumentClientCapabilities{
 					Synchronization: &protocol.TextDocumentSyncClientCapabilities{
@@ -175,7 +215,9 @@ func (c *Client) InitializeLSPClient(ctx context.Context, workspaceDir string) (
 						Formats:        []protocol.TokenFormat{},
 					},
 				},
-				Window: protocol.WindowClientCapabilities{},
+				Window: protocol.WindowClientCapabilities{
+					WorkDoneProgress: true,
+				},
 			},
 			InitializationOptions: map[string]any{
 				"codelenses": map[string]bool{
@@ -195,16 +237,32 @@ func (c *Client) InitializeLSPClient(ctx context.Context, workspaceDir string) (
 	if err := c.Call(ctx, "initialize", initParams, &result); err != nil {
 		return nil, fmt.Errorf("initialize failed: %w", err)
 	}
+	c.capabilities = result.Capabilities
 
 	if err := c.Initialized(ctx, protocol.InitializedParams{}); err != nil {
 		return nil, fmt.Errorf("initialized failed: %w", err)
 	}
 
 	// Register handlers
-	c.RegisterServerRequestHandler("workspace/applyEdit", HandleApplyEdit)
+	c.RegisterServerRequestHandler("workspace/applyEdit",
+		func(params json.RawMessage) (any, error) { return HandleApplyEdit(c, params) })
 	c.RegisterServerRequestHandler("workspace/configuration", HandleWorkspaceConfiguration)
-	c.RegisterServerRequestHandler("client/registerCapability", HandleRegisterCapability)
-	c.RegisterNotificationHandler("window/showMessage", HandleServerMessage)
+	c.RegisterServerRequestHandler("client/registerCapability",
+		func(params json.RawMessage) (any, error) { return HandleRegisterCapability(c, params) })
+	c.RegisterServerRequestHandler("client/unregisterCapability",
+		func(params json.RawMessage) (any, error) { return HandleUnregisterCapability(c, params) })
+	c.RegisterServerRequestHandler("window/workDoneProgress/create",
+		func(params json.RawMessage) (any, error) { return HandleWorkDoneProgressCreate(c, params) })
+	c.RegisterServerRequestHandler("workspace/codeLens/refresh",
+		func(params json.RawMessage) (any, error) { return HandleCodeLensRefresh(c, params) })
+	c.RegisterServerRequestHandler("window/showMessageRequest",
+		func(params json.RawMessage) (any, error) { return HandleShowMessageRequest(c, params) })
+	c.RegisterNotificationHandler("window/showMessage",
+		func(params json.RawMessage) { HandleServerMessage(c, params) })
+	c.RegisterNotificationHandler("window/logMessage",
+		func(params json.RawMessage) { HandleLogMessage(c, params) })
+	c.RegisterNotificationHandler("$/progress",
+		func(params json.RawMessage) { HandleProgress(c, params) })
 	c.RegisterNotificationHandler("textDocument/publishDiagnostics",
 		func(params json.RawMessage) { HandleDiagnostics(c, params) })
 
@@ -269,9 +327,19 @@ const (
 	StateError
 )
 
+// WaitForServerReady waits until the server has finished the work it reports
+// through $/progress (loading packages, indexing, ...) and has been quiet for a
+// second. Servers that never go idle are given up on after LSP_READY_TIMEOUT_MS.
 func (c *Client) WaitForServerReady(ctx context.Context) error {
-	// TODO: wait for specific messages or poll workspace/symbol
-	time.Sleep(time.Second * 1)
+	readyCtx, cancel := context.WithTimeout(ctx, readyTimeout())
+	defer cancel()
+
+	if err := c.waitForIdle(readyCtx, time.Second); err != nil {
+		if ctx.Err() != nil {
+			return err
+		}
+		lspLogger.Warn("Language server still busy after %v, continuing: %v", readyTimeout(), c.ActiveProgress())
+	}
 	return nil
 }
 
@@ -419,6 +487,39 @@ func (c *Client) CloseAllFiles(ctx context.Context) {
 	lspLogger.Debug("Closed %d files", len(filesToClose))
 }
 
+// ServerCapabilities returns the capabilities the server reported during initialization
+func (c *Client) ServerCapabilities() protocol.ServerCapabilities {
+	return c.capabilities
+}
+
+// ExecuteCommands returns the sorted list of commands the server accepts for
+// workspace/executeCommand, including dynamically registered ones
+func (c *Client) ExecuteCommands() []string {
+	seen := make(map[string]bool)
+	var commands []string
+	add := func(cmds []string) {
+		for _, cmd := range cmds {
+			if !seen[cmd] {
+				seen[cmd] = true
+				commands = append(commands, cmd)
+			}
+		}
+	}
+
+	if provider := c.capabilities.ExecuteCommandProvider; provider != nil {
+		add(provider.Commands)
+	}
+
+	c.registeredCommandsMu.RLock()
+	for _, cmds := range c.registeredCommands {
+		add(cmds)
+	}
+	c.registeredCommandsMu.RUnlock()
+
+	sort.Strings(commands)
+	return commands
+}
+
 func (c *Client) GetFileDiagnostics(uri protocol.DocumentUri) []protocol.Diagnostic {
 	c.diagnosticsMu.RLock()
 	defer c.diagnosticsMu.RUnlock()
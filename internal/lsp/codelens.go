@@ -0,0 +1,77 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// cachedCodeLenses holds resolved code lenses for one version of a document
+type cachedCodeLenses struct {
+	version int32
+	lenses  []protocol.CodeLens
+}
+
+// HandleCodeLensRefresh processes workspace/codeLens/refresh requests by
+// dropping all cached code lenses so the next request fetches fresh ones
+func HandleCodeLensRefresh(client *Client, params json.RawMessage) (any, error) {
+	client.codeLensesMu.Lock()
+	client.codeLenses = make(map[protocol.DocumentUri]cachedCodeLenses)
+	client.codeLensesMu.Unlock()
+
+	lspLogger.Debug("Code lenses invalidated by server refresh")
+	return nil, nil
+}
+
+// ResolvedCodeLenses returns the code lenses for an open document with their
+// commands resolved through codeLens/resolve. Results are cached until the
+// document changes or the server asks for a refresh.
+func (c *Client) ResolvedCodeLenses(ctx context.Context, uri protocol.DocumentUri) ([]protocol.CodeLens, error) {
+	version := c.fileVersion(uri)
+
+	c.codeLensesMu.Lock()
+	cached, ok := c.codeLenses[uri]
+	c.codeLensesMu.Unlock()
+	if ok && cached.version == version {
+		return cached.lenses, nil
+	}
+
+	lenses, err := c.CodeLens(ctx, protocol.CodeLensParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get code lenses: %w", err)
+	}
+
+	for i, lens := range lenses {
+		if lens.Command != nil {
+			continue
+		}
+		resolved, err := c.ResolveCodeLens(ctx, lens)
+		if err != nil {
+			// Keep the unresolved lens, it is still useful to list
+			lspLogger.Warn("Failed to resolve code lens at line %d: %v", lens.Range.Start.Line+1, err)
+			continue
+		}
+		lenses[i] = resolved
+	}
+
+	c.codeLensesMu.Lock()
+	c.codeLenses[uri] = cachedCodeLenses{version: version, lenses: lenses}
+	c.codeLensesMu.Unlock()
+
+	return lenses, nil
+}
+
+// fileVersion returns the version of an open document, or 0 if it is not open
+func (c *Client) fileVersion(uri protocol.DocumentUri) int32 {
+	c.openFilesMu.RLock()
+	defer c.openFilesMu.RUnlock()
+
+	if info, ok := c.openFiles[string(uri)]; ok {
+		return info.Version
+	}
+	return 0
+}
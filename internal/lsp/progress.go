@@ -0,0 +1,216 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// WorkDoneProgress is the latest state of a work done progress reported by the server
+type WorkDoneProgress struct {
+	Token      string
+	Title      string
+	Message    string
+	Percentage *uint32
+}
+
+// workDoneProgressValue covers the begin, report and end payloads of $/progress
+type workDoneProgressValue struct {
+	Kind       string  `json:"kind"`
+	Title      string  `json:"title,omitempty"`
+	Message    string  `json:"message,omitempty"`
+	Percentage *uint32 `json:"percentage,omitempty"`
+}
+
+// ProgressHandler is called when work done progress begins, reports or ends
+type ProgressHandler func(progress WorkDoneProgress, done bool)
+
+// progressHandler holds the current progress handler
+var progressHandler atomic.Pointer[ProgressHandler]
+
+// RegisterProgressHandler registers a handler for work done progress updates
+func RegisterProgressHandler(handler ProgressHandler) {
+	progressHandler.Store(&handler)
+}
+
+// progressPrefixKey is the context key of the prefix given to work done tokens
+type progressPrefixKey struct{}
+
+// WithProgressPrefix makes requests sent with the returned context carry a
+// work done token starting with prefix, so progress the server reports for
+// them can be told apart from other progress
+func WithProgressPrefix(ctx context.Context, prefix string) context.Context {
+	return context.WithValue(ctx, progressPrefixKey{}, prefix)
+}
+
+// withWorkDoneToken returns a copy of params with token as their work done
+// token, if they embed WorkDoneProgressParams and have none yet. Other params
+// are returned unchanged.
+func withWorkDoneToken(params any, token string) any {
+	v := reflect.ValueOf(params)
+	if v.Kind() == reflect.Pointer && !v.IsNil() {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return params
+	}
+	copied := reflect.New(v.Type())
+	copied.Elem().Set(v)
+	request, ok := copied.Interface().(protocol.WorkDoneProgressRequest)
+	if !ok || !request.SetWorkDoneToken(protocol.ProgressToken{Value: token}) {
+		return params
+	}
+	return copied.Interface()
+}
+
+// idleSettleTime is how long the server must stay quiet before it is considered idle
+const idleSettleTime = 200 * time.Millisecond
+
+// HandleWorkDoneProgressCreate processes window/workDoneProgress/create requests
+func HandleWorkDoneProgressCreate(client *Client, params json.RawMessage) (any, error) {
+	var createParams protocol.WorkDoneProgressCreateParams
+	if err := json.Unmarshal(params, &createParams); err != nil {
+		lspLogger.Error("Error unmarshaling progress create params: %v", err)
+		return nil, err
+	}
+
+	lspLogger.Debug("Progress token created: %v", createParams.Token.Value)
+	client.touchProgress()
+	return nil, nil
+}
+
+// HandleProgress processes $/progress notifications and tracks active work
+func HandleProgress(client *Client, params json.RawMessage) {
+	var progressParams struct {
+		Token protocol.ProgressToken `json:"token"`
+		Value json.RawMessage        `json:"value"`
+	}
+	if err := json.Unmarshal(params, &progressParams); err != nil {
+		lspLogger.Error("Error unmarshaling progress params: %v", err)
+		return
+	}
+
+	var value workDoneProgressValue
+	if err := json.Unmarshal(progressParams.Value, &value); err != nil {
+		// Partial result progress is not tracked
+		lspLogger.Debug("Ignoring non work done progress: %v", err)
+		return
+	}
+
+	token := fmt.Sprint(progressParams.Token.Value)
+
+	// Snapshot of the progress after this update, passed to the progress handler
+	var update *WorkDoneProgress
+	done := false
+
+	client.progressMu.Lock()
+	switch value.Kind {
+	case "begin":
+		p := &WorkDoneProgress{
+			Token:      token,
+			Title:      value.Title,
+			Message:    value.Message,
+			Percentage: value.Percentage,
+		}
+		client.progress[token] = p
+		snapshot := *p
+		update = &snapshot
+		lspLogger.Debug("Progress started: %s", value.Title)
+	case "report":
+		if p, ok := client.progress[token]; ok {
+			if value.Message != "" {
+				p.Message = value.Message
+			}
+			if value.Percentage != nil {
+				p.Percentage = value.Percentage
+			}
+			snapshot := *p
+			update = &snapshot
+		}
+	case "end":
+		if p, ok := client.progress[token]; ok {
+			lspLogger.Debug("Progress finished: %s", p.Title)
+			update = &WorkDoneProgress{Token: token, Title: p.Title, Message: value.Message}
+			done = true
+		}
+		delete(client.progress, token)
+	}
+	client.progressMu.Unlock()
+
+	client.touchProgress()
+
+	if handler := progressHandler.Load(); update != nil && handler != nil {
+		(*handler)(*update, done)
+	}
+}
+
+// touchProgress records server activity and wakes up anyone waiting for idle
+func (c *Client) touchProgress() {
+	c.progressMu.Lock()
+	c.lastProgress = time.Now()
+	close(c.progressChanged)
+	c.progressChanged = make(chan struct{})
+	c.progressMu.Unlock()
+}
+
+// ActiveProgress returns the work done progress the server currently reports
+func (c *Client) ActiveProgress() []WorkDoneProgress {
+	c.progressMu.Lock()
+	defer c.progressMu.Unlock()
+
+	active := make([]WorkDoneProgress, 0, len(c.progress))
+	for _, p := range c.progress {
+		active = append(active, *p)
+	}
+	return active
+}
+
+// WaitForIdle blocks until the server has no work in progress and has been
+// quiet for a short settle period
+func (c *Client) WaitForIdle(ctx context.Context) error {
+	return c.waitForIdle(ctx, idleSettleTime)
+}
+
+func (c *Client) waitForIdle(ctx context.Context, settle time.Duration) error {
+	for {
+		c.progressMu.Lock()
+		busy := len(c.progress) > 0
+		quietFor := time.Since(c.lastProgress)
+		changed := c.progressChanged
+		c.progressMu.Unlock()
+
+		if !busy && quietFor >= settle {
+			return nil
+		}
+
+		var wait <-chan time.Time
+		if !busy {
+			wait = time.After(settle - quietFor)
+		}
+
+		select {
+		case <-changed:
+		case <-wait:
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for language server: %w", ctx.Err())
+		}
+	}
+}
+
+// readyTimeout is the longest WaitForServerReady will wait for the server to go idle
+func readyTimeout() time.Duration {
+	timeout := 10 * time.Second
+	if envTimeout := os.Getenv("LSP_READY_TIMEOUT_MS"); envTimeout != "" {
+		if ms, err := strconv.Atoi(envTimeout); err == nil && ms > 0 {
+			timeout = time.Duration(ms) * time.Millisecond
+		}
+	}
+	return timeout
+}
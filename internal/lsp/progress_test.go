@@ -0,0 +1,56 @@
+package lsp
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+func TestWithWorkDoneToken(t *testing.T) {
+	params := protocol.ReferenceParams{}
+	params.TextDocument.URI = "file:///ws/main.go"
+	data, err := json.Marshal(withWorkDoneToken(params, "mcp-call-1/4"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got struct {
+		TextDocument  protocol.TextDocumentIdentifier `json:"textDocument"`
+		WorkDoneToken string                          `json:"workDoneToken"`
+	}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.WorkDoneToken != "mcp-call-1/4" || got.TextDocument.URI != "file:///ws/main.go" {
+		t.Errorf("params with token = %s", data)
+	}
+
+	// A token set by the caller is kept
+	params.WorkDoneToken = protocol.ProgressToken{Value: "own"}
+	data, _ = json.Marshal(withWorkDoneToken(params, "mcp-call-1/5"))
+	if err := json.Unmarshal(data, &got); err != nil || got.WorkDoneToken != "own" {
+		t.Errorf("caller's token replaced: %s", data)
+	}
+
+	// The caller's params are not changed
+	pointer := &protocol.ReferenceParams{}
+	if got, ok := withWorkDoneToken(pointer, "mcp-call-1/6").(*protocol.ReferenceParams); !ok || got.WorkDoneToken.Value != "mcp-call-1/6" {
+		t.Errorf("pointer params with token = %+v", got)
+	}
+	if pointer.WorkDoneToken.Value != nil {
+		t.Errorf("caller's params were changed: %+v", pointer)
+	}
+
+	// Params without WorkDoneProgressParams are left alone
+	lens := protocol.CodeLens{Data: map[string]any{"id": 1}}
+	if got, ok := withWorkDoneToken(lens, "mcp-call-1/7").(protocol.CodeLens); !ok || got.Data == nil {
+		t.Errorf("code lens params changed to %+v", got)
+	}
+	raw := json.RawMessage(`{"command":"gopls.tidy"}`)
+	if got := withWorkDoneToken(raw, "mcp-call-1/8"); string(got.(json.RawMessage)) != string(raw) {
+		t.Errorf("raw params changed to %s", got)
+	}
+	if got := withWorkDoneToken(nil, "mcp-call-1/9"); got != nil {
+		t.Errorf("nil params changed to %v", got)
+	}
+}
@@ -2,6 +2,9 @@ package lsp
 
 import (
 	"encoding/json"
+	"fmt"
+	"strings"
+	"sync/atomic"
 
 	"github.com/isaacphi/mcp-language-server/internal/protocol"
 	"github.com/isaacphi/mcp-language-server/internal/utilities"
@@ -10,12 +13,35 @@ import (
 // FileWatchHandler is called when file watchers are registered by the server
 type FileWatchHandler func(id string, watchers []protocol.FileSystemWatcher)
 
-// fileWatchHandler holds the current file watch handler
-var fileWatchHandler FileWatchHandler
+// fileWatchHandler holds the current file watch handler. Handlers are
+// registered while the server may already be sending requests.
+var fileWatchHandler atomic.Pointer[FileWatchHandler]
 
 // RegisterFileWatchHandler registers a handler for file watcher registrations
 func RegisterFileWatchHandler(handler FileWatchHandler) {
-	fileWatchHandler = handler
+	fileWatchHandler.Store(&handler)
+}
+
+// DiagnosticsHandler is called when the server publishes diagnostics for a document
+type DiagnosticsHandler func(uri protocol.DocumentUri, diagnostics []protocol.Diagnostic)
+
+// diagnosticsHandler holds the current diagnostics handler
+var diagnosticsHandler atomic.Pointer[DiagnosticsHandler]
+
+// RegisterDiagnosticsHandler registers a handler for published diagnostics
+func RegisterDiagnosticsHandler(handler DiagnosticsHandler) {
+	diagnosticsHandler.Store(&handler)
+}
+
+// MessageHandler is called for messages the server asks the client to show or log
+type MessageHandler func(msgType protocol.MessageType, message string)
+
+// messageHandler holds the current message handler
+var messageHandler atomic.Pointer[MessageHandler]
+
+// RegisterMessageHandler registers a handler for server messages
+func RegisterMessageHandler(handler MessageHandler) {
+	messageHandler.Store(&handler)
 }
 
 // Requests
@@ -24,7 +50,7 @@ func HandleWorkspaceConfiguration(params json.RawMessage) (any, error) {
 	return []map[string]any{{}}, nil
 }
 
-func HandleRegisterCapability(params json.RawMessage) (any, error) {
+func HandleRegisterCapability(client *Client, params json.RawMessage) (any, error) {
 	var registerParams protocol.RegistrationParams
 	if err := json.Unmarshal(params, &registerParams); err != nil {
 		lspLogger.Error("Error unmarshaling registration params: %v", err)
@@ -51,23 +77,107 @@ func HandleRegisterCapability(params json.RawMessage) (any, error) {
 			}
 
 			// Notify file watchers
-			if fileWatchHandler != nil {
-				fileWatchHandler(reg.ID, opts.Watchers)
+			if handler := fileWatchHandler.Load(); handler != nil {
+				(*handler)(reg.ID, opts.Watchers)
+			}
+		}
+
+		// Track commands registered for workspace/executeCommand
+		if reg.Method == "workspace/executeCommand" {
+			var opts protocol.ExecuteCommandRegistrationOptions
+			optJson, err := json.Marshal(reg.RegisterOptions)
+			if err != nil {
+				lspLogger.Error("Error marshaling registration options: %v", err)
+				continue
+			}
+
+			if err := json.Unmarshal(optJson, &opts); err != nil {
+				lspLogger.Error("Error unmarshaling registration options: %v", err)
+				continue
 			}
+
+			client.registeredCommandsMu.Lock()
+			client.registeredCommands[reg.ID] = opts.Commands
+			client.registeredCommandsMu.Unlock()
+		}
+	}
+
+	return nil, nil
+}
+
+func HandleUnregisterCapability(client *Client, params json.RawMessage) (any, error) {
+	var unregisterParams protocol.UnregistrationParams
+	if err := json.Unmarshal(params, &unregisterParams); err != nil {
+		lspLogger.Error("Error unmarshaling unregistration params: %v", err)
+		return nil, err
+	}
+
+	for _, unreg := range unregisterParams.Unregisterations {
+		lspLogger.Info("Unregistration received for method: %s, id: %s", unreg.Method, unreg.ID)
+
+		if unreg.Method == "workspace/executeCommand" {
+			client.registeredCommandsMu.Lock()
+			delete(client.registeredCommands, unreg.ID)
+			client.registeredCommandsMu.Unlock()
 		}
 	}
 
 	return nil, nil
 }
 
-func HandleApplyEdit(params json.RawMessage) (any, error) {
+// applyEditDescription describes a server initiated edit in the edit history
+func applyEditDescription(params protocol.ApplyWorkspaceEditParams) string {
+	if params.Label != "" {
+		return "workspace/applyEdit: " + params.Label
+	}
+	return "workspace/applyEdit"
+}
+
+func HandleApplyEdit(client *Client, params json.RawMessage) (any, error) {
 	var workspaceEdit protocol.ApplyWorkspaceEditParams
 	if err := json.Unmarshal(params, &workspaceEdit); err != nil {
 		return protocol.ApplyWorkspaceEditResult{Applied: false}, err
 	}
 
+	lspLogger.Info("Server requested %s of %s", applyEditDescription(workspaceEdit),
+		strings.Join(utilities.WorkspaceEditPaths(workspaceEdit.Edit), ", "))
+
+	// Refuse edits the policy does not allow
+	if reason := client.checkApplyEdit(workspaceEdit.Edit); reason != "" {
+		lspLogger.Warn("Rejected workspace edit: %s", reason)
+		client.recordEdit(AppliedEdit{
+			Params:        workspaceEdit,
+			Applied:       false,
+			FailureReason: reason,
+		})
+		return protocol.ApplyWorkspaceEditResult{
+			Applied:       false,
+			FailureReason: reason,
+		}, nil
+	}
+
+	// Keep the edit until it is approved
+	if id, queued := client.queueApplyEdit(workspaceEdit); queued {
+		reason := fmt.Sprintf("queued for approval as pending edit %d", id)
+		lspLogger.Info("Workspace edit %s", reason)
+		client.recordEdit(AppliedEdit{
+			Params:        workspaceEdit,
+			Applied:       false,
+			FailureReason: reason,
+		})
+		return protocol.ApplyWorkspaceEditResult{
+			Applied:       false,
+			FailureReason: reason,
+		}, nil
+	}
+
 	// Apply the edits
-	err := utilities.ApplyWorkspaceEdit(workspaceEdit.Edit)
+	err := utilities.ApplyWorkspaceEdit(workspaceEdit.Edit, applyEditDescription(workspaceEdit))
+	client.recordEdit(AppliedEdit{
+		Params:        workspaceEdit,
+		Applied:       err == nil,
+		FailureReason: workspaceEditFailure(err),
+	})
 	if err != nil {
 		lspLogger.Error("Error applying workspace edit: %v", err)
 		return protocol.ApplyWorkspaceEditResult{
@@ -88,16 +198,42 @@ func workspaceEditFailure(err error) string {
 	return err.Error()
 }
 
+// HandleShowMessageRequest processes window/showMessageRequest requests. There
+// is nobody to pick an action, so the message is passed on and no action is chosen.
+func HandleShowMessageRequest(client *Client, params json.RawMessage) (any, error) {
+	var msg protocol.ShowMessageRequestParams
+	if err := json.Unmarshal(params, &msg); err != nil {
+		lspLogger.Error("Error unmarshaling show message request: %v", err)
+		return nil, err
+	}
+
+	client.recordMessage(protocol.ShowMessageParams{Type: msg.Type, Message: msg.Message})
+
+	actions := make([]string, len(msg.Actions))
+	for i, action := range msg.Actions {
+		actions[i] = action.Title
+	}
+	lspLogger.Info("Server message request: %s (actions: %s)", msg.Message, strings.Join(actions, ", "))
+
+	if handler := messageHandler.Load(); handler != nil {
+		(*handler)(msg.Type, msg.Message)
+	}
+
+	return nil, nil
+}
+
 // Notifications
 
 // HandleServerMessage processes window/showMessage notifications from the server
-func HandleServerMessage(params json.RawMessage) {
+func HandleServerMessage(client *Client, params json.RawMessage) {
 	var msg protocol.ShowMessageParams
 	if err := json.Unmarshal(params, &msg); err != nil {
 		lspLogger.Error("Error unmarshaling server message: %v", err)
 		return
 	}
 
+	client.recordMessage(msg)
+
 	// Log the message with appropriate level
 	switch msg.Type {
 	case protocol.Error:
@@ -109,6 +245,26 @@ func HandleServerMessage(params json.RawMessage) {
 	default:
 		lspLogger.Debug("Server message: %s", msg.Message)
 	}
+
+	if handler := messageHandler.Load(); handler != nil {
+		(*handler)(msg.Type, msg.Message)
+	}
+}
+
+// HandleLogMessage processes window/logMessage notifications from the server
+func HandleLogMessage(client *Client, params json.RawMessage) {
+	var msg protocol.LogMessageParams
+	if err := json.Unmarshal(params, &msg); err != nil {
+		lspLogger.Error("Error unmarshaling log message: %v", err)
+		return
+	}
+
+	// Log messages are verbose, keep them out of the regular log
+	lspLogger.Debug("Server log: %s", msg.Message)
+
+	if handler := messageHandler.Load(); handler != nil {
+		(*handler)(msg.Type, msg.Message)
+	}
 }
 
 // HandleDiagnostics processes textDocument/publishDiagnostics notifications
@@ -125,4 +281,8 @@ func HandleDiagnostics(client *Client, params json.RawMessage) {
 	client.diagnosticsMu.Unlock()
 
 	lspLogger.Info("Received diagnostics for %s: %d items", diagParams.URI, len(diagParams.Diagnostics))
+
+	if handler := diagnosticsHandler.Load(); handler != nil {
+		(*handler)(diagParams.URI, diagParams.Diagnostics)
+	}
 }
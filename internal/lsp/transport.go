@@ -204,6 +204,10 @@ func (c *Client) Call(ctx context.Context, method string, params any, result any
 
 	lspLogger.Debug("Making call: method=%s id=%v", method, id)
 
+	if prefix, ok := ctx.Value(progressPrefixKey{}).(string); ok {
+		params = withWorkDoneToken(params, fmt.Sprintf("%s%d", prefix, id))
+	}
+
 	msg, err := NewRequest(id, method, params)
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
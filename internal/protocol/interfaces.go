@@ -2,6 +2,22 @@ package protocol
 
 import "fmt"
 
+// WorkDoneProgressRequest is implemented by the params of requests that
+// embed WorkDoneProgressParams and so can be given a work done token
+type WorkDoneProgressRequest interface {
+	SetWorkDoneToken(token ProgressToken) bool
+}
+
+// SetWorkDoneToken sets the work done token unless one is set already, and
+// reports whether it did
+func (p *WorkDoneProgressParams) SetWorkDoneToken(token ProgressToken) bool {
+	if p.WorkDoneToken.Value != nil {
+		return false
+	}
+	p.WorkDoneToken = token
+	return true
+}
+
 // TextEditResult is an interface for types that represent workspace symbols
 type WorkspaceSymbolResult interface {
 	GetName() string
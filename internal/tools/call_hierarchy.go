@@ -2,62 +2,216 @@ package tools
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"os"
+	"slices"
 	"sort"
 	"strings"
 
 	"github.com/isaacphi/mcp-language-server/internal/lsp"
 	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
 )
 
-func GetCallers(ctx context.Context, client *lsp.Client, symbolName string, maxDepth int) (string, error) {
-	return getCallHierarchy(ctx, client, symbolName, maxDepth, recurseIncomingCalls)
+// Formats a call hierarchy can be rendered in
+const (
+	CallGraphTree      = "tree"
+	CallGraphDot       = "dot"
+	CallGraphMermaid   = "mermaid"
+	CallGraphAdjacency = "adjacency"
+)
+
+// MaxCallHierarchyDepth bounds how many levels of calls are followed, since
+// hierarchies grow quickly with depth
+const MaxCallHierarchyDepth = 10
+
+// CallGraphFormats lists every format a call hierarchy can be rendered in
+var CallGraphFormats = []string{CallGraphTree, CallGraphDot, CallGraphMermaid, CallGraphAdjacency}
+
+// CallHierarchyOptions controls how far a call hierarchy is followed, which
+// functions it includes and how it is rendered
+type CallHierarchyOptions struct {
+	// MaxDepth is how many levels of calls to follow
+	MaxDepth int
+	// Exclude skips functions in files matching any of these globs
+	Exclude []string
+	// ExcludeExternal skips functions in the standard library and in
+	// third-party dependencies
+	ExcludeExternal bool
+	// CallSites adds where each call happens, shown with ContextLines lines
+	// of code around it
+	CallSites    bool
+	ContextLines int
+	// Format is one of CallGraphFormats, the indented tree by default
+	Format string
 }
 
-func GetCallees(ctx context.Context, client *lsp.Client, symbolName string, maxDepth int) (string, error) {
-	return getCallHierarchy(ctx, client, symbolName, maxDepth, recurseOutgoingCalls)
+// validate checks the globs and the format
+func (o CallHierarchyOptions) validate() error {
+	if o.MaxDepth < 1 || o.MaxDepth > MaxCallHierarchyDepth {
+		return fmt.Errorf("depth must be between 1 and %d, got %d", MaxCallHierarchyDepth, o.MaxDepth)
+	}
+	for _, pattern := range o.Exclude {
+		if _, err := utilities.MatchPathGlob(pattern, ""); err != nil {
+			return err
+		}
+	}
+	if o.Format != "" && !slices.Contains(CallGraphFormats, o.Format) {
+		return fmt.Errorf("unknown format %q, expected one of %s", o.Format, strings.Join(CallGraphFormats, ", "))
+	}
+	return nil
 }
 
-func getCallHierarchy(
-	ctx context.Context, client *lsp.Client, symbolName string, maxDepth int,
-	recurse func(ctx context.Context, client *lsp.Client, item protocol.CallHierarchyItem, result *strings.Builder, depth int, maxDepth int),
-) (string, error) {
-	// First get the symbol location like ReadDefinition does
-	symbolName, results, err := QuerySymbol(ctx, client, symbolName)
+// excluded reports whether the functions in a file are left out
+func (o CallHierarchyOptions) excluded(uri protocol.DocumentUri) bool {
+	path := strings.TrimPrefix(string(uri), "file://")
+	if o.ExcludeExternal && utilities.IsExternalPath(path) {
+		return true
+	}
+	for _, pattern := range o.Exclude {
+		if match, _ := utilities.MatchPathGlob(pattern, path); match {
+			return true
+		}
+	}
+	return false
+}
+
+// CallHierarchyResult is the call hierarchy of each symbol matching a name
+type CallHierarchyResult struct {
+	Symbol string `json:"symbol"`
+	// Direction is incoming for callers and outgoing for callees
+	Direction string               `json:"direction"`
+	Matches   []CallHierarchyMatch `json:"matches"`
+	// Candidates are the symbols the name matches when it is ambiguous, in
+	// which case no calls are looked up
+	Candidates []SymbolCandidate `json:"candidates,omitempty"`
+	// Graph is the hierarchy as an adjacency list, included when it is
+	// rendered in the adjacency format
+	Graph *CallGraph `json:"graph,omitempty"`
+	// Format is how Text renders the hierarchy
+	Format string `json:"-"`
+}
+
+// CallGraph is the functions of one or more call hierarchies and the calls
+// between them
+type CallGraph struct {
+	Nodes []CallGraphNode `json:"nodes"`
+	// Edges maps the ID of each caller to the IDs of the functions it calls
+	Edges map[string][]string `json:"edges"`
+}
+
+// CallGraphNode is a function in a call graph
+type CallGraphNode struct {
+	ID       string   `json:"id"`
+	Name     string   `json:"name"`
+	Detail   string   `json:"detail,omitempty"`
+	Location Location `json:"location"`
+}
+
+// CallHierarchyMatch is the call hierarchy of one symbol
+type CallHierarchyMatch struct {
+	Name string `json:"name"`
+	// Calls lists the hierarchy depth first, starting with the symbol itself
+	// at depth 0
+	Calls []CallItem `json:"calls"`
+	// Excluded is the number of calls left out by the exclude options
+	Excluded int `json:"excluded,omitempty"`
+	// Error explains why the hierarchy could not be prepared
+	Error string `json:"error,omitempty"`
+}
+
+// CallItem is a function in a call hierarchy
+type CallItem struct {
+	Name     string   `json:"name"`
+	Detail   string   `json:"detail"`
+	Location Location `json:"location"`
+	Depth    int      `json:"depth"`
+	// Parent is the index in Calls of the item this one calls or is called
+	// by, or -1 for the symbol itself
+	Parent int `json:"parent"`
+	// Cycle is set when the item is also one of its own ancestors, as with
+	// recursion. Its calls are not listed again.
+	Cycle bool `json:"cycle,omitempty"`
+	// Repeated is set when the calls of the item are already listed at an
+	// earlier occurrence
+	Repeated bool `json:"repeated,omitempty"`
+	// CallSites are where the item calls its parent, or where its parent
+	// calls it for callees
+	CallSites []Location `json:"callSites,omitempty"`
+	// Snippet shows the code around the call sites
+	Snippet string `json:"snippet,omitempty"`
+	// Error explains why the calls of this item could not be listed
+	Error string `json:"error,omitempty"`
+
+	item protocol.CallHierarchyItem
+}
+
+// GetCallers returns the callers of every symbol matching a name
+func GetCallers(ctx context.Context, client *lsp.Client, symbolName string, maxDepth int) (string, error) {
+	query := ParseSymbolQuery(symbolName)
+	query.All = true
+	result, err := GetCallersResult(ctx, client, query, CallHierarchyOptions{MaxDepth: maxDepth})
 	if err != nil {
 		return "", err
 	}
+	return result.Text(), nil
+}
 
-	// After this point we just return errors instead of erroring out
-	var result strings.Builder
+// GetCallees returns the callees of every symbol matching a name
+func GetCallees(ctx context.Context, client *lsp.Client, symbolName string, maxDepth int) (string, error) {
+	query := ParseSymbolQuery(symbolName)
+	query.All = true
+	result, err := GetCalleesResult(ctx, client, query, CallHierarchyOptions{MaxDepth: maxDepth})
+	if err != nil {
+		return "", err
+	}
+	return result.Text(), nil
+}
 
-	for _, symbol := range results {
-		var separator string
-		if strings.Contains(symbolName, ".") {
-			separator = "."
-		} else if strings.Contains(symbolName, "::") {
-			separator = "::"
-		}
+// GetCallersResult returns the functions calling the symbols matching a
+// query, up to options.MaxDepth levels
+func GetCallersResult(ctx context.Context, client *lsp.Client, query SymbolQuery, options CallHierarchyOptions) (*CallHierarchyResult, error) {
+	return getCallHierarchy(ctx, client, query, options, "incoming", incomingCalls)
+}
 
-		// Handle different matching strategies based on the search term
-		if separator != "" {
-			// For qualified names like "Type.Method", check for various matches
-			parts := strings.Split(symbolName, separator)
-			methodName := parts[len(parts)-1]
+// GetCalleesResult returns the functions the symbols matching a query call,
+// up to options.MaxDepth levels
+func GetCalleesResult(ctx context.Context, client *lsp.Client, query SymbolQuery, options CallHierarchyOptions) (*CallHierarchyResult, error) {
+	return getCallHierarchy(ctx, client, query, options, "outgoing", outgoingCalls)
+}
 
-			// Try matching the unqualified method name for languages that don't use qualified names in symbols
-			if symbol.GetName() != symbolName && symbol.GetName() != methodName {
-				continue
-			}
-		} else if symbol.GetName() != symbolName {
-			// For unqualified names, exact match only
-			continue
-		}
+// hierarchyCall is an item of a call hierarchy and the places it is called
+// from or calls its parent
+type hierarchyCall struct {
+	item protocol.CallHierarchyItem
+	// sites are ranges in the file of siteURI
+	siteURI protocol.DocumentUri
+	sites   []protocol.Range
+}
 
-		result.WriteString("\n---\n")
+// callsFunc lists the items an item calls or is called by
+type callsFunc func(ctx context.Context, client *lsp.Client, item protocol.CallHierarchyItem) ([]hierarchyCall, error)
+
+func getCallHierarchy(ctx context.Context, client *lsp.Client, query SymbolQuery, options CallHierarchyOptions, direction string, calls callsFunc) (*CallHierarchyResult, error) {
+	if err := options.validate(); err != nil {
+		return nil, err
+	}
+
+	// First get the symbol location like ReadDefinition does
+	symbols, candidates, err := resolveUnambiguous(ctx, client, query)
+	if err != nil {
+		return nil, err
+	}
+
+	// After this point we just return errors instead of erroring out
+	result := &CallHierarchyResult{Symbol: query.Name, Direction: direction, Matches: []CallHierarchyMatch{}, Candidates: candidates, Format: options.Format}
+
+	for _, symbol := range symbols {
+		match := CallHierarchyMatch{Name: symbol.Name, Calls: []CallItem{}}
 
 		// Get the location of the symbol
-		loc := symbol.GetLocation()
+		loc := symbol.Location
 
 		chParams := protocol.CallHierarchyPrepareParams{
 			TextDocumentPositionParams: protocol.TextDocumentPositionParams{
@@ -69,132 +223,345 @@ func getCallHierarchy(
 		}
 		items, err := client.PrepareCallHierarchy(ctx, chParams)
 		if err != nil {
-			result.WriteString(fmt.Sprintf("%s: Error: %v\n", symbol.GetName(), err))
+			match.Error = err.Error()
+			result.Matches = append(result.Matches, match)
 			continue
 		}
 
+		walker := newCallHierarchyWalker(client, options, calls)
 		for _, item := range items {
-			recurse(ctx, client, item, &result, 0, maxDepth)
+			walker.walk(ctx, hierarchyCall{item: item}, &match, -1, 0)
 		}
+		result.Matches = append(result.Matches, match)
 	}
 
-	return result.String(), nil
+	if options.Format == CallGraphAdjacency {
+		result.Graph = result.graph()
+	}
+	return result, nil
 }
 
-func recurseIncomingCalls(ctx context.Context, client *lsp.Client, item protocol.CallHierarchyItem, result *strings.Builder, depth int, maxDepth int) {
+// callItemKey identifies a function across the places it appears in a
+// hierarchy
+type callItemKey struct {
+	uri   protocol.DocumentUri
+	start protocol.Position
+}
 
-	var prefix string
-	if depth != 0 {
-		prefix = strings.Repeat(" ", (depth-1)*2+2)
+// callHierarchyWalker follows the calls of one symbol, listing the calls of
+// each function only once
+type callHierarchyWalker struct {
+	client  *lsp.Client
+	options CallHierarchyOptions
+	calls   callsFunc
+	// expanded holds the functions whose calls are listed
+	expanded map[callItemKey]bool
+	// ancestors holds the functions on the path to the current one
+	ancestors map[callItemKey]bool
+	// files caches the lines of the files with call sites
+	files map[protocol.DocumentUri][]string
+}
 
-		result.WriteString(strings.Repeat(" ", (depth-1)*2))
-		result.WriteRune('-')
-		result.WriteString(" Called By: ")
-	} else {
-		result.WriteString("Name: ")
+func newCallHierarchyWalker(client *lsp.Client, options CallHierarchyOptions, calls callsFunc) *callHierarchyWalker {
+	return &callHierarchyWalker{
+		client:    client,
+		options:   options,
+		calls:     calls,
+		expanded:  make(map[callItemKey]bool),
+		ancestors: make(map[callItemKey]bool),
+		files:     make(map[protocol.DocumentUri][]string),
 	}
+}
 
-	result.WriteString(item.Name)
-	result.WriteRune('\n')
+// walk appends item to the calls of a match and, up to the maximum depth,
+// the items it calls or is called by. Functions seen before are listed
+// again without their calls.
+func (w *callHierarchyWalker) walk(ctx context.Context, call hierarchyCall, match *CallHierarchyMatch, parent int, depth int) {
+	item := call.item
+	key := callItemKey{uri: item.URI, start: item.SelectionRange.Start}
+	index := len(match.Calls)
+	match.Calls = append(match.Calls, CallItem{
+		Name:     item.Name,
+		Detail:   item.Detail,
+		Location: newLocation(protocol.Location{URI: item.URI, Range: item.Range}),
+		Depth:    depth,
+		Parent:   parent,
+		item:     item,
+	})
+	if w.options.CallSites {
+		w.addCallSites(&match.Calls[index], call)
+	}
 
-	result.WriteString(prefix)
-	result.WriteString("Detail: ")
-	result.WriteString(item.Detail)
-	result.WriteRune('\n')
+	switch {
+	case w.ancestors[key]:
+		match.Calls[index].Cycle = true
+		return
+	case w.expanded[key]:
+		match.Calls[index].Repeated = true
+		return
+	case depth >= w.options.MaxDepth:
+		return
+	}
+
+	next, err := w.calls(ctx, w.client, item)
+	if err != nil {
+		match.Calls[index].Error = err.Error()
+		return
+	}
+	w.expanded[key] = true
+
+	// ensure output is deterministic for tests
+	sort.Slice(next, func(i, j int) bool {
+		return next[i].item.Name < next[j].item.Name
+	})
 
-	result.WriteString(prefix)
-	result.WriteString("File: ")
-	result.WriteString(strings.TrimPrefix(string(item.URI), "file://"))
-	result.WriteRune('\n')
+	w.ancestors[key] = true
+	defer delete(w.ancestors, key)
+	for _, call := range next {
+		if w.options.excluded(call.item.URI) {
+			match.Excluded++
+			continue
+		}
+		w.walk(ctx, call, match, index, depth+1)
+	}
+}
 
-	result.WriteString(prefix)
-	fmt.Fprintf(result, "Range: L%d:C%d - L%d:C%d\n",
-		item.Range.Start.Line+1,
-		item.Range.Start.Character+1,
-		item.Range.End.Line+1,
-		item.Range.End.Character+1)
+// addCallSites sets the call sites of an item and the code around them
+func (w *callHierarchyWalker) addCallSites(item *CallItem, call hierarchyCall) {
+	if len(call.sites) == 0 {
+		return
+	}
+	sites := slices.Clone(call.sites)
+	sort.Slice(sites, func(i, j int) bool {
+		return positionBefore(sites[i].Start, sites[j].Start)
+	})
+	for _, site := range sites {
+		item.CallSites = append(item.CallSites, newLocation(protocol.Location{URI: call.siteURI, Range: site}))
+	}
 
-	if depth >= maxDepth {
+	lines, ok := w.files[call.siteURI]
+	if !ok {
+		path := strings.TrimPrefix(string(call.siteURI), "file://")
+		if err := utilities.CheckReadAccess(path); err == nil {
+			if content, err := os.ReadFile(path); err == nil {
+				lines = strings.Split(string(content), "\n")
+			}
+		}
+		w.files[call.siteURI] = lines
+	}
+	if len(lines) == 0 {
 		return
 	}
 
+	linesToShow := make(map[int]bool)
+	for _, site := range sites {
+		start := int(site.Start.Line) - w.options.ContextLines
+		end := int(site.End.Line) + w.options.ContextLines
+		for line := max(start, 0); line <= end; line++ {
+			linesToShow[line] = true
+		}
+	}
+	item.Snippet = FormatLinesWithRanges(lines, ConvertLinesToRanges(linesToShow, len(lines)))
+}
+
+func incomingCalls(ctx context.Context, client *lsp.Client, item protocol.CallHierarchyItem) ([]hierarchyCall, error) {
 	calls, err := client.IncomingCalls(ctx, protocol.CallHierarchyIncomingCallsParams{
 		Item: item,
 	})
-
 	if err != nil {
-		result.WriteString(prefix)
-		result.WriteString("Error: ")
-		result.WriteString(err.Error())
-		result.WriteRune('\n')
-		return
+		return nil, err
 	}
 
-	// ensure output is deterministic for tests
-	sort.Slice(calls, func(i, j int) bool {
-		return calls[i].From.Name < calls[j].From.Name
+	// The calls are in the callers
+	result := make([]hierarchyCall, len(calls))
+	for i, call := range calls {
+		result[i] = hierarchyCall{item: call.From, siteURI: call.From.URI, sites: call.FromRanges}
+	}
+	return result, nil
+}
+
+func outgoingCalls(ctx context.Context, client *lsp.Client, item protocol.CallHierarchyItem) ([]hierarchyCall, error) {
+	calls, err := client.OutgoingCalls(ctx, protocol.CallHierarchyOutgoingCallsParams{
+		Item: item,
 	})
+	if err != nil {
+		return nil, err
+	}
 
-	for _, call := range calls {
-		recurseIncomingCalls(ctx, client, call.From, result, depth+1, maxDepth)
+	// The calls are in the item itself
+	result := make([]hierarchyCall, len(calls))
+	for i, call := range calls {
+		result[i] = hierarchyCall{item: call.To, siteURI: item.URI, sites: call.FromRanges}
 	}
+	return result, nil
 }
 
-func recurseOutgoingCalls(ctx context.Context, client *lsp.Client, item protocol.CallHierarchyItem, result *strings.Builder, depth int, maxDepth int) {
+// Text renders the hierarchies in the requested format, an indented tree
+// by default
+func (r *CallHierarchyResult) Text() string {
+	if len(r.Candidates) > 0 {
+		return ambiguityText(r.Symbol, r.Candidates)
+	}
 
-	var prefix string
-	if depth != 0 {
-		prefix = strings.Repeat(" ", (depth-1)*2+2)
+	switch r.Format {
+	case CallGraphDot:
+		return r.dotText()
+	case CallGraphMermaid:
+		return r.mermaidText()
+	case CallGraphAdjacency:
+		graph := r.Graph
+		if graph == nil {
+			graph = r.graph()
+		}
+		data, err := json.MarshalIndent(graph, "", "  ")
+		if err != nil {
+			return fmt.Sprintf("Error: %v", err)
+		}
+		return string(data)
+	}
 
-		result.WriteString(strings.Repeat(" ", (depth-1)*2))
-		result.WriteRune('-')
-		result.WriteString(" Calls: ")
-	} else {
-		result.WriteString("Name: ")
+	label := " Calls: "
+	if r.Direction == "incoming" {
+		label = " Called By: "
 	}
 
-	result.WriteString(item.Name)
-	result.WriteRune('\n')
+	var result strings.Builder
+	for _, match := range r.Matches {
+		result.WriteString("\n---\n")
+		if match.Error != "" {
+			result.WriteString(fmt.Sprintf("%s: Error: %s\n", match.Name, match.Error))
+			continue
+		}
+
+		for _, item := range match.Calls {
+			var prefix string
+			if item.Depth != 0 {
+				prefix = strings.Repeat(" ", (item.Depth-1)*2+2)
 
-	result.WriteString(prefix)
-	result.WriteString("Detail: ")
-	result.WriteString(item.Detail)
-	result.WriteRune('\n')
+				result.WriteString(strings.Repeat(" ", (item.Depth-1)*2))
+				result.WriteRune('-')
+				result.WriteString(label)
+			} else {
+				result.WriteString("Name: ")
+			}
 
-	result.WriteString(prefix)
-	result.WriteString("File: ")
-	result.WriteString(strings.TrimPrefix(string(item.URI), "file://"))
-	result.WriteRune('\n')
+			result.WriteString(item.Name)
+			switch {
+			case item.Cycle:
+				result.WriteString(" (recursive, calls not repeated)")
+			case item.Repeated:
+				result.WriteString(" (calls listed above)")
+			}
+			result.WriteRune('\n')
+
+			result.WriteString(prefix)
+			result.WriteString("Detail: ")
+			result.WriteString(item.Detail)
+			result.WriteRune('\n')
+
+			result.WriteString(prefix)
+			result.WriteString("File: ")
+			result.WriteString(item.Location.File)
+			result.WriteRune('\n')
+
+			result.WriteString(prefix)
+			fmt.Fprintf(&result, "Range: %s\n", item.Location.Range)
+
+			if len(item.CallSites) > 0 {
+				sites := make([]string, len(item.CallSites))
+				for i, site := range item.CallSites {
+					sites[i] = site.Range.Start.String()
+				}
+				fmt.Fprintf(&result, "%sCall Sites: %s\n", prefix, strings.Join(sites, ", "))
+			}
+			for _, line := range strings.SplitAfter(item.Snippet, "\n") {
+				if line != "" {
+					result.WriteString(prefix)
+					result.WriteString(line)
+				}
+			}
 
-	result.WriteString(prefix)
-	fmt.Fprintf(result, "Range: L%d:C%d - L%d:C%d\n",
-		item.Range.Start.Line+1,
-		item.Range.Start.Character+1,
-		item.Range.End.Line+1,
-		item.Range.End.Character+1)
+			if item.Error != "" {
+				result.WriteString(prefix)
+				result.WriteString("Error: ")
+				result.WriteString(item.Error)
+				result.WriteRune('\n')
+			}
+		}
+		if match.Excluded > 0 {
+			fmt.Fprintf(&result, "Excluded calls: %d\n", match.Excluded)
+		}
+	}
 
-	if depth >= maxDepth {
-		return
+	return result.String()
+}
+
+// graph merges the hierarchies into one graph, with an edge from each caller
+// to each function it calls
+func (r *CallHierarchyResult) graph() *CallGraph {
+	graph := &CallGraph{Nodes: []CallGraphNode{}, Edges: make(map[string][]string)}
+	ids := make(map[Location]string)
+	for _, match := range r.Matches {
+		nodeIDs := make([]string, len(match.Calls))
+		for i, item := range match.Calls {
+			id, ok := ids[item.Location]
+			if !ok {
+				id = fmt.Sprintf("n%d", len(graph.Nodes)+1)
+				ids[item.Location] = id
+				graph.Nodes = append(graph.Nodes, CallGraphNode{ID: id, Name: item.Name, Detail: item.Detail, Location: item.Location})
+			}
+			nodeIDs[i] = id
+
+			if item.Parent < 0 {
+				continue
+			}
+			from, to := nodeIDs[item.Parent], id
+			if r.Direction == "incoming" {
+				from, to = to, from
+			}
+			if !slices.Contains(graph.Edges[from], to) {
+				graph.Edges[from] = append(graph.Edges[from], to)
+			}
+		}
 	}
+	return graph
+}
 
-	calls, err := client.OutgoingCalls(ctx, protocol.CallHierarchyOutgoingCallsParams{
-		Item: item,
-	})
+// dotText renders the call graph in the Graphviz DOT language
+func (r *CallHierarchyResult) dotText() string {
+	escape := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+	graph := r.graph()
 
-	if err != nil {
-		result.WriteString(prefix)
-		result.WriteString("Error: ")
-		result.WriteString(err.Error())
-		result.WriteRune('\n')
-		return
+	var result strings.Builder
+	result.WriteString("digraph calls {\n  rankdir=LR;\n  node [shape=box];\n")
+	for _, node := range graph.Nodes {
+		fmt.Fprintf(&result, "  %s [label=\"%s\\n%s:%d\"];\n",
+			node.ID, escape.Replace(node.Name), escape.Replace(node.Location.File), node.Location.Range.Start.Line)
 	}
+	for _, node := range graph.Nodes {
+		for _, to := range graph.Edges[node.ID] {
+			fmt.Fprintf(&result, "  %s -> %s;\n", node.ID, to)
+		}
+	}
+	result.WriteString("}\n")
+	return result.String()
+}
 
-	// ensure output is deterministic for tests
-	sort.Slice(calls, func(i, j int) bool {
-		return calls[i].To.Name < calls[j].To.Name
-	})
+// mermaidText renders the call graph as a Mermaid flowchart
+func (r *CallHierarchyResult) mermaidText() string {
+	escape := strings.NewReplacer(`"`, "#quot;", "<", "#lt;", ">", "#gt;")
+	graph := r.graph()
 
-	for _, call := range calls {
-		recurseOutgoingCalls(ctx, client, call.To, result, depth+1, maxDepth)
+	var result strings.Builder
+	result.WriteString("flowchart LR\n")
+	for _, node := range graph.Nodes {
+		fmt.Fprintf(&result, "  %s[\"%s<br/>%s:%d\"]\n",
+			node.ID, escape.Replace(node.Name), escape.Replace(node.Location.File), node.Location.Range.Start.Line)
+	}
+	for _, node := range graph.Nodes {
+		for _, to := range graph.Edges[node.ID] {
+			fmt.Fprintf(&result, "  %s --> %s\n", node.ID, to)
+		}
 	}
+	return result.String()
 }
@@ -0,0 +1,153 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWalkCallHierarchy(t *testing.T) {
+	utilities.SetWorkspacePaths("/ws", false, nil)
+	defer utilities.SetWorkspacePaths("", false, nil)
+
+	item := func(name, file string, line uint32) protocol.CallHierarchyItem {
+		r := protocol.Range{Start: protocol.Position{Line: line}, End: protocol.Position{Line: line + 2}}
+		return protocol.CallHierarchyItem{Name: name, URI: protocol.DocumentUri("file://" + file), Range: r, SelectionRange: r}
+	}
+	parse := item("parse", "/ws/parse.go", 10)
+	eval := item("eval", "/ws/eval.go", 3)
+	apply := item("apply", "/ws/eval.go", 20)
+	printf := item("Printf", "/usr/local/go/src/fmt/print.go", 230)
+	helper := item("helper", "/ws/parse_test.go", 5)
+
+	// eval and apply call each other and both call parse
+	callees := map[string][]protocol.CallHierarchyItem{
+		"eval":  {apply, parse, printf},
+		"apply": {eval, parse},
+		"parse": {helper},
+	}
+	calls := func(ctx context.Context, client *lsp.Client, item protocol.CallHierarchyItem) ([]hierarchyCall, error) {
+		var result []hierarchyCall
+		for _, callee := range callees[item.Name] {
+			result = append(result, hierarchyCall{item: callee})
+		}
+		return result, nil
+	}
+
+	options := CallHierarchyOptions{MaxDepth: 5, ExcludeExternal: true, Exclude: []string{"*_test.go"}}
+	match := CallHierarchyMatch{}
+	newCallHierarchyWalker(nil, options, calls).walk(context.Background(), hierarchyCall{item: eval}, &match, -1, 0)
+
+	type call struct {
+		name     string
+		depth    int
+		parent   int
+		cycle    bool
+		repeated bool
+	}
+	var got []call
+	for _, item := range match.Calls {
+		got = append(got, call{item.Name, item.Depth, item.Parent, item.Cycle, item.Repeated})
+	}
+	assert.Equal(t, []call{
+		{"eval", 0, -1, false, false},
+		{"apply", 1, 0, false, false},
+		{"eval", 2, 1, true, false},
+		{"parse", 2, 1, false, false},
+		{"parse", 1, 0, false, true},
+	}, got)
+	assert.Equal(t, 2, match.Excluded, "Printf and the test helper are left out")
+}
+
+func TestCallSites(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "main.go")
+	source := "package main\n\nfunc main() {\n\tx := parse(1)\n\ty := 2\n\tz := 3\n\tif err := parse(x); err != nil {\n\t}\n}\n"
+	require.NoError(t, os.WriteFile(path, []byte(source), 0644))
+
+	at := func(line, start, end uint32) protocol.Range {
+		return protocol.Range{Start: protocol.Position{Line: line, Character: start}, End: protocol.Position{Line: line, Character: end}}
+	}
+	call := hierarchyCall{
+		item:    protocol.CallHierarchyItem{Name: "main", URI: protocol.DocumentUri("file://" + path), Range: at(2, 0, 1)},
+		siteURI: protocol.DocumentUri("file://" + path),
+		sites:   []protocol.Range{at(6, 11, 16), at(3, 6, 11)},
+	}
+
+	walker := newCallHierarchyWalker(nil, CallHierarchyOptions{CallSites: true, ContextLines: 1}, nil)
+	item := CallItem{}
+	walker.addCallSites(&item, call)
+
+	require.Len(t, item.CallSites, 2)
+	assert.Equal(t, Position{Line: 4, Column: 7}, item.CallSites[0].Range.Start, "call sites are sorted")
+	assert.Equal(t, "3|func main() {\n4|\tx := parse(1)\n5|\ty := 2\n6|\tz := 3\n7|\tif err := parse(x); err != nil {\n8|\t}\n", item.Snippet)
+}
+
+func TestCallHierarchyGraph(t *testing.T) {
+	location := func(file string, line int) Location {
+		return Location{File: file, Range: Range{Start: Position{Line: line, Column: 1}, End: Position{Line: line + 2, Column: 2}}}
+	}
+	result := &CallHierarchyResult{
+		Symbol:    "parse",
+		Direction: "incoming",
+		Matches: []CallHierarchyMatch{{
+			Name: "parse",
+			Calls: []CallItem{
+				{Name: "parse", Location: location("parse.go", 10), Depth: 0, Parent: -1},
+				{Name: "eval", Location: location("eval.go", 3), Depth: 1, Parent: 0},
+				{Name: "run", Location: location("main.go", 8), Depth: 2, Parent: 1},
+				{Name: "apply", Location: location("eval.go", 20), Depth: 1, Parent: 0},
+				{Name: "eval", Location: location("eval.go", 3), Depth: 2, Parent: 3, Repeated: true},
+			},
+		}},
+	}
+
+	result.Format = CallGraphDot
+	assert.Equal(t, "digraph calls {\n  rankdir=LR;\n  node [shape=box];\n"+
+		"  n1 [label=\"parse\\nparse.go:10\"];\n"+
+		"  n2 [label=\"eval\\neval.go:3\"];\n"+
+		"  n3 [label=\"run\\nmain.go:8\"];\n"+
+		"  n4 [label=\"apply\\neval.go:20\"];\n"+
+		"  n2 -> n1;\n"+
+		"  n2 -> n4;\n"+
+		"  n3 -> n2;\n"+
+		"  n4 -> n1;\n"+
+		"}\n", result.Text())
+
+	result.Format = CallGraphMermaid
+	assert.Equal(t, "flowchart LR\n"+
+		"  n1[\"parse<br/>parse.go:10\"]\n"+
+		"  n2[\"eval<br/>eval.go:3\"]\n"+
+		"  n3[\"run<br/>main.go:8\"]\n"+
+		"  n4[\"apply<br/>eval.go:20\"]\n"+
+		"  n2 --> n1\n"+
+		"  n2 --> n4\n"+
+		"  n3 --> n2\n"+
+		"  n4 --> n1\n", result.Text())
+
+	result.Format = CallGraphAdjacency
+	var graph CallGraph
+	require.NoError(t, json.Unmarshal([]byte(result.Text()), &graph))
+	assert.Len(t, graph.Nodes, 4)
+	assert.Equal(t, map[string][]string{"n2": {"n1", "n4"}, "n3": {"n2"}, "n4": {"n1"}}, graph.Edges)
+
+	// Callees point from the symbol to the functions it calls
+	result.Direction = "outgoing"
+	assert.Equal(t, map[string][]string{"n1": {"n2", "n4"}, "n2": {"n3"}, "n4": {"n2"}}, result.graph().Edges)
+}
+
+func TestCallHierarchyOptionsValidate(t *testing.T) {
+	assert.NoError(t, CallHierarchyOptions{MaxDepth: 3, Format: CallGraphMermaid}.validate())
+	assert.Error(t, CallHierarchyOptions{MaxDepth: 0}.validate())
+	assert.Error(t, CallHierarchyOptions{MaxDepth: MaxCallHierarchyDepth + 1}.validate())
+	assert.Error(t, CallHierarchyOptions{MaxDepth: 1, Format: "svg"}.validate())
+	assert.Error(t, CallHierarchyOptions{MaxDepth: 1, Exclude: []string{"[a-"}}.validate())
+}
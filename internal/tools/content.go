@@ -3,18 +3,38 @@ package tools
 import (
 	"context"
 	"fmt"
-	"strings"
 
 	"github.com/isaacphi/mcp-language-server/internal/lsp"
 	"github.com/isaacphi/mcp-language-server/internal/protocol"
 )
 
+// ContentResult is the definition of the symbol at a position
+type ContentResult struct {
+	Symbol   string   `json:"symbol"`
+	Location Location `json:"location"`
+	Source   string   `json:"source"`
+}
+
 // GetContentInfo reads the source code definition of a symbol (function, type, constant, etc.) at the specified position
 func GetContentInfo(ctx context.Context, client *lsp.Client, filePath string, line, column int) (string, error) {
+	result, err := GetContentInfoResult(ctx, client, filePath, line, column)
+	if err != nil {
+		return "", err
+	}
+	return result.Text(), nil
+}
+
+// GetContentInfoResult reads the definition of the symbol at the one-indexed position
+func GetContentInfoResult(ctx context.Context, client *lsp.Client, filePath string, line, column int) (*ContentResult, error) {
+	filePath, err := readablePath(filePath)
+	if err != nil {
+		return nil, err
+	}
+
 	// Open the file if not already open
-	err := client.OpenFile(ctx, filePath)
+	err = client.OpenFile(ctx, filePath)
 	if err != nil {
-		return "", fmt.Errorf("could not open file: %v", err)
+		return nil, fmt.Errorf("could not open file: %v", err)
 	}
 
 	// Convert 1-indexed line/column to 0-indexed for LSP protocol
@@ -33,22 +53,26 @@ func GetContentInfo(ctx context.Context, client *lsp.Client, filePath string, li
 
 	definition, loc, symbol, err := GetFullDefinition(ctx, client, location)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
+	return &ContentResult{
+		Symbol:   symbol.GetName(),
+		Location: newLocation(loc),
+		Source:   definition,
+	}, nil
+}
+
+// Text renders the symbol's location followed by its numbered source
+func (r *ContentResult) Text() string {
 	locationInfo := fmt.Sprintf(
 		"Symbol: %s\n"+
 			"File: %s\n"+
-			"Range: L%d:C%d - L%d:C%d\n\n",
-		symbol.GetName(),
-		strings.TrimPrefix(string(loc.URI), "file://"),
-		loc.Range.Start.Line+1,
-		loc.Range.Start.Character+1,
-		loc.Range.End.Line+1,
-		loc.Range.End.Character+1,
+			"Range: %s\n\n",
+		r.Symbol,
+		r.Location.File,
+		r.Location.Range,
 	)
 
-	definition = addLineNumbers(definition, int(loc.Range.Start.Line)+1)
-
-	return locationInfo + definition, nil
+	return locationInfo + addLineNumbers(r.Source, r.Location.Range.Start.Line)
 }
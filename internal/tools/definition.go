@@ -7,69 +7,66 @@ import (
 
 	"github.com/isaacphi/mcp-language-server/internal/lsp"
 	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
 )
 
+// DefinitionResult is the outcome of the definition tool
+type DefinitionResult struct {
+	Symbol      string       `json:"symbol"`
+	Definitions []Definition `json:"definitions"`
+	// Candidates are the symbols the name matches when it is ambiguous, in
+	// which case no definitions are read
+	Candidates []SymbolCandidate `json:"candidates,omitempty"`
+	// Page is set when the result is split into pages
+	Page *Page `json:"page,omitempty"`
+}
+
+// Definition is the source of one symbol matching a definition lookup
+type Definition struct {
+	Name      string   `json:"name"`
+	Kind      string   `json:"kind,omitempty"`
+	Container string   `json:"container,omitempty"`
+	Location  Location `json:"location"`
+	Source    string   `json:"source,omitempty"`
+	// Error explains why the source could not be read
+	Error string `json:"error,omitempty"`
+}
+
+// ReadDefinition returns the definitions of every symbol matching a name
 func ReadDefinition(ctx context.Context, client *lsp.Client, symbolName string) (string, error) {
-	symbolName, results, err := QuerySymbol(ctx, client, symbolName)
+	query := ParseSymbolQuery(symbolName)
+	query.All = true
+	result, err := ReadDefinitionResult(ctx, client, query)
 	if err != nil {
 		return "", err
 	}
+	return result.Text(), nil
+}
 
-	var definitions []string
-	for _, symbol := range results {
-		kind := ""
-		container := ""
-
-		// Skip symbols that we are not looking for. workspace/symbol may return
-		// a large number of fuzzy matches. This handles BaseSymbolInformation
-		doesSymbolMatch := func(vKind protocol.SymbolKind, vContainerName string) bool {
-			thisName := symbol.GetName()
-
-			kind = fmt.Sprintf("Kind: %s\n", protocol.TableKindMap[vKind])
-			if vContainerName != "" {
-				container = fmt.Sprintf("Container Name: %s\n", vContainerName)
-			}
-
-			if thisName == symbolName {
-				return true
-			}
-
-			// Handle different matching strategies based on the search term
-			if strings.Contains(symbolName, ".") {
-				// For qualified names like "Type.Method", don't do fuzzy match
-
-			} else if vKind == protocol.Method {
-				// For methods, only match if the method name matches exactly Type.symbolName or Type::symbolName or symbolName
-				if strings.HasSuffix(thisName, "::"+symbolName) || strings.HasSuffix(symbolName, "::"+thisName) {
-					return true
-				}
-
-				if strings.HasSuffix(thisName, "."+symbolName) || strings.HasSuffix(symbolName, "."+thisName) {
-					return true
-				}
-			}
-
-			return false
-		}
+// ReadDefinitionResult finds the definitions of the symbols matching a
+// query, or the candidates to choose from if the query is ambiguous
+func ReadDefinitionResult(ctx context.Context, client *lsp.Client, query SymbolQuery) (*DefinitionResult, error) {
+	symbols, candidates, err := resolveUnambiguous(ctx, client, query)
+	if err != nil {
+		return nil, err
+	}
 
-		switch v := symbol.(type) {
-		case *protocol.SymbolInformation:
-			if !doesSymbolMatch(v.Kind, v.ContainerName) {
-				continue
-			}
-
-		case *protocol.WorkspaceSymbol:
-			if !doesSymbolMatch(v.Kind, v.ContainerName) {
-				continue
-			}
-		default:
-			if symbol.GetName() != symbolName {
-				continue
-			}
+	result := &DefinitionResult{Symbol: query.Name, Definitions: []Definition{}, Candidates: candidates}
+	for _, symbol := range symbols {
+		toolsLogger.Debug("Found symbol: %s", symbol.Name)
+		loc := symbol.Location
+		definition := Definition{
+			Name:      symbol.Name,
+			Kind:      protocol.TableKindMap[symbol.Kind],
+			Container: symbol.Container,
+			Location:  newLocation(loc),
 		}
 
-		toolsLogger.Debug("Found symbol: %s", symbol.GetName())
-		loc := symbol.GetLocation()
+		if err := utilities.CheckReadAccess(loc.URI.Path()); err != nil {
+			definition.Error = err.Error()
+			result.Definitions = append(result.Definitions, definition)
+			continue
+		}
 
 		err := client.OpenFile(ctx, loc.URI.Path())
 		if err != nil {
@@ -77,35 +74,66 @@ func ReadDefinition(ctx context.Context, client *lsp.Client, symbolName string)
 			continue
 		}
 
-		banner := "---\n\n"
-		definition, loc, _, err := GetFullDefinition(ctx, client, loc)
-		locationInfo := fmt.Sprintf(
-			"Symbol: %s\n"+
-				"File: %s\n"+
-				kind+
-				container+
-				"Range: L%d:C%d - L%d:C%d\n\n",
-			symbol.GetName(),
-			strings.TrimPrefix(string(loc.URI), "file://"),
-			loc.Range.Start.Line+1,
-			loc.Range.Start.Character+1,
-			loc.Range.End.Line+1,
-			loc.Range.End.Character+1,
-		)
-
+		source, loc, _, err := GetFullDefinition(ctx, client, loc)
 		if err != nil {
 			toolsLogger.Error("Error getting definition: %v", err)
 			continue
 		}
 
-		definition = addLineNumbers(definition, int(loc.Range.Start.Line)+1)
+		definition.Location = newLocation(loc)
+		definition.Source = source
+		result.Definitions = append(result.Definitions, definition)
+	}
 
-		definitions = append(definitions, banner+locationInfo+definition+"\n")
+	return result, nil
+}
+
+// Text renders the definitions with line numbers
+func (r *DefinitionResult) Text() string {
+	if len(r.Candidates) > 0 {
+		return ambiguityText(r.Symbol, r.Candidates)
+	}
+	if len(r.Definitions) == 0 {
+		return fmt.Sprintf("%s not found", r.Symbol)
 	}
 
-	if len(definitions) == 0 {
-		return fmt.Sprintf("%s not found", symbolName), nil
+	var output strings.Builder
+	for _, definition := range r.Definitions {
+		output.WriteString("---\n\n")
+		output.WriteString(fmt.Sprintf("Symbol: %s\n", definition.Name))
+		if definition.Error != "" {
+			output.WriteString(definition.Error + "\n")
+			continue
+		}
+
+		output.WriteString(fmt.Sprintf("File: %s\n", definition.Location.File))
+		if definition.Kind != "" {
+			output.WriteString(fmt.Sprintf("Kind: %s\n", definition.Kind))
+		}
+		if definition.Container != "" {
+			output.WriteString(fmt.Sprintf("Container Name: %s\n", definition.Container))
+		}
+		output.WriteString(fmt.Sprintf("Range: %s\n\n", definition.Location.Range))
+		output.WriteString(addLineNumbers(definition.Source, definition.Location.Range.Start.Line))
+		output.WriteString("\n")
 	}
+	return output.String()
+}
+
+// Len returns the number of definitions
+func (r *DefinitionResult) Len() int {
+	return len(r.Definitions)
+}
+
+// Slice returns a copy holding only the given definitions
+func (r *DefinitionResult) Slice(start, end int, page *Page) PagedResult {
+	sliced := *r
+	sliced.Definitions = r.Definitions[start:end]
+	sliced.Page = page
+	return &sliced
+}
 
-	return strings.Join(definitions, ""), nil
+// ItemName names the items a page is made of
+func (r *DefinitionResult) ItemName() string {
+	return "definitions"
 }
@@ -4,16 +4,48 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/isaacphi/mcp-language-server/internal/lsp"
 	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
 )
 
+// DiagnosticsResult is the outcome of the diagnostics tool
+type DiagnosticsResult struct {
+	File        string       `json:"file"`
+	Diagnostics []Diagnostic `json:"diagnostics"`
+	// Snippet shows the diagnostic lines with surrounding context
+	Snippet string `json:"snippet,omitempty"`
+	// Error explains why the file content could not be shown
+	Error string `json:"error,omitempty"`
+}
+
+// Diagnostic is an error, warning or hint reported for a file
+type Diagnostic struct {
+	Severity string `json:"severity"`
+	Range    Range  `json:"range"`
+	Message  string `json:"message"`
+	Source   string `json:"source,omitempty"`
+	Code     string `json:"code,omitempty"`
+}
+
 // GetDiagnosticsForFile retrieves diagnostics for a specific file from the language server
 func GetDiagnosticsForFile(ctx context.Context, client *lsp.Client, filePath string, contextLines int, showLineNumbers bool) (string, error) {
+	result, err := GetDiagnosticsForFileResult(ctx, client, filePath, contextLines, showLineNumbers)
+	if err != nil {
+		return "", err
+	}
+	return result.Text(), nil
+}
+
+// GetDiagnosticsForFileResult retrieves the diagnostics for a file. The
+// diagnostic lines are only included when showLineNumbers is set.
+func GetDiagnosticsForFileResult(ctx context.Context, client *lsp.Client, filePath string, contextLines int, showLineNumbers bool) (*DiagnosticsResult, error) {
 	// Override with environment variable if specified
 	if envLines := os.Getenv("LSP_CONTEXT_LINES"); envLines != "" {
 		if val, err := strconv.Atoi(envLines); err == nil && val >= 0 {
@@ -21,9 +53,14 @@ func GetDiagnosticsForFile(ctx context.Context, client *lsp.Client, filePath str
 		}
 	}
 
-	err := client.OpenFile(ctx, filePath)
+	filePath, err := readablePath(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	err = client.OpenFile(ctx, filePath)
 	if err != nil {
-		return "", fmt.Errorf("could not open file: %v", err)
+		return nil, fmt.Errorf("could not open file: %v", err)
 	}
 
 	// Convert the file path to URI format
@@ -42,7 +79,7 @@ func GetDiagnosticsForFile(ctx context.Context, client *lsp.Client, filePath str
 	case <-time.After(waitDuration):
 		// Continue after wait
 	case <-ctx.Done():
-		return "", fmt.Errorf("context cancelled while waiting for initial diagnostics: %w", ctx.Err())
+		return nil, fmt.Errorf("context cancelled while waiting for initial diagnostics: %w", ctx.Err())
 	}
 
 	// Try to request fresh diagnostics (not all language servers support this)
@@ -63,43 +100,30 @@ func GetDiagnosticsForFile(ctx context.Context, client *lsp.Client, filePath str
 	// Get diagnostics from the cache
 	diagnostics := client.GetFileDiagnostics(uri)
 
+	result := &DiagnosticsResult{File: utilities.DisplayPath(filePath), Diagnostics: []Diagnostic{}}
 	if len(diagnostics) == 0 {
-		return "No diagnostics found for " + filePath, nil
+		return result, nil
 	}
 
-	// Format file header
-	fileInfo := fmt.Sprintf("%s\nDiagnostics in File: %d\n",
-		filePath,
-		len(diagnostics),
-	)
+	// Report diagnostics in file order so the output is stable. The slice
+	// belongs to the client's cache, so sort a copy.
+	diagnostics = slices.Clone(diagnostics)
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		return positionBefore(diagnostics[i].Range.Start, diagnostics[j].Range.Start)
+	})
 
-	// Create a summary of all the diagnostics
-	var diagSummaries []string
 	var diagLocations []protocol.Location
-
 	for _, diag := range diagnostics {
-		severity := getSeverityString(diag.Severity)
-		location := fmt.Sprintf("L%d:C%d",
-			diag.Range.Start.Line+1,
-			diag.Range.Start.Character+1)
-
-		summary := fmt.Sprintf("%s at %s: %s",
-			severity,
-			location,
-			diag.Message)
-
-		// Add source and code if available
-		if diag.Source != "" {
-			summary += fmt.Sprintf(" (Source: %s", diag.Source)
-			if diag.Code != nil {
-				summary += fmt.Sprintf(", Code: %v", diag.Code)
-			}
-			summary += ")"
-		} else if diag.Code != nil {
-			summary += fmt.Sprintf(" (Code: %v)", diag.Code)
+		diagnostic := Diagnostic{
+			Severity: getSeverityString(diag.Severity),
+			Range:    newRange(diag.Range),
+			Message:  diag.Message,
+			Source:   diag.Source,
 		}
-
-		diagSummaries = append(diagSummaries, summary)
+		if diag.Code != nil {
+			diagnostic.Code = fmt.Sprintf("%v", diag.Code)
+		}
+		result.Diagnostics = append(result.Diagnostics, diagnostic)
 
 		// Create a location for this diagnostic to use with line ranges
 		diagLocations = append(diagLocations, protocol.Location{
@@ -108,10 +132,15 @@ func GetDiagnosticsForFile(ctx context.Context, client *lsp.Client, filePath str
 		})
 	}
 
+	if !showLineNumbers {
+		return result, nil
+	}
+
 	// Format content with context
 	fileContent, err := os.ReadFile(filePath)
 	if err != nil {
-		return fileInfo + "\nError reading file: " + err.Error(), nil
+		result.Error = "Error reading file: " + err.Error()
+		return result, nil
 	}
 
 	lines := strings.Split(string(fileContent), "\n")
@@ -138,19 +167,52 @@ func GetDiagnosticsForFile(ctx context.Context, client *lsp.Client, filePath str
 
 	// Convert to line ranges
 	lineRanges := ConvertLinesToRanges(linesToShow, len(lines))
+	result.Snippet = FormatLinesWithRanges(lines, lineRanges)
+
+	return result, nil
+}
+
+// Text renders a summary line for each diagnostic followed by the affected lines
+func (r *DiagnosticsResult) Text() string {
+	if len(r.Diagnostics) == 0 {
+		return "No diagnostics found for " + r.File
+	}
+
+	// Format file header
+	result := fmt.Sprintf("%s\nDiagnostics in File: %d\n",
+		r.File,
+		len(r.Diagnostics),
+	)
+	if r.Error != "" {
+		return result + "\n" + r.Error
+	}
+
+	// Create a summary of all the diagnostics
+	for _, diag := range r.Diagnostics {
+		summary := fmt.Sprintf("%s at %s: %s",
+			diag.Severity,
+			diag.Range.Start,
+			diag.Message)
 
-	// Format with diagnostics summary in header
-	result := fileInfo
-	if len(diagSummaries) > 0 {
-		result += strings.Join(diagSummaries, "\n") + "\n"
+		// Add source and code if available
+		if diag.Source != "" {
+			summary += fmt.Sprintf(" (Source: %s", diag.Source)
+			if diag.Code != "" {
+				summary += fmt.Sprintf(", Code: %s", diag.Code)
+			}
+			summary += ")"
+		} else if diag.Code != "" {
+			summary += fmt.Sprintf(" (Code: %s)", diag.Code)
+		}
+		result += summary + "\n"
 	}
 
 	// Format the content with ranges
-	if showLineNumbers {
-		result += "\n" + FormatLinesWithRanges(lines, lineRanges)
+	if r.Snippet != "" {
+		result += "\n" + r.Snippet
 	}
 
-	return result, nil
+	return result
 }
 
 func getSeverityString(severity protocol.DiagnosticSeverity) string {
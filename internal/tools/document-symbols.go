@@ -0,0 +1,190 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
+)
+
+// GetDocumentSymbols returns an outline of the symbols declared in a file,
+// with nested symbols indented under their parents
+func GetDocumentSymbols(ctx context.Context, client *lsp.Client, filePath string) (string, error) {
+	filePath, err := readablePath(filePath)
+	if err != nil {
+		return "", err
+	}
+
+	err = client.OpenFile(ctx, filePath)
+	if err != nil {
+		return "", fmt.Errorf("could not open file: %v", err)
+	}
+
+	symResult, err := client.DocumentSymbol(ctx, protocol.DocumentSymbolParams{
+		TextDocument: protocol.TextDocumentIdentifier{
+			URI: protocol.DocumentUri("file://" + filePath),
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to get document symbols: %v", err)
+	}
+
+	symbols, err := symResult.Results()
+	if err != nil {
+		return "", fmt.Errorf("failed to process document symbols: %v", err)
+	}
+
+	if len(symbols) == 0 {
+		return "No symbols found in " + utilities.DisplayPath(filePath), nil
+	}
+
+	var output strings.Builder
+	output.WriteString(utilities.DisplayPath(filePath))
+	output.WriteRune('\n')
+
+	var writeSymbols func(symbols []protocol.DocumentSymbolResult, depth int)
+	writeSymbols = func(symbols []protocol.DocumentSymbolResult, depth int) {
+		for _, sym := range symbols {
+			kind := ""
+			switch v := sym.(type) {
+			case *protocol.DocumentSymbol:
+				kind = protocol.TableKindMap[v.Kind]
+			case *protocol.SymbolInformation:
+				kind = protocol.TableKindMap[v.Kind]
+			}
+
+			symRange := sym.GetRange()
+			output.WriteString(fmt.Sprintf("%s%s %s: Lines %d-%d\n",
+				strings.Repeat("  ", depth),
+				kind,
+				sym.GetName(),
+				symRange.Start.Line+1,
+				symRange.End.Line+1))
+
+			if ds, ok := sym.(*protocol.DocumentSymbol); ok && len(ds.Children) > 0 {
+				children := make([]protocol.DocumentSymbolResult, len(ds.Children))
+				for i := range ds.Children {
+					children[i] = &ds.Children[i]
+				}
+				writeSymbols(children, depth+1)
+			}
+		}
+	}
+	writeSymbols(symbols, 0)
+
+	return output.String(), nil
+}
+
+// documentSymbol is a symbol declared in a file, with the names of the
+// symbols it is nested in
+type documentSymbol struct {
+	Name      string
+	Kind      protocol.SymbolKind
+	Container string
+	Range     protocol.Range
+}
+
+// parts returns the symbol name qualified with its containers
+func (s documentSymbol) parts() []qualifiedPart {
+	return symbolParts(s.Name, s.Container)
+}
+
+// path returns the symbol name qualified with its containers, with receiver
+// decorations such as "(*T).Method" normalized to "T.Method"
+func (s documentSymbol) path() string {
+	parts := s.parts()
+	names := make([]string, len(parts))
+	for i, part := range parts {
+		names[i] = part.name
+	}
+	return strings.Join(names, ".")
+}
+
+// listDocumentSymbols returns every symbol declared in a file, flattening
+// nested symbols
+func listDocumentSymbols(ctx context.Context, client *lsp.Client, filePath string) ([]documentSymbol, error) {
+	symResult, err := client.DocumentSymbol(ctx, protocol.DocumentSymbolParams{
+		TextDocument: protocol.TextDocumentIdentifier{
+			URI: protocol.DocumentUri("file://" + filePath),
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get document symbols: %v", err)
+	}
+
+	results, err := symResult.Results()
+	if err != nil {
+		return nil, fmt.Errorf("failed to process document symbols: %v", err)
+	}
+
+	var symbols []documentSymbol
+	var collect func(results []protocol.DocumentSymbolResult, container string)
+	collect = func(results []protocol.DocumentSymbolResult, container string) {
+		for _, result := range results {
+			switch v := result.(type) {
+			case *protocol.DocumentSymbol:
+				symbols = append(symbols, documentSymbol{Name: v.Name, Kind: v.Kind, Container: container, Range: v.Range})
+				children := make([]protocol.DocumentSymbolResult, len(v.Children))
+				for i := range v.Children {
+					children[i] = &v.Children[i]
+				}
+				childContainer := v.Name
+				if container != "" {
+					childContainer = container + "." + v.Name
+				}
+				collect(children, childContainer)
+			case *protocol.SymbolInformation:
+				symbols = append(symbols, documentSymbol{Name: v.Name, Kind: v.Kind, Container: v.ContainerName, Range: v.Location.Range})
+			}
+		}
+	}
+	collect(results, "")
+
+	return symbols, nil
+}
+
+// findDocumentSymbol returns the single symbol in a file matching name,
+// which may be qualified with its containers, such as "Type.Method". It is
+// an error if no symbol or more than one symbol matches.
+func findDocumentSymbol(ctx context.Context, client *lsp.Client, filePath string, name string) (documentSymbol, error) {
+	symbols, err := listDocumentSymbols(ctx, client, filePath)
+	if err != nil {
+		return documentSymbol{}, err
+	}
+
+	query := ParseSymbolQuery(name)
+	var matches []documentSymbol
+	for _, sym := range symbols {
+		if query.matches(sym.parts(), filePath) {
+			matches = append(matches, sym)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return documentSymbol{}, fmt.Errorf("symbol %s not found in %s", name, utilities.DisplayPath(filePath))
+	case 1:
+		return matches[0], nil
+	}
+
+	// Prefer an exact match over suffix matches
+	var exact []documentSymbol
+	for _, sym := range matches {
+		if len(sym.parts()) == len(query.Parts) {
+			exact = append(exact, sym)
+		}
+	}
+	if len(exact) == 1 {
+		return exact[0], nil
+	}
+
+	var candidates strings.Builder
+	for _, sym := range matches {
+		candidates.WriteString(fmt.Sprintf("\n  %s %s: Lines %d-%d",
+			protocol.TableKindMap[sym.Kind], sym.path(), sym.Range.Start.Line+1, sym.Range.End.Line+1))
+	}
+	return documentSymbol{}, fmt.Errorf("symbol %s is ambiguous in %s, qualify it with its type or container:%s", name, utilities.DisplayPath(filePath), candidates.String())
+}
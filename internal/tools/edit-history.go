@@ -0,0 +1,232 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
+)
+
+// EditHistoryResult lists recent edits, newest first
+type EditHistoryResult struct {
+	Edits []HistoryEdit `json:"edits"`
+	// Older is the number of edits left out because of the limit
+	Older int `json:"older,omitempty"`
+	// Page is set when the result is split into pages
+	Page *Page `json:"page,omitempty"`
+}
+
+// HistoryEdit is an edit applied through the server
+type HistoryEdit struct {
+	ID          int          `json:"id"`
+	Time        time.Time    `json:"time"`
+	Description string       `json:"description"`
+	Undone      bool         `json:"undone"`
+	Files       []FileChange `json:"files"`
+}
+
+// FileChange describes how an edit changed a file
+type FileChange struct {
+	File string `json:"file"`
+	// Change is created, deleted or modified
+	Change      string `json:"change"`
+	LinesBefore int    `json:"linesBefore"`
+	LinesAfter  int    `json:"linesAfter"`
+}
+
+// RestoredEditResult is the outcome of undoing or redoing an edit
+type RestoredEditResult struct {
+	// Action is undo or redo
+	Action string      `json:"action"`
+	Edit   HistoryEdit `json:"edit"`
+}
+
+// ListEditHistory lists the most recent edits applied through the server,
+// newest first, with the files each of them changed
+func ListEditHistory(limit int) (string, error) {
+	return ListEditHistoryResult(limit).Text(), nil
+}
+
+// ListEditHistoryResult returns up to limit of the most recent edits, or all
+// of them if limit is not positive
+func ListEditHistoryResult(limit int) *EditHistoryResult {
+	records := utilities.EditHistory()
+
+	result := &EditHistoryResult{Edits: []HistoryEdit{}}
+	for i := len(records) - 1; i >= 0; i-- {
+		if limit > 0 && len(result.Edits) == limit {
+			result.Older = i + 1
+			break
+		}
+		result.Edits = append(result.Edits, newHistoryEdit(records[i], false))
+	}
+
+	return result
+}
+
+// Text renders one line per edit followed by the files it changed
+func (r *EditHistoryResult) Text() string {
+	if len(r.Edits) == 0 {
+		return "No edits have been made"
+	}
+
+	var output strings.Builder
+	for _, edit := range r.Edits {
+		output.WriteString(edit.String())
+		output.WriteRune('\n')
+		for _, file := range edit.Files {
+			output.WriteString(fmt.Sprintf("  %s\n", file))
+		}
+	}
+	if r.Older > 0 {
+		output.WriteString(fmt.Sprintf("... %d older edits\n", r.Older))
+	}
+
+	return output.String()
+}
+
+// UndoLastEdit reverts the most recent edit that has not been undone
+func UndoLastEdit() (string, error) {
+	result, err := UndoLastEditResult()
+	if err != nil {
+		return "", err
+	}
+	return result.Text(), nil
+}
+
+// UndoLastEditResult reverts the most recent edit that has not been undone.
+// The file changes are reported in the direction of the undo.
+func UndoLastEditResult() (*RestoredEditResult, error) {
+	record, err := utilities.UndoLastEdit()
+	if err != nil {
+		return nil, err
+	}
+	return &RestoredEditResult{Action: "undo", Edit: newHistoryEdit(record, true)}, nil
+}
+
+// RedoLastEdit applies the most recently undone edit again
+func RedoLastEdit() (string, error) {
+	result, err := RedoLastEditResult()
+	if err != nil {
+		return "", err
+	}
+	return result.Text(), nil
+}
+
+// RedoLastEditResult applies the most recently undone edit again
+func RedoLastEditResult() (*RestoredEditResult, error) {
+	record, err := utilities.RedoLastEdit()
+	if err != nil {
+		return nil, err
+	}
+	return &RestoredEditResult{Action: "redo", Edit: newHistoryEdit(record, false)}, nil
+}
+
+// Text reports the restored edit and how each file changed
+func (r *RestoredEditResult) Text() string {
+	action := "Redid"
+	if r.Action == "undo" {
+		action = "Undid"
+	}
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("%s edit #%d: %s\n", action, r.Edit.ID, r.Edit.Description))
+	for _, file := range r.Edit.Files {
+		output.WriteString(fmt.Sprintf("  %s\n", file))
+	}
+	return output.String()
+}
+
+// newHistoryEdit converts an edit record, with its file changes in reverse
+// when undoing
+func newHistoryEdit(record utilities.EditRecord, reverse bool) HistoryEdit {
+	edit := HistoryEdit{
+		ID:          record.ID,
+		Time:        record.Time,
+		Description: record.Description,
+		Undone:      record.Undone,
+		Files:       []FileChange{},
+	}
+	for _, file := range record.Files {
+		edit.Files = append(edit.Files, newFileChange(file, reverse))
+	}
+	return edit
+}
+
+// String summarizes the edit on one line
+func (e HistoryEdit) String() string {
+	status := ""
+	if e.Undone {
+		status = " [undone]"
+	}
+	files := fmt.Sprintf("%d files", len(e.Files))
+	if len(e.Files) == 1 {
+		files = "1 file"
+	}
+	return fmt.Sprintf("#%d %s %s (%s)%s", e.ID, e.Time.Format(time.DateTime), e.Description, files, status)
+}
+
+// newFileChange summarizes how a file changed, in reverse when undoing
+func newFileChange(file utilities.FileRevision, reverse bool) FileChange {
+	before, beforeExists := file.Before, file.BeforeExists
+	after, afterExists := file.After, file.AfterExists
+	if reverse {
+		before, beforeExists, after, afterExists = after, afterExists, before, beforeExists
+	}
+
+	change := FileChange{
+		File:        utilities.DisplayPath(file.Path),
+		Change:      "modified",
+		LinesBefore: countLines(before),
+		LinesAfter:  countLines(after),
+	}
+	switch {
+	case !beforeExists:
+		change.Change = "created"
+	case !afterExists:
+		change.Change = "deleted"
+	}
+	return change
+}
+
+// String renders the change as it is shown in the history
+func (c FileChange) String() string {
+	switch c.Change {
+	case "created":
+		return fmt.Sprintf("created %s (%d lines)", c.File, c.LinesAfter)
+	case "deleted":
+		return fmt.Sprintf("deleted %s", c.File)
+	default:
+		return fmt.Sprintf("modified %s (%d -> %d lines)", c.File, c.LinesBefore, c.LinesAfter)
+	}
+}
+
+func countLines(content []byte) int {
+	if len(content) == 0 {
+		return 0
+	}
+	return strings.Count(strings.TrimSuffix(string(content), "\n"), "\n") + 1
+}
+
+// Len returns the number of edits
+func (r *EditHistoryResult) Len() int {
+	return len(r.Edits)
+}
+
+// Slice returns a copy holding only the given edits
+func (r *EditHistoryResult) Slice(start, end int, page *Page) PagedResult {
+	sliced := *r
+	sliced.Edits = r.Edits[start:end]
+	sliced.Page = page
+	// Older edits left out by the limit follow the last page
+	if end < len(r.Edits) {
+		sliced.Older = 0
+	}
+	return &sliced
+}
+
+// ItemName names the items a page is made of
+func (r *EditHistoryResult) ItemName() string {
+	return "edits"
+}
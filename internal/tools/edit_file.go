@@ -3,8 +3,11 @@ package tools
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"os"
+	"regexp"
 	"sort"
 	"strings"
 
@@ -17,28 +20,155 @@ type TextEdit struct {
 	StartLine int    `json:"startLine" jsonschema:"required,description=Start line to replace, inclusive"`
 	EndLine   int    `json:"endLine" jsonschema:"required,description=End line to replace, inclusive"`
 	NewText   string `json:"newText" jsonschema:"description=Replacement text. Replace with the new text. Leave blank to remove lines."`
+	// ExpectedText is the current text of the lines being replaced, if the
+	// caller wants the edit rejected when the file no longer matches
+	ExpectedText *string `json:"expectedText,omitempty" jsonschema:"description=Current text of the lines being replaced. The edit is rejected if it does not match."`
+
+	// OldText switches to exact string replacement of OldText with NewText
+	OldText string `json:"oldText,omitempty" jsonschema:"description=Exact text to replace with newText"`
+	// Pattern switches to regular expression replacement. NewText may refer
+	// to capture groups as $1 or ${name}.
+	Pattern string `json:"pattern,omitempty" jsonschema:"description=Regular expression to replace with newText"`
+	// ReplaceAll replaces every match of OldText or Pattern instead of
+	// requiring exactly one
+	ReplaceAll bool `json:"replaceAll,omitempty" jsonschema:"description=Replace every match instead of requiring a unique one"`
+
+	// Symbol switches to inserting NewText as whole lines before or after
+	// the declaration of a symbol in the file
+	Symbol   string `json:"symbol,omitempty" jsonschema:"description=Symbol to insert newText next to"`
+	Position string `json:"position,omitempty" jsonschema:"enum=before,enum=after,description=Insert before or after the symbol"`
 }
 
-func ApplyTextEdits(ctx context.Context, client *lsp.Client, filePath string, edits []TextEdit) (string, error) {
-	err := client.OpenFile(ctx, filePath)
+// Edit modes, chosen by which fields of a TextEdit are set
+const (
+	lineEditMode    = "lines"
+	replaceEditMode = "replace"
+	regexEditMode   = "regex"
+	symbolEditMode  = "symbol"
+)
+
+// mode returns how a TextEdit locates the text it changes
+func (e TextEdit) mode() (string, error) {
+	var modes []string
+	if e.OldText != "" {
+		modes = append(modes, replaceEditMode)
+	}
+	if e.Pattern != "" {
+		modes = append(modes, regexEditMode)
+	}
+	if e.Symbol != "" {
+		modes = append(modes, symbolEditMode)
+	}
+
+	switch len(modes) {
+	case 0:
+		return lineEditMode, nil
+	case 1:
+		if e.ExpectedText != nil {
+			return "", fmt.Errorf("expectedText only applies to line edits")
+		}
+		return modes[0], nil
+	default:
+		return "", fmt.Errorf("only one of oldText, pattern and symbol can be set")
+	}
+}
+
+// TextEditsResult is the outcome of applying edits to a file
+type TextEditsResult struct {
+	File         string `json:"file"`
+	LinesRemoved int    `json:"linesRemoved"`
+	LinesAdded   int    `json:"linesAdded"`
+	// Changes describes each edit that was not line based
+	Changes []string `json:"changes,omitempty"`
+	// Hash is the SHA-256 of the file after the edits
+	Hash string `json:"hash"`
+}
+
+// ApplyTextEdits applies edits to a file. Edits replace a line range, an
+// exact string, regular expression matches, or insert lines next to a symbol.
+// If expectedHash is set, or any line edit has ExpectedText, the edits are
+// only applied when the file still matches what the caller expects.
+func ApplyTextEdits(ctx context.Context, client *lsp.Client, filePath string, edits []TextEdit, expectedHash string) (string, error) {
+	result, err := ApplyTextEditsResult(ctx, client, filePath, edits, expectedHash)
 	if err != nil {
-		return "", fmt.Errorf("could not open file: %v", err)
+		return "", err
+	}
+	return result.Text(), nil
+}
+
+// ApplyTextEditsResult applies edits to a file and reports how many lines
+// changed and the new file hash
+func ApplyTextEditsResult(ctx context.Context, client *lsp.Client, filePath string, edits []TextEdit, expectedHash string) (*TextEditsResult, error) {
+	filePath, err := writablePath(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	err = client.OpenFile(ctx, filePath)
+	if err != nil {
+		return nil, fmt.Errorf("could not open file: %v", err)
+	}
+
+	if err := checkEditConflicts(filePath, edits, expectedHash); err != nil {
+		return nil, err
+	}
+
+	// Resolve the edits that are not line based against the current content
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %v", err)
+	}
+	text := strings.ReplaceAll(string(content), "\r\n", "\n")
+
+	var lineEdits []TextEdit
+	var textEdits []protocol.TextEdit
+	var changes []string
+	linesRemoved := 0
+	linesAdded := 0
+	for i, edit := range edits {
+		mode, err := edit.mode()
+		if err != nil {
+			return nil, fmt.Errorf("edit %d: %v", i+1, err)
+		}
+
+		var resolved []protocol.TextEdit
+		var change string
+		switch mode {
+		case lineEditMode:
+			lineEdits = append(lineEdits, edit)
+			continue
+		case replaceEditMode:
+			resolved, change, err = resolveStringReplacement(text, edit)
+		case regexEditMode:
+			resolved, change, err = resolveRegexReplacement(text, edit)
+		case symbolEditMode:
+			resolved, change, err = resolveSymbolInsertion(ctx, client, filePath, text, edit)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("edit %d: %v", i+1, err)
+		}
+
+		for _, textEdit := range resolved {
+			removed, added := countChangedLines(textEdit)
+			linesRemoved += removed
+			linesAdded += added
+		}
+		textEdits = append(textEdits, resolved...)
+		changes = append(changes, change)
 	}
 
 	// Create a sorted copy of edits for reporting
-	sortedEdits := make([]TextEdit, len(edits))
-	copy(sortedEdits, edits)
+	sortedEdits := make([]TextEdit, len(lineEdits))
+	copy(sortedEdits, lineEdits)
 	sort.Slice(sortedEdits, func(i, j int) bool {
 		return sortedEdits[i].StartLine < sortedEdits[j].StartLine
 	})
 
 	// Track lines added and removed for sorted edits
-	linesRemovedSorted := 0
-	linesAddedSorted := 0
 	for _, edit := range sortedEdits {
 		// Calculate lines removed: end - start + 1
 		removedLineCount := edit.EndLine - edit.StartLine + 1
-		linesRemovedSorted += removedLineCount
+		linesRemoved += removedLineCount
 
 		// Calculate lines added: count newlines in the replacement text + 1
 		addedLineCount := 1
@@ -47,22 +177,21 @@ func ApplyTextEdits(ctx context.Context, client *lsp.Client, filePath string, ed
 		} else if edit.NewText == "" {
 			addedLineCount = 0
 		}
-		linesAddedSorted += addedLineCount
+		linesAdded += addedLineCount
 	}
 
 	// Sort edits by line number in descending order to process from bottom to top
 	// This way line numbers don't shift under us as we make edits
-	sort.Slice(edits, func(i, j int) bool {
-		return edits[i].StartLine > edits[j].StartLine
+	sort.Slice(lineEdits, func(i, j int) bool {
+		return lineEdits[i].StartLine > lineEdits[j].StartLine
 	})
 
 	// Convert from input format to protocol.TextEdit
-	var textEdits []protocol.TextEdit
-	for _, edit := range edits {
+	for _, edit := range lineEdits {
 		// Get the range covering the requested lines
 		rng, err := getRange(edit.StartLine, edit.EndLine, filePath)
 		if err != nil {
-			return "", fmt.Errorf("invalid position: %v", err)
+			return nil, fmt.Errorf("invalid position: %v", err)
 		}
 
 		// Always do a replacement
@@ -78,11 +207,100 @@ func ApplyTextEdits(ctx context.Context, client *lsp.Client, filePath string, ed
 		},
 	}
 
-	if err := utilities.ApplyWorkspaceEdit(edit); err != nil {
-		return "", fmt.Errorf("failed to apply text edits: %v", err)
+	if err := utilities.ApplyWorkspaceEdit(edit, "edit_file "+utilities.DisplayPath(filePath)); err != nil {
+		return nil, fmt.Errorf("failed to apply text edits: %v", err)
+	}
+
+	hash, err := FileHash(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TextEditsResult{
+		File:         utilities.DisplayPath(filePath),
+		LinesRemoved: linesRemoved,
+		LinesAdded:   linesAdded,
+		Changes:      changes,
+		Hash:         hash,
+	}, nil
+}
+
+// Text summarizes the changed lines followed by the new file hash
+func (r *TextEditsResult) Text() string {
+	var result strings.Builder
+	result.WriteString(fmt.Sprintf("Successfully applied text edits. %d lines removed, %d lines added.\n", r.LinesRemoved, r.LinesAdded))
+	for _, change := range r.Changes {
+		result.WriteString(fmt.Sprintf("- %s\n", change))
 	}
+	result.WriteString(fmt.Sprintf("File hash: %s", r.Hash))
 
-	return fmt.Sprintf("Successfully applied text edits. %d lines removed, %d lines added.", linesRemovedSorted, linesAddedSorted), nil
+	return result.String()
+}
+
+// FileHash returns the hex encoded SHA-256 of a file's content, which callers
+// can pass back to edit_file to make sure the file has not changed
+func FileHash(filePath string) (string, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file: %w", err)
+	}
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// checkEditConflicts compares the file against the expected hash and the
+// expected text of each edit, and returns a report of every mismatch
+func checkEditConflicts(filePath string, edits []TextEdit, expectedHash string) error {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+
+	var conflicts strings.Builder
+
+	sum := sha256.Sum256(content)
+	currentHash := hex.EncodeToString(sum[:])
+	if expectedHash != "" && !strings.EqualFold(strings.TrimSpace(expectedHash), currentHash) {
+		conflicts.WriteString(fmt.Sprintf("File hash: expected %s, current %s\n", expectedHash, currentHash))
+	}
+
+	// Compare with normalized line endings, the way the agent saw the file
+	lines := strings.Split(strings.ReplaceAll(string(content), "\r\n", "\n"), "\n")
+
+	for _, edit := range edits {
+		if edit.ExpectedText == nil {
+			continue
+		}
+
+		var current string
+		startIdx := edit.StartLine - 1
+		endIdx := min(edit.EndLine, len(lines))
+		if startIdx >= 0 && startIdx < endIdx {
+			current = strings.Join(lines[startIdx:endIdx], "\n")
+		}
+
+		expected := strings.TrimSuffix(strings.ReplaceAll(*edit.ExpectedText, "\r\n", "\n"), "\n")
+		if current == expected {
+			continue
+		}
+
+		conflicts.WriteString(fmt.Sprintf("\nLines %d-%d do not match the expected text\n", edit.StartLine, edit.EndLine))
+		conflicts.WriteString("Expected:\n")
+		conflicts.WriteString(addLineNumbers(expected, edit.StartLine))
+		conflicts.WriteString("Current:\n")
+		if startIdx >= 0 && startIdx < endIdx {
+			conflicts.WriteString(addLineNumbers(current, edit.StartLine))
+		} else {
+			conflicts.WriteString("(past the end of the file)\n")
+		}
+	}
+
+	if conflicts.Len() == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("%s has changed since it was read, no edits were applied.\n%s\nRe-read the file and retry against the current content (file hash: %s)",
+		filePath, conflicts.String(), currentHash)
 }
 
 // getRange creates a protocol.Range that covers the specified start and end lines
@@ -152,3 +370,213 @@ func getRange(startLine, endLine int, filePath string) (protocol.Range, error) {
 		},
 	}, nil
 }
+
+// textMatch is a byte range of normalized file content and its replacement
+type textMatch struct {
+	start, end int
+	newText    string
+}
+
+// resolveStringReplacement finds the exact text to replace. Unless
+// ReplaceAll is set the text must occur exactly once.
+func resolveStringReplacement(text string, edit TextEdit) ([]protocol.TextEdit, string, error) {
+	oldText := strings.ReplaceAll(edit.OldText, "\r\n", "\n")
+
+	var matches []textMatch
+	for offset := 0; ; {
+		idx := strings.Index(text[offset:], oldText)
+		if idx < 0 {
+			break
+		}
+		start := offset + idx
+		matches = append(matches, textMatch{start: start, end: start + len(oldText), newText: edit.NewText})
+		offset = start + len(oldText)
+	}
+
+	if err := checkMatchCount(text, matches, edit.ReplaceAll, "oldText", "make it longer to include surrounding lines"); err != nil {
+		return nil, "", err
+	}
+
+	return matchesToEdits(text, matches), describeMatches(text, matches, "exact text"), nil
+}
+
+// resolveRegexReplacement finds the matches of a regular expression and
+// expands capture group references in the replacement for each of them.
+// Unless ReplaceAll is set the pattern must match exactly once.
+func resolveRegexReplacement(text string, edit TextEdit) ([]protocol.TextEdit, string, error) {
+	re, err := regexp.Compile(edit.Pattern)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid pattern: %v", err)
+	}
+
+	var matches []textMatch
+	for _, submatch := range re.FindAllStringSubmatchIndex(text, -1) {
+		newText := string(re.ExpandString(nil, edit.NewText, text, submatch))
+		matches = append(matches, textMatch{start: submatch[0], end: submatch[1], newText: newText})
+	}
+
+	if err := checkMatchCount(text, matches, edit.ReplaceAll, "pattern", "make it more specific"); err != nil {
+		return nil, "", err
+	}
+
+	return matchesToEdits(text, matches), describeMatches(text, matches, "regex"), nil
+}
+
+// resolveSymbolInsertion inserts whole lines before a symbol's declaration,
+// above its doc comment, or after the end of the declaration
+func resolveSymbolInsertion(ctx context.Context, client *lsp.Client, filePath string, text string, edit TextEdit) ([]protocol.TextEdit, string, error) {
+	if edit.Position != "before" && edit.Position != "after" {
+		return nil, "", fmt.Errorf("position must be before or after, got %q", edit.Position)
+	}
+
+	sym, err := findDocumentSymbol(ctx, client, filePath, edit.Symbol)
+	if err != nil {
+		return nil, "", err
+	}
+
+	lines := strings.Split(text, "\n")
+	newText := strings.TrimSuffix(strings.ReplaceAll(edit.NewText, "\r\n", "\n"), "\n")
+	insertedLines := strings.Count(newText, "\n") + 1
+
+	var pos protocol.Position
+	var firstLine int
+	if edit.Position == "before" {
+		startLine := declarationStart(lines, int(sym.Range.Start.Line))
+		pos = protocol.Position{Line: uint32(startLine), Character: 0}
+		newText += "\n"
+		firstLine = startLine + 1
+	} else {
+		endLine := min(int(sym.Range.End.Line), len(lines)-1)
+		pos = protocol.Position{Line: uint32(endLine), Character: uint32(len(lines[endLine]))}
+		newText = "\n" + newText
+		firstLine = endLine + 2
+	}
+
+	change := fmt.Sprintf("Inserted %d lines %s %s (lines %d-%d)",
+		insertedLines, edit.Position, sym.path(), firstLine, firstLine+insertedLines-1)
+
+	return []protocol.TextEdit{{
+		Range:   protocol.Range{Start: pos, End: pos},
+		NewText: newText,
+	}}, change, nil
+}
+
+// declarationStart returns the first line of the comments, decorators and
+// attributes directly above the declaration starting at line (zero-based)
+func declarationStart(lines []string, line int) int {
+	for line > 0 && line <= len(lines) {
+		prev := strings.TrimSpace(lines[line-1])
+		if prev == "" || !isCommentOrAttribute(prev) {
+			break
+		}
+		line--
+	}
+	return line
+}
+
+func isCommentOrAttribute(line string) bool {
+	return isCommentLine(line) || isAttributeLine(line)
+}
+
+// isCommentLine reports whether a trimmed line is part of a comment
+func isCommentLine(line string) bool {
+	if isAttributeLine(line) {
+		return false
+	}
+	for _, prefix := range []string{"//", "#", "/*", "*/", "* ", "--"} {
+		if strings.HasPrefix(line, prefix) {
+			return true
+		}
+	}
+	return line == "*"
+}
+
+// isAttributeLine reports whether a trimmed line is a decorator or attribute,
+// such as Python's @decorator or Rust's #[test]
+func isAttributeLine(line string) bool {
+	return strings.HasPrefix(line, "@") || strings.HasPrefix(line, "#[") || strings.HasPrefix(line, "#![")
+}
+
+// checkMatchCount rejects edits that match nothing, or match more than once
+// without replaceAll
+func checkMatchCount(text string, matches []textMatch, replaceAll bool, field string, hint string) error {
+	if len(matches) == 0 {
+		return fmt.Errorf("%s not found in file", field)
+	}
+	if len(matches) > 1 && !replaceAll {
+		return fmt.Errorf("%s matches %d times, at lines %s. Either %s so it is unique or set replaceAll",
+			field, len(matches), joinLines(matchLines(text, matches)), hint)
+	}
+	return nil
+}
+
+// matchesToEdits converts byte ranges of the content to text edits. Matches
+// that touch are merged, since adjacent edits are rejected as overlapping.
+func matchesToEdits(text string, matches []textMatch) []protocol.TextEdit {
+	var merged []textMatch
+	for _, match := range matches {
+		if n := len(merged); n > 0 && merged[n-1].end == match.start {
+			merged[n-1].end = match.end
+			merged[n-1].newText += match.newText
+			continue
+		}
+		merged = append(merged, match)
+	}
+
+	edits := make([]protocol.TextEdit, 0, len(merged))
+	for _, match := range merged {
+		edits = append(edits, protocol.TextEdit{
+			Range: protocol.Range{
+				Start: offsetToPosition(text, match.start),
+				End:   offsetToPosition(text, match.end),
+			},
+			NewText: match.newText,
+		})
+	}
+	return edits
+}
+
+// offsetToPosition converts a byte offset of the content to a line and byte
+// column, the positions utilities.ApplyWorkspaceEdit works with
+func offsetToPosition(text string, offset int) protocol.Position {
+	line := strings.Count(text[:offset], "\n")
+	lineStart := strings.LastIndex(text[:offset], "\n") + 1
+	return protocol.Position{Line: uint32(line), Character: uint32(offset - lineStart)}
+}
+
+// matchLines returns the one-indexed line each match starts on
+func matchLines(text string, matches []textMatch) []int {
+	lines := make([]int, len(matches))
+	for i, match := range matches {
+		lines[i] = int(offsetToPosition(text, match.start).Line) + 1
+	}
+	return lines
+}
+
+func joinLines(lines []int) string {
+	parts := make([]string, len(lines))
+	for i, line := range lines {
+		parts[i] = fmt.Sprint(line)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func describeMatches(text string, matches []textMatch, what string) string {
+	if len(matches) == 1 {
+		return fmt.Sprintf("Replaced 1 %s match at line %d", what, matchLines(text, matches)[0])
+	}
+	return fmt.Sprintf("Replaced %d %s matches at lines %s", len(matches), what, joinLines(matchLines(text, matches)))
+}
+
+// countChangedLines returns how many lines a text edit removes and adds
+func countChangedLines(edit protocol.TextEdit) (int, int) {
+	if edit.Range.Start == edit.Range.End {
+		return 0, strings.Count(edit.NewText, "\n")
+	}
+	removed := int(edit.Range.End.Line-edit.Range.Start.Line) + 1
+	added := 0
+	if edit.NewText != "" {
+		added = strings.Count(edit.NewText, "\n") + 1
+	}
+	return removed, added
+}
@@ -3,57 +3,64 @@ package tools
 import (
 	"context"
 	"fmt"
-	"time"
+	"strings"
 
 	"github.com/isaacphi/mcp-language-server/internal/lsp"
 	"github.com/isaacphi/mcp-language-server/internal/protocol"
 )
 
+// CodeLensExecution is the outcome of running a code lens command
+type CodeLensExecution struct {
+	ID       string         `json:"id"`
+	Title    string         `json:"title"`
+	Command  string         `json:"command"`
+	Activity ServerActivity `json:"activity"`
+}
+
 // ExecuteCodeLens executes a specific code lens command from a file.
-func ExecuteCodeLens(ctx context.Context, client *lsp.Client, filePath string, index int) (string, error) {
-	// Open the file
-	err := client.OpenFile(ctx, filePath)
+// The lens is identified by the ID reported by GetCodeLens.
+func ExecuteCodeLens(ctx context.Context, client *lsp.Client, filePath string, lensID string) (string, error) {
+	result, err := ExecuteCodeLensResult(ctx, client, filePath, lensID)
 	if err != nil {
-		return "", fmt.Errorf("could not open file: %v", err)
-	}
-	// TODO: find a more appropriate way to wait
-	time.Sleep(time.Second)
-
-	// Get code lenses
-	docIdentifier := protocol.TextDocumentIdentifier{
-		URI: protocol.DocumentUri("file://" + filePath),
+		return "", err
 	}
+	return result.Text(), nil
+}
 
-	params := protocol.CodeLensParams{
-		TextDocument: docIdentifier,
-	}
-	codeLenses, err := client.CodeLens(ctx, params)
+// ExecuteCodeLensResult executes the code lens with the given ID
+func ExecuteCodeLensResult(ctx context.Context, client *lsp.Client, filePath string, lensID string) (*CodeLensExecution, error) {
+	codeLenses, err := fetchCodeLenses(ctx, client, filePath)
 	if err != nil {
-		return "", fmt.Errorf("failed to get code lenses: %v", err)
+		return nil, err
 	}
 
 	if len(codeLenses) == 0 {
-		return "", fmt.Errorf("no code lenses found in file")
+		return nil, fmt.Errorf("no code lenses found in file")
 	}
 
-	if index < 1 || index > len(codeLenses) {
-		return "", fmt.Errorf("invalid code lens index: %d. Available range: 1-%d", index, len(codeLenses))
+	ids := codeLensIDs(codeLenses)
+	var lens *protocol.CodeLens
+	for i, id := range ids {
+		if id == lensID {
+			lens = &codeLenses[i]
+			break
+		}
 	}
 
-	lens := codeLenses[index-1]
+	if lens == nil {
+		return nil, fmt.Errorf("code lens %s not found. Available code lenses: %s. Use get_codelens to see what they do",
+			lensID, strings.Join(ids, ", "))
+	}
 
-	// Resolve the code lens if it doesn't have a command
 	if lens.Command == nil {
-		resolvedLens, err := client.ResolveCodeLens(ctx, lens)
-		if err != nil {
-			return "", fmt.Errorf("failed to resolve code lens: %v", err)
-		}
-		lens = resolvedLens
+		return nil, fmt.Errorf("code lens has no command after resolution")
 	}
 
-	if lens.Command == nil {
-		return "", fmt.Errorf("code lens has no command after resolution")
+	recorder, err := client.StartRecording(ctx)
+	if err != nil {
+		return nil, err
 	}
+	defer client.StopRecording(recorder)
 
 	// Execute the command
 	_, err = client.ExecuteCommand(ctx, protocol.ExecuteCommandParams{
@@ -61,8 +68,19 @@ func ExecuteCodeLens(ctx context.Context, client *lsp.Client, filePath string, i
 		Arguments: lens.Command.Arguments,
 	})
 	if err != nil {
-		return "", fmt.Errorf("failed to execute code lens command: %v", err)
+		// Edits the server was refused are often why the command failed
+		return nil, fmt.Errorf("%v\n%s", err, newServerActivity(recorder).Text())
 	}
 
-	return fmt.Sprintf("Successfully executed code lens command: %s", lens.Command.Title), nil
+	return &CodeLensExecution{
+		ID:       lensID,
+		Title:    lens.Command.Title,
+		Command:  lens.Command.Command,
+		Activity: newServerActivity(recorder),
+	}, nil
+}
+
+// Text reports the executed lens and the server activity it caused
+func (r *CodeLensExecution) Text() string {
+	return fmt.Sprintf("Successfully executed code lens command: %s\n", r.Title) + r.Activity.Text()
 }
@@ -0,0 +1,256 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
+)
+
+// CommandsResult lists the commands a language server accepts
+type CommandsResult struct {
+	Commands []string `json:"commands"`
+	// Page is set when the result is split into pages
+	Page *Page `json:"page,omitempty"`
+}
+
+// CommandResult is the outcome of a workspace/executeCommand request
+type CommandResult struct {
+	Command  string         `json:"command"`
+	Result   any            `json:"result"`
+	Activity ServerActivity `json:"activity"`
+}
+
+// ServerActivity holds the edits and messages a server sent while handling a request
+type ServerActivity struct {
+	Edits    []ServerEdit    `json:"edits,omitempty"`
+	Messages []ServerMessage `json:"messages,omitempty"`
+}
+
+// ServerEdit is a workspace edit requested by the server
+type ServerEdit struct {
+	Label   string `json:"label,omitempty"`
+	Applied bool   `json:"applied"`
+	// Reason explains why the edit was not applied
+	Reason string `json:"reason,omitempty"`
+	// Changes has one line per file or resource operation in the edit
+	Changes []string `json:"changes,omitempty"`
+}
+
+// ServerMessage is a window/showMessage notification from the server
+type ServerMessage struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+// ListCommands lists the commands the language server accepts for workspace/executeCommand
+func ListCommands(client *lsp.Client) (string, error) {
+	return ListCommandsResult(client).Text(), nil
+}
+
+// ListCommandsResult lists the commands the language server accepts
+func ListCommandsResult(client *lsp.Client) *CommandsResult {
+	commands := client.ExecuteCommands()
+	if commands == nil {
+		commands = []string{}
+	}
+	return &CommandsResult{Commands: commands}
+}
+
+// Text renders one command per line
+func (r *CommandsResult) Text() string {
+	if len(r.Commands) == 0 {
+		return "The language server does not advertise any commands."
+	}
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("Available commands: %d\n", len(r.Commands)))
+	for _, command := range r.Commands {
+		output.WriteString(command)
+		output.WriteRune('\n')
+	}
+
+	return output.String()
+}
+
+// ExecuteCommand runs a workspace/executeCommand request and reports the result
+// together with any edits and messages the server sent while handling it
+func ExecuteCommand(ctx context.Context, client *lsp.Client, command string, arguments []json.RawMessage) (string, error) {
+	result, err := ExecuteCommandResult(ctx, client, command, arguments)
+	if err != nil {
+		return "", err
+	}
+	return result.Text(), nil
+}
+
+// ExecuteCommandResult runs a workspace/executeCommand request
+func ExecuteCommandResult(ctx context.Context, client *lsp.Client, command string, arguments []json.RawMessage) (*CommandResult, error) {
+	commands := client.ExecuteCommands()
+	if len(commands) > 0 && !slices.Contains(commands, command) {
+		return nil, fmt.Errorf("command %q is not supported by the language server, use list_commands to see available commands", command)
+	}
+
+	recorder, err := client.StartRecording(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer client.StopRecording(recorder)
+
+	result, err := client.ExecuteCommand(ctx, protocol.ExecuteCommandParams{
+		Command:   command,
+		Arguments: arguments,
+	})
+	if err != nil {
+		// Edits the server was refused are often why the command failed
+		return nil, fmt.Errorf("%v\n%s", err, newServerActivity(recorder).Text())
+	}
+
+	return &CommandResult{
+		Command:  command,
+		Result:   result,
+		Activity: newServerActivity(recorder),
+	}, nil
+}
+
+// Text renders the command result as indented JSON followed by the server activity
+func (r *CommandResult) Text() string {
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("Executed command: %s\n", r.Command))
+
+	resultJSON, err := json.MarshalIndent(r.Result, "", "  ")
+	if err != nil {
+		resultJSON = []byte(fmt.Sprintf("%v", r.Result))
+	}
+	output.WriteString(fmt.Sprintf("Result: %s\n", resultJSON))
+
+	output.WriteString(r.Activity.Text())
+
+	return output.String()
+}
+
+// newServerActivity collects the edits and messages captured by a recorder
+func newServerActivity(recorder *lsp.ActivityRecorder) ServerActivity {
+	var activity ServerActivity
+
+	for _, edit := range recorder.Edits() {
+		activity.Edits = append(activity.Edits, ServerEdit{
+			Label:   edit.Params.Label,
+			Applied: edit.Applied,
+			Reason:  edit.FailureReason,
+			Changes: summarizeWorkspaceEdit(edit.Params.Edit),
+		})
+	}
+
+	for _, msg := range recorder.Messages() {
+		activity.Messages = append(activity.Messages, ServerMessage{
+			Type:    getMessageTypeString(msg.Type),
+			Message: msg.Message,
+		})
+	}
+
+	return activity
+}
+
+// Text renders the edits and messages, or nothing when there were none
+func (a ServerActivity) Text() string {
+	var output strings.Builder
+
+	if len(a.Edits) > 0 {
+		output.WriteString(fmt.Sprintf("\nWorkspace edits requested by the server: %d\n", len(a.Edits)))
+		for _, edit := range a.Edits {
+			label := edit.Label
+			if label == "" {
+				label = "(unlabeled)"
+			}
+			if edit.Applied {
+				output.WriteString(fmt.Sprintf("- %s: applied\n", label))
+			} else {
+				output.WriteString(fmt.Sprintf("- %s: not applied: %s\n", label, edit.Reason))
+			}
+			for _, line := range edit.Changes {
+				output.WriteString("  ")
+				output.WriteString(line)
+				output.WriteRune('\n')
+			}
+		}
+	}
+
+	if len(a.Messages) > 0 {
+		output.WriteString(fmt.Sprintf("\nServer messages: %d\n", len(a.Messages)))
+		for _, msg := range a.Messages {
+			output.WriteString(fmt.Sprintf("- %s: %s\n", msg.Type, msg.Message))
+		}
+	}
+
+	return output.String()
+}
+
+// summarizeWorkspaceEdit returns one line per file or resource operation in a workspace edit
+func summarizeWorkspaceEdit(edit protocol.WorkspaceEdit) []string {
+	var lines []string
+
+	for uri, textEdits := range edit.Changes {
+		lines = append(lines, fmt.Sprintf("%s: %d edits", utilities.DisplayURI(uri), len(textEdits)))
+	}
+	sort.Strings(lines)
+
+	for _, change := range edit.DocumentChanges {
+		switch {
+		case change.TextDocumentEdit != nil:
+			lines = append(lines, fmt.Sprintf("%s: %d edits",
+				utilities.DisplayURI(change.TextDocumentEdit.TextDocument.URI),
+				len(change.TextDocumentEdit.Edits)))
+		case change.CreateFile != nil:
+			lines = append(lines, fmt.Sprintf("%s: created", utilities.DisplayURI(change.CreateFile.URI)))
+		case change.RenameFile != nil:
+			lines = append(lines, fmt.Sprintf("%s: renamed to %s",
+				utilities.DisplayURI(change.RenameFile.OldURI),
+				utilities.DisplayURI(change.RenameFile.NewURI)))
+		case change.DeleteFile != nil:
+			lines = append(lines, fmt.Sprintf("%s: deleted", utilities.DisplayURI(change.DeleteFile.URI)))
+		}
+	}
+
+	return lines
+}
+
+func getMessageTypeString(messageType protocol.MessageType) string {
+	switch messageType {
+	case protocol.Error:
+		return "ERROR"
+	case protocol.Warning:
+		return "WARNING"
+	case protocol.Info:
+		return "INFO"
+	case protocol.Log:
+		return "LOG"
+	case protocol.Debug:
+		return "DEBUG"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// Len returns the number of commands
+func (r *CommandsResult) Len() int {
+	return len(r.Commands)
+}
+
+// Slice returns a copy holding only the given commands
+func (r *CommandsResult) Slice(start, end int, page *Page) PagedResult {
+	sliced := *r
+	sliced.Commands = r.Commands[start:end]
+	sliced.Page = page
+	return &sliced
+}
+
+// ItemName names the items a page is made of
+func (r *CommandsResult) ItemName() string {
+	return "commands"
+}
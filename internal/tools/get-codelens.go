@@ -2,62 +2,109 @@ package tools
 
 import (
 	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"strings"
 	"time"
 
 	"github.com/isaacphi/mcp-language-server/internal/lsp"
 	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
 )
 
+// codeLensWaitTimeout bounds how long code lens tools wait for a busy server
+const codeLensWaitTimeout = 5 * time.Second
+
+// CodeLensResult lists the code lenses of a file
+type CodeLensResult struct {
+	File   string     `json:"file"`
+	Lenses []CodeLens `json:"lenses"`
+	// Page is set when the result is split into pages
+	Page *Page `json:"page,omitempty"`
+}
+
+// CodeLens is an action the server offers for a range of lines
+type CodeLens struct {
+	// ID identifies the lens for execute_codelens
+	ID        string `json:"id"`
+	StartLine int    `json:"startLine"`
+	EndLine   int    `json:"endLine"`
+	// Resolved is false when the server did not provide a command
+	Resolved  bool   `json:"resolved"`
+	Title     string `json:"title,omitempty"`
+	Command   string `json:"command,omitempty"`
+	Arguments []any  `json:"arguments,omitempty"`
+	Data      any    `json:"data,omitempty"`
+}
+
 // GetCodeLens retrieves code lens hints for a given file location
 func GetCodeLens(ctx context.Context, client *lsp.Client, filePath string) (string, error) {
-	err := client.OpenFile(ctx, filePath)
+	result, err := GetCodeLensResult(ctx, client, filePath)
 	if err != nil {
-		return "", fmt.Errorf("could not open file: %v", err)
-	}
-	// TODO: find a more appropriate way to wait
-	time.Sleep(time.Second)
-
-	// Create document identifier
-	docIdentifier := protocol.TextDocumentIdentifier{
-		URI: protocol.DocumentUri("file://" + filePath),
+		return "", err
 	}
+	return result.Text(), nil
+}
 
-	// Request code lens from LSP
-	params := protocol.CodeLensParams{
-		TextDocument: docIdentifier,
-	}
-	codeLensResult, err := client.CodeLens(ctx, params)
+// GetCodeLensResult retrieves the code lenses of a file
+func GetCodeLensResult(ctx context.Context, client *lsp.Client, filePath string) (*CodeLensResult, error) {
+	codeLenses, err := fetchCodeLenses(ctx, client, filePath)
 	if err != nil {
-		return "", fmt.Errorf("failed to get code lens: %w", err)
+		return nil, err
 	}
 
-	if codeLensResult == nil {
-		return "No code lens providers available for this file.", nil
+	result := &CodeLensResult{File: utilities.DisplayPath(filePath), Lenses: []CodeLens{}}
+	ids := codeLensIDs(codeLenses)
+	for i, lens := range codeLenses {
+		item := CodeLens{
+			ID:        ids[i],
+			StartLine: int(lens.Range.Start.Line) + 1,
+			EndLine:   int(lens.Range.End.Line) + 1,
+			Data:      lens.Data,
+		}
+		if lens.Command != nil {
+			item.Resolved = true
+			item.Title = lens.Command.Title
+			item.Command = lens.Command.Command
+			if lens.Command.Arguments != nil {
+				item.Arguments = make([]any, len(lens.Command.Arguments))
+				for j, arg := range lens.Command.Arguments {
+					item.Arguments[j] = arg
+				}
+			}
+		}
+		result.Lenses = append(result.Lenses, item)
 	}
 
-	// Format the code lens results
+	return result, nil
+}
+
+// Text renders each lens with its ID, lines and command
+func (r *CodeLensResult) Text() string {
 	var output strings.Builder
-	output.WriteString(fmt.Sprintf("Code Lens results for %s:\n\n", filePath))
+	output.WriteString(fmt.Sprintf("Code Lens results for %s:\n\n", r.File))
 
-	for i, lens := range codeLensResult {
-		output.WriteString(fmt.Sprintf("[%d] Location: Lines %d-%d\n",
-			i+1,
-			lens.Range.Start.Line+1,
-			lens.Range.End.Line+1))
+	for _, lens := range r.Lenses {
+		output.WriteString(fmt.Sprintf("[%s] Location: Lines %d-%d\n",
+			lens.ID,
+			lens.StartLine,
+			lens.EndLine))
 
-		if lens.Command != nil {
-			output.WriteString(fmt.Sprintf("    Title: %s\n", lens.Command.Title))
-			if lens.Command.Command != "" {
-				output.WriteString(fmt.Sprintf("    Command: %s\n", lens.Command.Command))
+		if lens.Resolved {
+			output.WriteString(fmt.Sprintf("    Title: %s\n", lens.Title))
+			if lens.Command != "" {
+				output.WriteString(fmt.Sprintf("    Command: %s\n", lens.Command))
 			}
-			if lens.Command.Arguments != nil {
+			if lens.Arguments != nil {
 				output.WriteString("    Arguments:\n")
-				for _, arg := range lens.Command.Arguments {
+				for _, arg := range lens.Arguments {
 					output.WriteString(fmt.Sprintf("%s\n", arg))
 				}
 			}
+		} else {
+			output.WriteString("    Unresolved: the server did not provide a command for this lens\n")
 		}
 
 		// Print any custom data that might help identify the provider
@@ -68,11 +115,97 @@ func GetCodeLens(ctx context.Context, client *lsp.Client, filePath string) (stri
 		output.WriteString("\n")
 	}
 
-	if len(codeLensResult) == 0 {
+	if len(r.Lenses) == 0 {
 		output.WriteString("No code lens found for this file.\n")
 	} else {
-		output.WriteString(fmt.Sprintf("Found %d code lens items.\n", len(codeLensResult)))
+		output.WriteString(fmt.Sprintf("Found %d code lens items.\n", len(r.Lenses)))
+	}
+
+	return output.String()
+}
+
+// fetchCodeLenses opens the file, waits for the server to settle and returns
+// the resolved code lenses for it
+func fetchCodeLenses(ctx context.Context, client *lsp.Client, filePath string) ([]protocol.CodeLens, error) {
+	filePath, err := readablePath(filePath)
+	if err != nil {
+		return nil, err
 	}
 
-	return output.String(), nil
+	err = client.OpenFile(ctx, filePath)
+	if err != nil {
+		return nil, fmt.Errorf("could not open file: %v", err)
+	}
+
+	// Lenses are often computed from analysis the server does in the
+	// background, so give it a chance to finish before asking
+	waitCtx, cancel := context.WithTimeout(ctx, codeLensWaitTimeout)
+	defer cancel()
+	if err := client.WaitForIdle(waitCtx); err != nil {
+		toolsLogger.Debug("Requesting code lenses from a busy server: %v", err)
+	}
+
+	uri := protocol.DocumentUri("file://" + filePath)
+	return client.ResolvedCodeLenses(ctx, uri)
+}
+
+// codeLensIDs returns an identifier for each lens that stays the same across
+// calls as long as the lens itself does not change. Lenses that are otherwise
+// identical are told apart by their order in the file.
+func codeLensIDs(lenses []protocol.CodeLens) []string {
+	ids := make([]string, len(lenses))
+	seen := make(map[string]int)
+
+	for i, lens := range lenses {
+		key := struct {
+			Command   string
+			Title     string
+			Arguments []json.RawMessage
+			Data      any
+			Line      *uint32
+		}{
+			Data: lens.Data,
+		}
+		if lens.Command != nil {
+			key.Command = lens.Command.Command
+			key.Title = lens.Command.Title
+			key.Arguments = lens.Command.Arguments
+		} else {
+			// Unresolved lenses only have their position and data to go on
+			key.Line = &lens.Range.Start.Line
+		}
+
+		keyJSON, err := json.Marshal(key)
+		if err != nil {
+			keyJSON = []byte(fmt.Sprintf("%v", key))
+		}
+		sum := sha1.Sum(keyJSON)
+		id := hex.EncodeToString(sum[:4])
+
+		seen[id]++
+		if seen[id] > 1 {
+			id = fmt.Sprintf("%s-%d", id, seen[id])
+		}
+		ids[i] = id
+	}
+
+	return ids
+}
+
+// Len returns the number of code lenses
+func (r *CodeLensResult) Len() int {
+	return len(r.Lenses)
+}
+
+// Slice returns a copy holding only the given code lenses
+func (r *CodeLensResult) Slice(start, end int, page *Page) PagedResult {
+	sliced := *r
+	sliced.Lenses = r.Lenses[start:end]
+	sliced.Page = page
+	return &sliced
+}
+
+// ItemName names the items a page is made of
+func (r *CodeLensResult) ItemName() string {
+	return "code lenses"
 }
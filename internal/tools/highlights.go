@@ -0,0 +1,198 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
+)
+
+// HighlightsResult is the occurrences of the symbol at a position within
+// its file
+type HighlightsResult struct {
+	File       string      `json:"file"`
+	Position   Position    `json:"position"`
+	Highlights []Highlight `json:"highlights"`
+	// Snippet shows the code around the occurrences, each marked with its
+	// kind on the line below
+	Snippet string `json:"snippet,omitempty"`
+}
+
+// Highlight is one occurrence of a symbol
+type Highlight struct {
+	Range Range `json:"range"`
+	// Kind is read or write if the server tells them apart, otherwise text
+	Kind string `json:"kind"`
+}
+
+// GetHighlights returns the occurrences of the symbol at a position in its
+// file, marked as text, read or write
+func GetHighlights(ctx context.Context, client *lsp.Client, filePath string, line, column int) (string, error) {
+	result, err := GetHighlightsResult(ctx, client, filePath, line, column)
+	if err != nil {
+		return "", err
+	}
+	return result.Text(), nil
+}
+
+// GetHighlightsResult asks the server for the document highlights at the
+// one-indexed position and shows them within the enclosing function
+func GetHighlightsResult(ctx context.Context, client *lsp.Client, filePath string, line, column int) (*HighlightsResult, error) {
+	filePath, err := readablePath(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	err = client.OpenFile(ctx, filePath)
+	if err != nil {
+		return nil, fmt.Errorf("could not open file: %v", err)
+	}
+
+	position := protocol.Position{
+		Line:      uint32(line - 1),
+		Character: uint32(column - 1),
+	}
+	uri := protocol.DocumentUri("file://" + filePath)
+	highlights, err := client.DocumentHighlight(ctx, protocol.DocumentHighlightParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+			Position:     position,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get document highlights: %v", err)
+	}
+	sort.SliceStable(highlights, func(i, j int) bool {
+		return positionBefore(highlights[i].Range.Start, highlights[j].Range.Start)
+	})
+
+	result := &HighlightsResult{
+		File:       utilities.DisplayPath(filePath),
+		Position:   newPosition(position),
+		Highlights: []Highlight{},
+	}
+	if len(highlights) == 0 {
+		return result, nil
+	}
+
+	locations := make([]protocol.Location, len(highlights))
+	for i, highlight := range highlights {
+		result.Highlights = append(result.Highlights, Highlight{
+			Range: newRange(highlight.Range),
+			Kind:  highlightKindName(highlight.Kind),
+		})
+		locations[i] = protocol.Location{URI: uri, Range: highlight.Range}
+	}
+
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %v", err)
+	}
+	lines := strings.Split(string(content), "\n")
+
+	linesToShow, err := GetLineRangesToDisplay(ctx, client, locations, len(lines), contextLinesSetting())
+	if err != nil {
+		return nil, err
+	}
+	snippet := FormatLinesWithRanges(lines, ConvertLinesToRanges(linesToShow, len(lines)))
+	result.Snippet = markHighlights(snippet, lines, highlights)
+
+	return result, nil
+}
+
+// Text renders the occurrences and the code around them
+func (r *HighlightsResult) Text() string {
+	if len(r.Highlights) == 0 {
+		return fmt.Sprintf("%s\nNo highlights found at %s", r.File, r.Position)
+	}
+
+	counts := make(map[string]int)
+	for _, highlight := range r.Highlights {
+		counts[highlight.Kind]++
+	}
+	var summary []string
+	for _, kind := range []string{"write", "read", "text"} {
+		if counts[kind] > 0 {
+			summary = append(summary, fmt.Sprintf("%d %s", counts[kind], kind))
+		}
+	}
+
+	return fmt.Sprintf("%s\nOccurrences: %d (%s)\n\n%s",
+		r.File, len(r.Highlights), strings.Join(summary, ", "), r.Snippet)
+}
+
+// highlightKindName names a document highlight kind, which defaults to text
+func highlightKindName(kind protocol.DocumentHighlightKind) string {
+	switch kind {
+	case protocol.Read:
+		return "read"
+	case protocol.Write:
+		return "write"
+	default:
+		return "text"
+	}
+}
+
+// markHighlights adds a line below each line of a snippet with occurrences,
+// with carets under each occurrence followed by their kinds
+func markHighlights(snippet string, lines []string, highlights []protocol.DocumentHighlight) string {
+	byLine := make(map[int][]protocol.DocumentHighlight)
+	for _, highlight := range highlights {
+		line := int(highlight.Range.Start.Line)
+		byLine[line] = append(byLine[line], highlight)
+	}
+
+	var output strings.Builder
+	for _, snippetLine := range strings.SplitAfter(snippet, "\n") {
+		output.WriteString(snippetLine)
+
+		number, _, found := strings.Cut(snippetLine, "|")
+		if !found {
+			continue
+		}
+		lineNumber, err := strconv.Atoi(strings.TrimSpace(number))
+		if err != nil || lineNumber < 1 || lineNumber > len(lines) {
+			continue
+		}
+		lineHighlights := byLine[lineNumber-1]
+		if len(lineHighlights) == 0 {
+			continue
+		}
+
+		source := strings.TrimRight(lines[lineNumber-1], "\r")
+		var marker strings.Builder
+		var kinds []string
+		offset := 0
+		for _, highlight := range lineHighlights {
+			start := min(int(highlight.Range.Start.Character), len(source))
+			end := len(source)
+			if highlight.Range.End.Line == highlight.Range.Start.Line {
+				end = min(int(highlight.Range.End.Character), len(source))
+			}
+			if start < offset || end <= start {
+				continue
+			}
+			// Keep tabs so that the carets line up with the source
+			for _, c := range source[offset:start] {
+				if c == '\t' {
+					marker.WriteRune('\t')
+				} else {
+					marker.WriteRune(' ')
+				}
+			}
+			marker.WriteString(strings.Repeat("^", utf8.RuneCountInString(source[start:end])))
+			kinds = append(kinds, highlightKindName(highlight.Kind))
+			offset = end
+		}
+
+		output.WriteString(strings.Repeat(" ", len(number)) + "|" + marker.String() + " " + strings.Join(kinds, ", ") + "\n")
+	}
+	return output.String()
+}
@@ -0,0 +1,55 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMarkHighlights(t *testing.T) {
+	lines := []string{
+		"func f() {",
+		"\ttotal := 0",
+		"\ttotal = total + 1",
+		"}",
+	}
+	highlight := func(line, start, end uint32, kind protocol.DocumentHighlightKind) protocol.DocumentHighlight {
+		return protocol.DocumentHighlight{
+			Range: protocol.Range{
+				Start: protocol.Position{Line: line, Character: start},
+				End:   protocol.Position{Line: line, Character: end},
+			},
+			Kind: kind,
+		}
+	}
+	highlights := []protocol.DocumentHighlight{
+		highlight(1, 1, 6, protocol.Write),
+		highlight(2, 1, 6, protocol.Write),
+		highlight(2, 9, 14, protocol.Read),
+	}
+
+	snippet := FormatLinesWithRanges(lines, []LineRange{{Start: 0, End: 3}})
+	assert.Equal(t, "1|func f() {\n"+
+		"2|\ttotal := 0\n"+
+		" |\t^^^^^ write\n"+
+		"3|\ttotal = total + 1\n"+
+		" |\t^^^^^   ^^^^^ write, read\n"+
+		"4|}\n",
+		markHighlights(snippet, lines, highlights))
+}
+
+func TestHighlightsResultText(t *testing.T) {
+	result := &HighlightsResult{
+		File:     "main.go",
+		Position: Position{Line: 2, Column: 2},
+		Highlights: []Highlight{
+			{Kind: "write"}, {Kind: "read"}, {Kind: "read"},
+		},
+		Snippet: "2|\ttotal := 0\n",
+	}
+	assert.Equal(t, "main.go\nOccurrences: 3 (1 write, 2 read)\n\n2|\ttotal := 0\n", result.Text())
+
+	result.Highlights = nil
+	assert.Equal(t, "main.go\nNo highlights found at L2:C2", result.Text())
+}
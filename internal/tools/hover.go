@@ -4,18 +4,44 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"strings"
 
 	"github.com/isaacphi/mcp-language-server/internal/lsp"
 	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
 )
 
+// HoverResult is the hover information at a position
+type HoverResult struct {
+	File     string   `json:"file"`
+	Position Position `json:"position"`
+	// Contents is empty when the server has no information for the position
+	Contents string `json:"contents"`
+	// Range is the span the hover applies to, if the server reported one
+	Range *Range `json:"range,omitempty"`
+	// LineText is the requested line, included when there are no contents
+	LineText string `json:"lineText,omitempty"`
+}
+
 // GetHoverInfo retrieves hover information (type, documentation) for a symbol at the specified position
 func GetHoverInfo(ctx context.Context, client *lsp.Client, filePath string, line, column int) (string, error) {
+	result, err := GetHoverInfoResult(ctx, client, filePath, line, column)
+	if err != nil {
+		return "", err
+	}
+	return result.Text(), nil
+}
+
+// GetHoverInfoResult retrieves hover information for the one-indexed position
+func GetHoverInfoResult(ctx context.Context, client *lsp.Client, filePath string, line, column int) (*HoverResult, error) {
+	filePath, err := readablePath(filePath)
+	if err != nil {
+		return nil, err
+	}
+
 	// Open the file if not already open
-	err := client.OpenFile(ctx, filePath)
+	err = client.OpenFile(ctx, filePath)
 	if err != nil {
-		return "", fmt.Errorf("could not open file: %v", err)
+		return nil, fmt.Errorf("could not open file: %v", err)
 	}
 
 	params := protocol.HoverParams{}
@@ -41,14 +67,22 @@ func GetHoverInfo(ctx context.Context, client *lsp.Client, filePath string, line
 		} else if errors.Is(err, lsp.ErrContentModified) {
 			continue
 		} else if i == 2 {
-			return "", fmt.Errorf("failed to get hover information: %v", err)
+			return nil, fmt.Errorf("failed to get hover information: %v", err)
 		}
 	}
 
-	var result strings.Builder
+	result := &HoverResult{
+		File:     utilities.DisplayPath(filePath),
+		Position: newPosition(position),
+		Contents: hoverResult.Contents.Value,
+	}
+	if hoverResult.Range != (protocol.Range{}) {
+		rng := newRange(hoverResult.Range)
+		result.Range = &rng
+	}
 
 	// Process the hover contents based on Markup content
-	if hoverResult.Contents.Value == "" {
+	if result.Contents == "" {
 		// Extract the line where the hover was requested
 		lineText, err := ExtractTextFromLocation(protocol.Location{
 			URI: uri,
@@ -66,10 +100,34 @@ func GetHoverInfo(ctx context.Context, client *lsp.Client, filePath string, line
 		if err != nil {
 			toolsLogger.Warn("failed to extract line at position: %v", err)
 		}
-		result.WriteString(fmt.Sprintf("No hover information available for this position on the following line:\n%s", lineText))
-	} else {
-		result.WriteString(hoverResult.Contents.Value)
+		result.LineText = lineText
+	}
+
+	return result, nil
+}
+
+// Text renders the hover contents, or the requested line when there are none
+func (r *HoverResult) Text() string {
+	if r.Contents == "" {
+		return fmt.Sprintf("No hover information available for this position on the following line:\n%s", r.LineText)
+	}
+	return r.Contents
+}
+
+// GetSymbolHover returns hover information for a symbol found by name. A
+// name that matches several symbols returns the candidates instead.
+func GetSymbolHover(ctx context.Context, client *lsp.Client, symbolName string) (string, error) {
+	symbols, candidates, err := resolveUnambiguous(ctx, client, ParseSymbolQuery(symbolName))
+	if err != nil {
+		return "", err
+	}
+	if len(candidates) > 0 {
+		return ambiguityText(symbolName, candidates), nil
+	}
+	if len(symbols) == 0 {
+		return "", fmt.Errorf("symbol %s not found", symbolName)
 	}
 
-	return result.String(), nil
+	loc := symbols[0].Location
+	return GetHoverInfo(ctx, client, loc.URI.Path(), int(loc.Range.Start.Line)+1, int(loc.Range.Start.Character)+1)
 }
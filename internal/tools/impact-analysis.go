@@ -0,0 +1,413 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
+)
+
+// Why a file is affected by a change to a symbol
+const (
+	ImpactDeclaration    = "declaration"
+	ImpactReference      = "reference"
+	ImpactCaller         = "caller"
+	ImpactImplementation = "implementation"
+	ImpactSubtype        = "subtype"
+)
+
+// Symbol kinds each lookup applies to, as servers fail the others
+var (
+	callableKinds = map[protocol.SymbolKind]bool{
+		protocol.Function: true, protocol.Method: true, protocol.Constructor: true,
+	}
+	implementableKinds = map[protocol.SymbolKind]bool{
+		protocol.Interface: true, protocol.Class: true, protocol.Struct: true, protocol.Method: true,
+	}
+	subtypableKinds = map[protocol.SymbolKind]bool{
+		protocol.Interface: true, protocol.Class: true, protocol.Struct: true,
+	}
+)
+
+// Test files by naming convention: Go, Python, JavaScript and TypeScript,
+// Java, Kotlin and C#, and the test directories of Rust and most others
+var testFilePattern = regexp.MustCompile(`(^|/)(` +
+	`[^/]+_test\.go|` +
+	`test_[^/]+\.py|[^/]+_test\.py|` +
+	`[^/]+\.(test|spec)\.[cm]?[jt]sx?|` +
+	`[^/]+Tests?\.(java|kt|cs)|` +
+	`(tests?|__tests__|spec)/.+)$`)
+
+// isTestFile reports whether a path names a test file
+func isTestFile(path string) bool {
+	return testFilePattern.MatchString(filepath.ToSlash(path))
+}
+
+// ImpactResult is what a change to the symbols matching a name may affect,
+// grouped by how far from the symbol each file is
+type ImpactResult struct {
+	Symbol string `json:"symbol"`
+	// Depth is how many levels of callers and subtypes were followed
+	Depth int `json:"depth"`
+	// Levels start with the files declaring the symbol at distance 0, then
+	// the files referencing it, calling it or implementing it at distance 1,
+	// and the files calling those callers or subtyping those subtypes further
+	// out
+	Levels    []ImpactLevel `json:"levels"`
+	Files     int           `json:"files"`
+	Packages  int           `json:"packages"`
+	TestFiles int           `json:"testFiles"`
+	// Errors are the lookups that failed, leaving the result incomplete
+	Errors []string `json:"errors,omitempty"`
+	// Candidates are the symbols the name matches when it is ambiguous, in
+	// which case nothing is looked up
+	Candidates []SymbolCandidate `json:"candidates,omitempty"`
+}
+
+// ImpactLevel is the files at one distance from the symbol
+type ImpactLevel struct {
+	Distance int            `json:"distance"`
+	Files    []ImpactedFile `json:"files"`
+	// Packages are the directories first reached at this distance, which are
+	// packages in Go and modules or namespaces in most other languages
+	Packages []string `json:"packages"`
+	// TestFiles are the files of this level that hold tests
+	TestFiles []string `json:"testFiles"`
+}
+
+// ImpactedFile is a file a change may affect and why
+type ImpactedFile struct {
+	File    string   `json:"file"`
+	Package string   `json:"package"`
+	Test    bool     `json:"test,omitempty"`
+	Reasons []string `json:"reasons"`
+	// Symbols are the callers and subtypes found in the file
+	Symbols []string `json:"symbols,omitempty"`
+}
+
+// AnalyzeImpact returns what a change to every symbol matching a name may
+// affect, up to maxDepth levels of callers and subtypes
+func AnalyzeImpact(ctx context.Context, client *lsp.Client, symbolName string, maxDepth int) (string, error) {
+	query := ParseSymbolQuery(symbolName)
+	query.All = true
+	result, err := AnalyzeImpactResult(ctx, client, query, maxDepth)
+	if err != nil {
+		return "", err
+	}
+	return result.Text(), nil
+}
+
+// AnalyzeImpactResult combines the references, callers, implementations and
+// subtypes of the symbols matching a query into the files, packages and tests
+// a change to them may affect. Only files of the workspace are included:
+// files outside of it, including those reached through symlinks, and
+// dependencies installed or vendored in it are left out.
+func AnalyzeImpactResult(ctx context.Context, client *lsp.Client, query SymbolQuery, maxDepth int) (*ImpactResult, error) {
+	if maxDepth < 1 || maxDepth > MaxCallHierarchyDepth {
+		return nil, fmt.Errorf("depth must be between 1 and %d, got %d", MaxCallHierarchyDepth, maxDepth)
+	}
+
+	symbols, candidates, err := resolveUnambiguous(ctx, client, query)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ImpactResult{Symbol: query.Name, Depth: maxDepth, Levels: []ImpactLevel{}, Candidates: candidates}
+	impact := &impactCollector{files: make(map[string]*impactedFile)}
+	for _, symbol := range symbols {
+		impact.add(symbol.Location.URI, 0, ImpactDeclaration, "")
+
+		if err := client.OpenFile(ctx, symbol.Location.URI.Path()); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: could not open file: %v", symbol.Name, err))
+			continue
+		}
+		position := protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: symbol.Location.URI},
+			Position:     symbol.Location.Range.Start,
+		}
+
+		steps := []struct {
+			name    string
+			applies bool
+			run     func() error
+		}{
+			{"references", true, func() error { return impact.addReferences(ctx, client, position) }},
+			{"callers", callableKinds[symbol.Kind], func() error { return impact.addCallers(ctx, client, position, maxDepth) }},
+			{"implementations", implementableKinds[symbol.Kind], func() error { return impact.addImplementations(ctx, client, position) }},
+			{"subtypes", subtypableKinds[symbol.Kind], func() error { return impact.addSubtypes(ctx, client, position, maxDepth) }},
+		}
+		for _, step := range steps {
+			if !step.applies {
+				continue
+			}
+			if err := step.run(); err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("%s: failed to get %s: %v", symbol.Name, step.name, err))
+			}
+		}
+	}
+
+	impact.group(result)
+	return result, nil
+}
+
+// impactedFile is a file found while collecting the impact of a change
+type impactedFile struct {
+	distance int
+	reasons  []string
+	symbols  []string
+}
+
+// impactCollector records the affected files by their display path, each at
+// the shortest distance it is reached at
+type impactCollector struct {
+	files map[string]*impactedFile
+}
+
+// add records that the file of uri is affected at a distance, skipping
+// files outside the workspace
+func (c *impactCollector) add(uri protocol.DocumentUri, distance int, reason string, symbol string) {
+	path := uri.Path()
+	if utilities.IsExternalPath(path) {
+		return
+	}
+	if workspace := utilities.WorkspaceDir(); workspace != "" && utilities.CheckPathWithinRoot(workspace, path) != nil {
+		return
+	}
+	path = utilities.DisplayPath(path)
+
+	file, ok := c.files[path]
+	if !ok {
+		file = &impactedFile{distance: distance}
+		c.files[path] = file
+	}
+	file.distance = min(file.distance, distance)
+	if !slices.Contains(file.reasons, reason) {
+		file.reasons = append(file.reasons, reason)
+	}
+	if symbol != "" && !slices.Contains(file.symbols, symbol) {
+		file.symbols = append(file.symbols, symbol)
+	}
+}
+
+func (c *impactCollector) addReferences(ctx context.Context, client *lsp.Client, position protocol.TextDocumentPositionParams) error {
+	refs, err := client.References(ctx, protocol.ReferenceParams{TextDocumentPositionParams: position})
+	if err != nil {
+		return err
+	}
+	for _, ref := range refs {
+		c.add(ref.URI, 1, ImpactReference, "")
+	}
+	return nil
+}
+
+// addCallers follows the callers up to maxDepth levels, each at the distance
+// of its level
+func (c *impactCollector) addCallers(ctx context.Context, client *lsp.Client, position protocol.TextDocumentPositionParams, maxDepth int) error {
+	if client.ServerCapabilities().CallHierarchyProvider == nil {
+		return nil
+	}
+	items, prepareErr := client.PrepareCallHierarchy(ctx, protocol.CallHierarchyPrepareParams{TextDocumentPositionParams: position})
+	if prepareErr != nil {
+		return prepareErr
+	}
+
+	match := CallHierarchyMatch{}
+	walker := newCallHierarchyWalker(client, CallHierarchyOptions{MaxDepth: maxDepth, ExcludeExternal: true}, incomingCalls)
+	for _, item := range items {
+		walker.walk(ctx, hierarchyCall{item: item}, &match, -1, 0)
+	}
+	var err error
+	for _, call := range match.Calls {
+		if call.Depth > 0 {
+			c.add(call.item.URI, call.Depth, ImpactCaller, call.Name)
+		}
+		if call.Error != "" && err == nil {
+			err = fmt.Errorf("%s: %s", call.Name, call.Error)
+		}
+	}
+	return err
+}
+
+func (c *impactCollector) addImplementations(ctx context.Context, client *lsp.Client, position protocol.TextDocumentPositionParams) error {
+	if client.ServerCapabilities().ImplementationProvider == nil {
+		return nil
+	}
+	result, err := client.Implementation(ctx, protocol.ImplementationParams{TextDocumentPositionParams: position})
+	if err != nil {
+		return err
+	}
+	for _, loc := range implementationLocations(result) {
+		c.add(loc.URI, 1, ImpactImplementation, "")
+	}
+	return nil
+}
+
+// addSubtypes follows the subtypes up to maxDepth levels, each at the
+// distance of its level
+func (c *impactCollector) addSubtypes(ctx context.Context, client *lsp.Client, position protocol.TextDocumentPositionParams, maxDepth int) error {
+	if client.ServerCapabilities().TypeHierarchyProvider == nil {
+		return nil
+	}
+	level, err := client.PrepareTypeHierarchy(ctx, protocol.TypeHierarchyPrepareParams{TextDocumentPositionParams: position})
+	if err != nil {
+		return err
+	}
+
+	visited := make(map[callItemKey]bool)
+	for _, item := range level {
+		visited[callItemKey{uri: item.URI, start: item.SelectionRange.Start}] = true
+	}
+	for distance := 1; distance <= maxDepth && len(level) > 0; distance++ {
+		var next []protocol.TypeHierarchyItem
+		for _, item := range level {
+			subtypes, err := client.Subtypes(ctx, protocol.TypeHierarchySubtypesParams{Item: item})
+			if err != nil {
+				return err
+			}
+			for _, subtype := range subtypes {
+				key := callItemKey{uri: subtype.URI, start: subtype.SelectionRange.Start}
+				if visited[key] {
+					continue
+				}
+				visited[key] = true
+				c.add(subtype.URI, distance, ImpactSubtype, subtype.Name)
+				next = append(next, subtype)
+			}
+		}
+		level = next
+	}
+	return nil
+}
+
+// implementationLocations flattens the locations and location links an
+// implementation request may return
+func implementationLocations(result protocol.Or_Result_textDocument_implementation) []protocol.Location {
+	switch value := result.Value.(type) {
+	case protocol.Definition:
+		switch definition := value.Value.(type) {
+		case protocol.Location:
+			return []protocol.Location{definition}
+		case []protocol.Location:
+			return definition
+		}
+	case []protocol.DefinitionLink:
+		locations := make([]protocol.Location, len(value))
+		for i, link := range value {
+			locations[i] = protocol.Location{URI: link.TargetURI, Range: link.TargetSelectionRange}
+		}
+		return locations
+	}
+	return nil
+}
+
+// group sorts the collected files into levels by distance, listing each
+// package at the shortest distance of its files
+func (c *impactCollector) group(result *ImpactResult) {
+	paths := make([]string, 0, len(c.files))
+	for path := range c.files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	packageDistance := make(map[string]int)
+	for _, path := range paths {
+		pkg := filepath.Dir(path)
+		if distance, ok := packageDistance[pkg]; !ok || c.files[path].distance < distance {
+			packageDistance[pkg] = c.files[path].distance
+		}
+	}
+
+	levels := make(map[int]*ImpactLevel)
+	level := func(distance int) *ImpactLevel {
+		if levels[distance] == nil {
+			levels[distance] = &ImpactLevel{Distance: distance, Files: []ImpactedFile{}, Packages: []string{}, TestFiles: []string{}}
+		}
+		return levels[distance]
+	}
+	for _, path := range paths {
+		file := c.files[path]
+		impacted := ImpactedFile{
+			File:    path,
+			Package: filepath.Dir(path),
+			Test:    isTestFile(path),
+			Reasons: file.reasons,
+			Symbols: file.symbols,
+		}
+		l := level(file.distance)
+		l.Files = append(l.Files, impacted)
+		if impacted.Test {
+			l.TestFiles = append(l.TestFiles, path)
+			result.TestFiles++
+		}
+	}
+	for pkg, distance := range packageDistance {
+		l := level(distance)
+		l.Packages = append(l.Packages, pkg)
+	}
+
+	for _, l := range levels {
+		sort.Strings(l.Packages)
+		result.Levels = append(result.Levels, *l)
+	}
+	sort.Slice(result.Levels, func(i, j int) bool {
+		return result.Levels[i].Distance < result.Levels[j].Distance
+	})
+	result.Files = len(paths)
+	result.Packages = len(packageDistance)
+}
+
+// Text renders each level with its files, packages and tests
+func (r *ImpactResult) Text() string {
+	if len(r.Candidates) > 0 {
+		return ambiguityText(r.Symbol, r.Candidates)
+	}
+
+	var result strings.Builder
+	fmt.Fprintf(&result, "Impact of changing %s: %s in %s, %s\n",
+		r.Symbol, countOf(r.Files, "file"), countOf(r.Packages, "package"), countOf(r.TestFiles, "test file"))
+	for _, err := range r.Errors {
+		fmt.Fprintf(&result, "Error: %s\n", err)
+	}
+
+	for _, level := range r.Levels {
+		switch level.Distance {
+		case 0:
+			result.WriteString("\nDistance 0, the symbol itself:\n")
+		case 1:
+			result.WriteString("\nDistance 1, direct uses:\n")
+		default:
+			fmt.Fprintf(&result, "\nDistance %d:\n", level.Distance)
+		}
+
+		for _, file := range level.Files {
+			details := strings.Join(file.Reasons, ", ")
+			if len(file.Symbols) > 0 {
+				details += ": " + strings.Join(file.Symbols, ", ")
+			}
+			fmt.Fprintf(&result, "  %s (%s)\n", file.File, details)
+		}
+		if len(level.Packages) > 0 {
+			fmt.Fprintf(&result, "  Packages: %s\n", strings.Join(level.Packages, ", "))
+		}
+		if len(level.TestFiles) > 0 {
+			fmt.Fprintf(&result, "  Test files: %s\n", strings.Join(level.TestFiles, ", "))
+		}
+	}
+
+	return result.String()
+}
+
+// countOf formats a count of things, as in 1 file or 2 files
+func countOf(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
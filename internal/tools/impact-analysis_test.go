@@ -0,0 +1,90 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsTestFile(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected bool
+	}{
+		{"pkg/parse_test.go", true},
+		{"pkg/parse.go", false},
+		{"tests/test_parse.py", true},
+		{"app/parse_test.py", true},
+		{"app/testing.py", false},
+		{"src/parse.test.ts", true},
+		{"src/parse.spec.jsx", true},
+		{"src/__tests__/parse.js", true},
+		{"src/main/java/ParserTest.java", true},
+		{"tests/integration.rs", true},
+		{"src/lib.rs", false},
+		{"latest/parse.go", false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.path, func(t *testing.T) {
+			assert.Equal(t, tc.expected, isTestFile(tc.path))
+		})
+	}
+}
+
+func TestImpactGroup(t *testing.T) {
+	impact := &impactCollector{files: make(map[string]*impactedFile)}
+	uri := func(path string) protocol.DocumentUri {
+		return protocol.DocumentUri("file:///ws/" + path)
+	}
+	impact.add(uri("parse/parse.go"), 0, ImpactDeclaration, "")
+	impact.add(uri("eval/eval.go"), 1, ImpactReference, "")
+	impact.add(uri("eval/eval.go"), 1, ImpactCaller, "Eval")
+	impact.add(uri("eval/eval_test.go"), 2, ImpactCaller, "TestEval")
+	impact.add(uri("parse/parse_test.go"), 2, ImpactCaller, "TestParse")
+	impact.add(uri("parse/parse_test.go"), 1, ImpactReference, "")
+
+	result := &ImpactResult{Symbol: "Parse", Levels: []ImpactLevel{}}
+	impact.group(result)
+
+	assert.Equal(t, 4, result.Files)
+	assert.Equal(t, 2, result.Packages)
+	assert.Equal(t, 2, result.TestFiles)
+	assert.Equal(t, "Impact of changing Parse: 4 files in 2 packages, 2 test files\n"+
+		"\nDistance 0, the symbol itself:\n"+
+		"  /ws/parse/parse.go (declaration)\n"+
+		"  Packages: /ws/parse\n"+
+		"\nDistance 1, direct uses:\n"+
+		"  /ws/eval/eval.go (reference, caller: Eval)\n"+
+		"  /ws/parse/parse_test.go (caller, reference: TestParse)\n"+
+		"  Packages: /ws/eval\n"+
+		"  Test files: /ws/parse/parse_test.go\n"+
+		"\nDistance 2:\n"+
+		"  /ws/eval/eval_test.go (caller: TestEval)\n"+
+		"  Test files: /ws/eval/eval_test.go\n", result.Text())
+}
+
+func TestImpactOutsideWorkspace(t *testing.T) {
+	dir := t.TempDir()
+	workspace := filepath.Join(dir, "ws")
+	sibling := filepath.Join(dir, "sibling")
+	require.NoError(t, os.MkdirAll(workspace, 0755))
+	require.NoError(t, os.MkdirAll(sibling, 0755))
+	require.NoError(t, os.Symlink(sibling, filepath.Join(workspace, "linked")))
+	utilities.SetWorkspacePaths(workspace, false, nil)
+	defer utilities.SetWorkspacePaths("", false, nil)
+
+	impact := &impactCollector{files: make(map[string]*impactedFile)}
+	impact.add(protocol.URIFromPath(filepath.Join(workspace, "parse.go")), 0, ImpactDeclaration, "")
+	impact.add(protocol.URIFromPath(filepath.Join(sibling, "eval.go")), 1, ImpactReference, "")
+	impact.add(protocol.URIFromPath(filepath.Join(workspace, "linked", "eval.go")), 1, ImpactReference, "")
+	impact.add(protocol.URIFromPath(filepath.Join(workspace, "vendor", "lib", "lib.go")), 1, ImpactReference, "")
+
+	assert.Len(t, impact.files, 1)
+	assert.Contains(t, impact.files, utilities.DisplayPath(filepath.Join(workspace, "parse.go")))
+}
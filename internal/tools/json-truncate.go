@@ -0,0 +1,197 @@
+package tools
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"unicode/utf8"
+)
+
+// jsonNode is a decoded JSON value that keeps the order of object keys, so
+// that a shortened result is marshaled in the same order as the original
+type jsonNode struct {
+	object bool
+	array  bool
+	keys   []string
+	// values are the object values or array elements
+	values []*jsonNode
+	str    *string
+	// raw holds numbers, booleans and null
+	raw json.RawMessage
+}
+
+func decodeJSONNode(dec *json.Decoder) (*jsonNode, error) {
+	token, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch t := token.(type) {
+	case json.Delim:
+		node := &jsonNode{object: t == '{', array: t == '['}
+		for dec.More() {
+			if node.object {
+				key, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				node.keys = append(node.keys, key.(string))
+			}
+			value, err := decodeJSONNode(dec)
+			if err != nil {
+				return nil, err
+			}
+			node.values = append(node.values, value)
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		return node, nil
+	case string:
+		return &jsonNode{str: &t}, nil
+	default:
+		raw, err := json.Marshal(t)
+		return &jsonNode{raw: raw}, err
+	}
+}
+
+// write writes the node as compact JSON
+func (n *jsonNode) write(w *bytes.Buffer) {
+	switch {
+	case n.object:
+		w.WriteByte('{')
+		for i, key := range n.keys {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			data, _ := json.Marshal(key)
+			w.Write(data)
+			w.WriteByte(':')
+			n.values[i].write(w)
+		}
+		w.WriteByte('}')
+	case n.array:
+		w.WriteByte('[')
+		for i, value := range n.values {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			value.write(w)
+		}
+		w.WriteByte(']')
+	case n.str != nil:
+		data, _ := json.Marshal(*n.str)
+		w.Write(data)
+	default:
+		w.Write(n.raw)
+	}
+}
+
+// size returns the length of the node as compact JSON, and the largest list
+// or string within it that can still be shortened
+func (n *jsonNode) size() (int, *jsonNode, int) {
+	var largest *jsonNode
+	largestSize := 0
+	consider := func(node *jsonNode, size int) {
+		if size > largestSize {
+			largest, largestSize = node, size
+		}
+	}
+
+	total := 2
+	switch {
+	case n.object || n.array:
+		for i, value := range n.values {
+			size, inner, innerSize := value.size()
+			total += size + 1
+			if n.object {
+				total += len(n.keys[i]) + 3
+			}
+			if inner != nil {
+				consider(inner, innerSize)
+			}
+		}
+		if n.array && len(n.values) > 1 {
+			consider(n, total)
+		}
+	case n.str != nil:
+		total += len(*n.str)
+		if len(*n.str) > minTruncatedString {
+			consider(n, total)
+		}
+	default:
+		total = len(n.raw)
+	}
+	return total, largest, largestSize
+}
+
+// minTruncatedString is the length below which strings are left whole
+const minTruncatedString = 40
+
+// shorten halves a list or string, keeping its start
+func (n *jsonNode) shorten() {
+	if n.array {
+		n.values = n.values[:len(n.values)/2]
+		return
+	}
+	cut := len(*n.str) / 2
+	for cut > 0 && !utf8.RuneStart((*n.str)[cut]) {
+		cut--
+	}
+	shortened := (*n.str)[:cut] + "…"
+	n.str = &shortened
+}
+
+// truncateJSON marshals a result, shortening its longest lists and strings
+// until its JSON fits in maxChars, and sets "truncated" on results that had
+// to be shortened. The indented JSON must fit if indented is set, and the
+// compact JSON otherwise. It returns the compact JSON to send as
+// structured content, the indented JSON to show as text and whether
+// anything was left out.
+func truncateJSON(result any, maxChars int, indented bool) (json.RawMessage, string, bool, error) {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, "", false, fmt.Errorf("failed to marshal result: %v", err)
+	}
+	indent := func(compact []byte) string {
+		var out bytes.Buffer
+		_ = json.Indent(&out, compact, "", "  ")
+		return out.String()
+	}
+	fits := func(compact []byte) bool {
+		if indented {
+			return len(indent(compact)) <= maxChars
+		}
+		return len(compact) <= maxChars
+	}
+	if maxChars <= 0 || fits(data) {
+		return data, indent(data), false, nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	root, err := decodeJSONNode(dec)
+	if err != nil && err != io.EOF {
+		return nil, "", false, fmt.Errorf("failed to truncate result: %v", err)
+	}
+	render := func() []byte {
+		var out bytes.Buffer
+		root.write(&out)
+		return out.Bytes()
+	}
+
+	if root.object {
+		root.keys = append(root.keys, "truncated")
+		root.values = append(root.values, &jsonNode{raw: json.RawMessage("true")})
+	}
+	compact := render()
+	for !fits(compact) {
+		_, largest, _ := root.size()
+		if largest == nil {
+			break
+		}
+		largest.shorten()
+		compact = render()
+	}
+	return compact, indent(compact), true, nil
+}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/isaacphi/mcp-language-server/internal/lsp"
 	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
 )
 
 type match struct {
@@ -86,6 +87,10 @@ func GetFullDefinition(ctx context.Context, client *lsp.Client, startLocation pr
 			return "", protocol.Location{}, nil, fmt.Errorf("failed to unescape URI: %w", err)
 		}
 
+		if err := utilities.CheckReadAccess(filePath); err != nil {
+			return "", protocol.Location{}, nil, err
+		}
+
 		// Read the file to get the full lines of the definition
 		// because we may have a start and end column
 		content, err := os.ReadFile(filePath)
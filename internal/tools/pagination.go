@@ -0,0 +1,191 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// charsPerToken is a rough estimate used to turn a token budget into a
+// character limit
+const charsPerToken = 4
+
+// Page describes which items of a paged result a response holds
+type Page struct {
+	// Start and End are the zero-based offsets of the items shown
+	Start int `json:"start"`
+	End   int `json:"end"`
+	Total int `json:"total"`
+	// NextCursor fetches the next page, and is empty on the last page
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+// PagedResult is a result made of a list of items that can be split into
+// pages. The items must be in a stable order so that cursors stay valid
+// across calls.
+type PagedResult interface {
+	Result
+	// Len returns the number of items
+	Len() int
+	// Slice returns a copy holding only items [start, end) and page
+	Slice(start, end int, page *Page) PagedResult
+	// ItemName is the plural name of the items, such as "files"
+	ItemName() string
+}
+
+// ParseCursor checks a cursor returned in a previous page and returns the
+// offset of the first item it points to
+func ParseCursor(cursor string) (int, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+	offset, err := strconv.Atoi(cursor)
+	if err != nil || offset < 0 {
+		return 0, fmt.Errorf("invalid cursor: %q, pass the nextCursor of a previous response", cursor)
+	}
+	return offset, nil
+}
+
+// Paginate renders result as text or JSON within a budget of maxTokens. Paged
+// results are cut into pages of whole items starting at cursor, and a summary
+// of the items left out is appended. Output that still does not fit, such as
+// a single large item, is truncated: text at the last line that fits, and
+// JSON by shortening its longest lists and strings and setting "truncated",
+// so that it still parses. It returns the structured content, which is kept
+// within the budget the same way, along with the rendered text. A maxTokens
+// of zero or less means no limit.
+func Paginate(result Result, cursor string, maxTokens int, format string) (any, string, error) {
+	maxChars := maxTokens * charsPerToken
+
+	// finish limits the structured content and text of the result to send,
+	// with summary appended to text output
+	finish := func(r Result, summary string) (any, string, error) {
+		compact, jsonText, truncated, err := truncateJSON(r, maxChars, format == "json")
+		if err != nil {
+			return nil, "", err
+		}
+		var structured any = r
+		if truncated {
+			structured = compact
+		}
+		if format == "json" {
+			return structured, jsonText, nil
+		}
+
+		text := r.Text()
+		limit := maxChars
+		if summary != "" {
+			text = strings.TrimRight(text, "\n")
+			if limit > 0 {
+				limit = max(limit-len(summary), 1)
+			}
+		}
+		return structured, truncateOutput(text, limit) + summary, nil
+	}
+
+	paged, ok := result.(PagedResult)
+	if !ok {
+		return finish(result, "")
+	}
+
+	start, err := ParseCursor(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+	total := paged.Len()
+	if start > total {
+		return nil, "", fmt.Errorf("cursor %s is past the last of %d %s, the result may have changed since the previous page", cursor, total, paged.ItemName())
+	}
+
+	// renderPage renders items [start, end) along with the summary that is
+	// appended to text output when there are other pages. The size of the
+	// page is that of its text, or of its structured content if larger.
+	renderPage := func(end int) (PagedResult, int, string, error) {
+		page := &Page{Start: start, End: end, Total: total}
+		if end < total {
+			page.NextCursor = strconv.Itoa(end)
+		}
+		sliced := paged.Slice(start, end, page)
+
+		if format == "json" {
+			// JSON output already carries the page
+			data, err := json.MarshalIndent(sliced, "", "  ")
+			if err != nil {
+				return nil, 0, "", fmt.Errorf("failed to marshal result: %v", err)
+			}
+			return sliced, len(data), "", nil
+		}
+
+		var summary string
+		if start > 0 || end < total {
+			summary = "\n\n" + page.summary(paged.ItemName())
+		}
+		data, err := json.Marshal(sliced)
+		if err != nil {
+			return nil, 0, "", fmt.Errorf("failed to marshal result: %v", err)
+		}
+		return sliced, max(len(sliced.Text())+len(summary), len(data)), summary, nil
+	}
+
+	// Find the largest page that fits, but always show at least one item.
+	// Rendering the page as a whole accounts for headers and separators.
+	end := total
+	if maxChars > 0 && start < total {
+		var renderErr error
+		fits := sort.Search(total-start, func(i int) bool {
+			_, size, _, err := renderPage(start + i + 1)
+			if err != nil {
+				renderErr = err
+			}
+			return size > maxChars
+		})
+		if renderErr != nil {
+			return nil, "", renderErr
+		}
+		end = start + max(fits, 1)
+	}
+
+	sliced, _, summary, err := renderPage(end)
+	if err != nil {
+		return nil, "", err
+	}
+	return finish(sliced, summary)
+}
+
+// summary tells the agent which items it is seeing and how to get the rest
+func (p *Page) summary(itemName string) string {
+	shown := fmt.Sprintf("Showing %s %d-%d of %d.", itemName, p.Start+1, p.End, p.Total)
+	if p.Start >= p.End {
+		shown = fmt.Sprintf("No %s left, all %d were shown on previous pages.", itemName, p.Total)
+	}
+	if p.NextCursor == "" {
+		return "[" + shown + " This is the last page.]"
+	}
+	return fmt.Sprintf("[%s %d more %s omitted. Call again with cursor %q for the next page.]",
+		shown, p.Total-p.End, itemName, p.NextCursor)
+}
+
+// truncateOutput cuts text that is longer than maxChars at the last line
+// that fits and notes how much was left out
+func truncateOutput(text string, maxChars int) string {
+	if maxChars <= 0 || len(text) <= maxChars {
+		return text
+	}
+
+	cut := maxChars
+	// Prefer ending on a whole line unless that drops most of the output
+	if i := strings.LastIndexByte(text[:cut], '\n'); i > maxChars/2 {
+		cut = i + 1
+	}
+	// Do not split a multi-byte character
+	for cut > 0 && cut < len(text) && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+
+	omitted := text[cut:]
+	return fmt.Sprintf("%s\n... [Output truncated to fit the output budget: %d more lines (%d characters) omitted.]",
+		strings.TrimRight(text[:cut], "\n"), strings.Count(strings.TrimRight(omitted, "\n"), "\n")+1, len(omitted))
+}
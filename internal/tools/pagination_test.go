@@ -0,0 +1,159 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testCommands(n int) *CommandsResult {
+	result := &CommandsResult{}
+	for i := range n {
+		result.Commands = append(result.Commands, fmt.Sprintf("server.command%02d", i))
+	}
+	return result
+}
+
+func TestPaginateWithoutLimit(t *testing.T) {
+	result := testCommands(30)
+
+	page, text, err := Paginate(result, "", 0, "text")
+	require.NoError(t, err)
+
+	assert.Equal(t, result.Text(), text)
+	assert.Len(t, page.(*CommandsResult).Commands, 30)
+}
+
+func TestPaginatePages(t *testing.T) {
+	result := testCommands(30)
+
+	var seen []string
+	cursor := ""
+	for pages := 0; ; pages++ {
+		require.Less(t, pages, 30, "pagination did not finish")
+
+		page, text, err := Paginate(result, cursor, 50, "text")
+		require.NoError(t, err)
+		assert.LessOrEqual(t, len(text), 50*charsPerToken)
+
+		commands := page.(*CommandsResult)
+		require.NotNil(t, commands.Page)
+		assert.Equal(t, len(seen), commands.Page.Start)
+		assert.Equal(t, 30, commands.Page.Total)
+		for _, command := range commands.Commands {
+			assert.Contains(t, text, command)
+		}
+		seen = append(seen, commands.Commands...)
+
+		if commands.Page.NextCursor == "" {
+			assert.Contains(t, text, "This is the last page.")
+			break
+		}
+		assert.Contains(t, text, fmt.Sprintf("Call again with cursor %q for the next page.", commands.Page.NextCursor))
+		cursor = commands.Page.NextCursor
+	}
+
+	assert.Equal(t, result.Commands, seen)
+}
+
+func TestPaginateJSON(t *testing.T) {
+	page, text, err := Paginate(testCommands(30), "10", 50, "json")
+	require.NoError(t, err)
+
+	var decoded CommandsResult
+	require.NoError(t, json.Unmarshal([]byte(text), &decoded))
+	assert.Equal(t, page.(*CommandsResult).Commands, decoded.Commands)
+	require.NotNil(t, decoded.Page)
+	assert.Equal(t, 10, decoded.Page.Start)
+	assert.Equal(t, fmt.Sprint(decoded.Page.End), decoded.Page.NextCursor)
+}
+
+func TestPaginateInvalidCursor(t *testing.T) {
+	_, _, err := Paginate(testCommands(3), "abc", 0, "text")
+	assert.ErrorContains(t, err, "invalid cursor")
+
+	_, _, err = Paginate(testCommands(3), "-1", 0, "text")
+	assert.ErrorContains(t, err, "invalid cursor")
+
+	_, _, err = Paginate(testCommands(3), "4", 0, "text")
+	assert.ErrorContains(t, err, "past the last of 3 commands")
+}
+
+func TestPaginateTruncatesLargeItems(t *testing.T) {
+	var source strings.Builder
+	for i := range 200 {
+		fmt.Fprintf(&source, "line %d of a long definition\n", i)
+	}
+	result := &ContentResult{Symbol: "Big", Location: Location{File: "big.go"}, Source: source.String()}
+
+	page, text, err := Paginate(result, "", 100, "text")
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(text, "Symbol: Big\n"))
+	assert.Contains(t, text, "[Output truncated to fit the output budget:")
+	assert.NotContains(t, text, "line 199 of")
+	assert.Less(t, len(text), 100*charsPerToken+200)
+
+	// The structured content is limited to the budget too
+	structured, ok := page.(json.RawMessage)
+	require.True(t, ok, "expected truncated JSON, got %T", page)
+	assert.LessOrEqual(t, len(structured), 100*charsPerToken)
+	var decoded map[string]any
+	require.NoError(t, json.Unmarshal(structured, &decoded))
+	assert.Equal(t, true, decoded["truncated"])
+}
+
+func TestPaginateTruncatesLargeJSON(t *testing.T) {
+	var source strings.Builder
+	for i := range 200 {
+		fmt.Fprintf(&source, "line %d of a long definition\n", i)
+	}
+	result := &ContentResult{Symbol: "Big", Location: Location{File: "big.go"}, Source: source.String()}
+
+	page, text, err := Paginate(result, "", 100, "json")
+	require.NoError(t, err)
+	assert.LessOrEqual(t, len(text), 100*charsPerToken)
+
+	var decoded struct {
+		ContentResult
+		Truncated bool `json:"truncated"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(text), &decoded), "truncated JSON must still parse:\n%s", text)
+	assert.True(t, decoded.Truncated)
+	assert.Equal(t, "Big", decoded.Symbol)
+	assert.True(t, strings.HasPrefix(decoded.Source, "line 0 of a long definition\n"))
+	assert.NotContains(t, decoded.Source, "line 199 of")
+
+	structured, ok := page.(json.RawMessage)
+	require.True(t, ok, "expected truncated JSON, got %T", page)
+	assert.JSONEq(t, text, string(structured))
+
+	// A single item of a paged result that does not fit is shortened too
+	commands := &CommandsResult{Commands: []string{strings.Repeat("x", 1000)}}
+	_, text, err = Paginate(commands, "", 50, "json")
+	require.NoError(t, err)
+	assert.LessOrEqual(t, len(text), 50*charsPerToken)
+	assert.True(t, json.Valid([]byte(text)), "truncated JSON must still parse:\n%s", text)
+
+	// Results that fit are sent as they are
+	page, _, err = Paginate(result, "", 0, "json")
+	require.NoError(t, err)
+	assert.Same(t, result, page)
+}
+
+func TestTruncateOutput(t *testing.T) {
+	assert.Equal(t, "short", truncateOutput("short", 10))
+	assert.Equal(t, "unlimited", truncateOutput("unlimited", 0))
+
+	text := "first line\nsecond line\nthird line\n"
+	assert.Equal(t, "first line\nsecond line\n... [Output truncated to fit the output budget: 1 more lines (11 characters) omitted.]",
+		truncateOutput(text, 27))
+
+	// Multi-byte characters are never split
+	truncated := truncateOutput(strings.Repeat("é", 10), 5)
+	assert.True(t, strings.HasPrefix(truncated, "éé\n"))
+}
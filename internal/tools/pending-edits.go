@@ -0,0 +1,203 @@
+package tools
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
+)
+
+// maxPreviewLength limits how much of each replacement text is shown
+const maxPreviewLength = 200
+
+// PendingEditsReport is the outcome of listing, approving or rejecting
+// pending edits
+type PendingEditsReport struct {
+	// Action is list, approve or reject
+	Action string        `json:"action"`
+	Edits  []PendingEdit `json:"edits"`
+	// Page is set when the result is split into pages
+	Page *Page `json:"page,omitempty"`
+}
+
+// PendingEdit is a workspace edit requested by the server that is waiting
+// for approval
+type PendingEdit struct {
+	ID       int       `json:"id"`
+	Received time.Time `json:"received"`
+	Label    string    `json:"label,omitempty"`
+	// Changes has one line per file or resource operation in the edit
+	Changes   []string          `json:"changes"`
+	TextEdits []PendingTextEdit `json:"textEdits,omitempty"`
+}
+
+// PendingTextEdit is a replacement a pending edit makes in a file
+type PendingTextEdit struct {
+	File    string `json:"file"`
+	Range   Range  `json:"range"`
+	NewText string `json:"newText"`
+}
+
+// PendingEdits lists, approves or rejects edits requested by the language
+// server that are waiting for approval
+func PendingEdits(client *lsp.Client, action string, id int) (string, error) {
+	result, err := PendingEditsResult(client, action, id)
+	if err != nil {
+		return "", err
+	}
+	return result.Text(), nil
+}
+
+// PendingEditsResult lists, approves or rejects pending edits and returns
+// the edits the action applied to
+func PendingEditsResult(client *lsp.Client, action string, id int) (*PendingEditsReport, error) {
+	switch action {
+	case "", "list":
+		result := &PendingEditsReport{Action: "list", Edits: []PendingEdit{}}
+		for _, edit := range client.PendingEdits() {
+			result.Edits = append(result.Edits, newPendingEdit(edit))
+		}
+		return result, nil
+	case "approve":
+		edit, err := client.ApprovePendingEdit(id)
+		if err != nil {
+			return nil, err
+		}
+		return &PendingEditsReport{Action: action, Edits: []PendingEdit{newPendingEdit(edit)}}, nil
+	case "reject":
+		edit, err := client.RejectPendingEdit(id)
+		if err != nil {
+			return nil, err
+		}
+		return &PendingEditsReport{Action: action, Edits: []PendingEdit{newPendingEdit(edit)}}, nil
+	default:
+		return nil, fmt.Errorf("invalid action: %s (must be list, approve or reject)", action)
+	}
+}
+
+// Text renders the pending edits with a preview of their changes, or what
+// happened to the approved or rejected edit
+func (r *PendingEditsReport) Text() string {
+	switch r.Action {
+	case "approve":
+		edit := r.Edits[0]
+		return fmt.Sprintf("Applied pending edit %d: %s\n%s", edit.ID, edit.label(), strings.Join(edit.Changes, "\n"))
+	case "reject":
+		edit := r.Edits[0]
+		return fmt.Sprintf("Rejected pending edit %d: %s", edit.ID, edit.label())
+	}
+
+	if len(r.Edits) == 0 {
+		return "No pending edits"
+	}
+
+	var output strings.Builder
+	for _, edit := range r.Edits {
+		output.WriteString(fmt.Sprintf("Pending edit %d (received %s): %s\n",
+			edit.ID, edit.Received.Format(time.TimeOnly), edit.label()))
+		output.WriteString(edit.preview())
+		output.WriteRune('\n')
+	}
+	return output.String()
+}
+
+func newPendingEdit(edit lsp.PendingEdit) PendingEdit {
+	pending := PendingEdit{
+		ID:       edit.ID,
+		Received: edit.Received,
+		Label:    edit.Params.Label,
+		Changes:  summarizeWorkspaceEdit(edit.Params.Edit),
+	}
+	if pending.Changes == nil {
+		pending.Changes = []string{}
+	}
+
+	addTextEdits := func(uri protocol.DocumentUri, edits []protocol.TextEdit) {
+		for _, textEdit := range edits {
+			pending.TextEdits = append(pending.TextEdits, PendingTextEdit{
+				File:    utilities.DisplayURI(uri),
+				Range:   newRange(textEdit.Range),
+				NewText: textEdit.NewText,
+			})
+		}
+	}
+
+	for uri, textEdits := range edit.Params.Edit.Changes {
+		addTextEdits(uri, textEdits)
+	}
+	for _, change := range edit.Params.Edit.DocumentChanges {
+		if change.TextDocumentEdit == nil {
+			continue
+		}
+		var textEdits []protocol.TextEdit
+		for _, e := range change.TextDocumentEdit.Edits {
+			if textEdit, err := e.AsTextEdit(); err == nil {
+				textEdits = append(textEdits, textEdit)
+			}
+		}
+		addTextEdits(change.TextDocumentEdit.TextDocument.URI, textEdits)
+	}
+
+	// Changes come from a map, so sort them for a stable preview
+	sort.SliceStable(pending.TextEdits, func(i, j int) bool {
+		a, b := pending.TextEdits[i], pending.TextEdits[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Range.Start.Line != b.Range.Start.Line {
+			return a.Range.Start.Line < b.Range.Start.Line
+		}
+		return a.Range.Start.Column < b.Range.Start.Column
+	})
+
+	return pending
+}
+
+func (e PendingEdit) label() string {
+	if e.Label == "" {
+		return "(unlabeled)"
+	}
+	return e.Label
+}
+
+// preview shows each change of the edit with the ranges it replaces and the
+// new text
+func (e PendingEdit) preview() string {
+	var output strings.Builder
+	for _, line := range e.Changes {
+		output.WriteString(fmt.Sprintf("  %s\n", line))
+	}
+
+	for _, textEdit := range e.TextEdits {
+		newText := textEdit.NewText
+		if len(newText) > maxPreviewLength {
+			newText = newText[:maxPreviewLength] + "..."
+		}
+		output.WriteString(fmt.Sprintf("    %s %s-%s -> %q\n",
+			textEdit.File, textEdit.Range.Start, textEdit.Range.End, newText))
+	}
+
+	return output.String()
+}
+
+// Len returns the number of pending edits
+func (r *PendingEditsReport) Len() int {
+	return len(r.Edits)
+}
+
+// Slice returns a copy holding only the given pending edits
+func (r *PendingEditsReport) Slice(start, end int, page *Page) PagedResult {
+	sliced := *r
+	sliced.Edits = r.Edits[start:end]
+	sliced.Page = page
+	return &sliced
+}
+
+// ItemName names the items a page is made of
+func (r *PendingEditsReport) ItemName() string {
+	return "pending edits"
+}
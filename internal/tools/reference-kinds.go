@@ -0,0 +1,200 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// Kinds of references
+const (
+	ReferenceRead        = "read"
+	ReferenceWrite       = "write"
+	ReferenceCall        = "call"
+	ReferenceImport      = "import"
+	ReferenceTypeUse     = "type-use"
+	ReferenceDeclaration = "declaration"
+)
+
+// ReferenceKinds lists every kind a reference can be classified as
+var ReferenceKinds = []string{ReferenceRead, ReferenceWrite, ReferenceCall, ReferenceImport, ReferenceTypeUse, ReferenceDeclaration}
+
+// Semantic token types that name a type
+var typeTokenTypes = map[string]bool{
+	"type": true, "class": true, "struct": true, "interface": true, "enum": true, "typeParameter": true,
+}
+
+// Symbol kinds that declare a type, for servers without semantic tokens
+var typeSymbolKinds = map[protocol.SymbolKind]bool{
+	protocol.Class: true, protocol.Struct: true, protocol.Interface: true, protocol.Enum: true, protocol.TypeParameter: true,
+}
+
+// Statements that import a name, as in import, from x import, use, using
+// and #include, and the entries of a Go import block
+var importLine = regexp.MustCompile(`^(import\b|from\s+\S+\s+import\b|(pub(\(\w+\))?\s+)?use\s|using\s|#\s*include\b|export\s.*\bfrom\s)|^(\w+\s+|_\s+|\.\s+)?"[^"]*"$`)
+
+// Assignment operators following a name that is written to
+var assignmentOperator = regexp.MustCompile(`^\s*([-+*/%&|^]|<<|>>|&\^|\*\*|//|\?\?)?=[^=]|^\s*(\+\+|--)|^\s*:=`)
+
+// Generic arguments between a name and its call, as in F[int]( or f::<T>(
+var callGenericArgs = regexp.MustCompile(`^\s*(::)?(<[^()]*>|\[[^()]*\])`)
+
+// referenceClassifier tells the kinds of references to one symbol in a file
+// apart, using the highlight kinds and semantic tokens the server reports
+// for it and the text around each reference
+type referenceClassifier struct {
+	lines       []string
+	symbolKind  protocol.SymbolKind
+	declaration *protocol.Position
+	highlights  map[protocol.Position]protocol.DocumentHighlightKind
+	tokenTypes  map[protocol.Position]string
+}
+
+// newReferenceClassifier asks the server about the references in a file.
+// Servers that do not support document highlights or semantic tokens leave
+// the text around each reference to go on.
+func newReferenceClassifier(ctx context.Context, client *lsp.Client, uri protocol.DocumentUri, refs []protocol.Location, lines []string, symbol ResolvedSymbol) *referenceClassifier {
+	classifier := &referenceClassifier{
+		lines:      lines,
+		symbolKind: symbol.Kind,
+		highlights: make(map[protocol.Position]protocol.DocumentHighlightKind),
+		tokenTypes: make(map[protocol.Position]string),
+	}
+	if symbol.Location.URI == uri {
+		classifier.declaration = &symbol.Location.Range.Start
+	}
+	if len(refs) == 0 {
+		return classifier
+	}
+
+	capabilities := client.ServerCapabilities()
+	if capabilities.DocumentHighlightProvider != nil {
+		highlights, err := client.DocumentHighlight(ctx, protocol.DocumentHighlightParams{
+			TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+				TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+				Position:     refs[0].Range.Start,
+			},
+		})
+		if err != nil {
+			toolsLogger.Debug("Failed to get document highlights for %s: %v", uri, err)
+		}
+		for _, highlight := range highlights {
+			classifier.highlights[highlight.Range.Start] = highlight.Kind
+		}
+	}
+
+	if legend, ok := semanticTokensLegend(capabilities); ok {
+		tokens, err := client.SemanticTokensFull(ctx, protocol.SemanticTokensParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+		})
+		if err != nil {
+			toolsLogger.Debug("Failed to get semantic tokens for %s: %v", uri, err)
+		}
+		classifier.tokenTypes = decodeSemanticTokens(tokens.Data, legend)
+	}
+
+	return classifier
+}
+
+// classify returns the kind of one reference
+func (c *referenceClassifier) classify(ref protocol.Range) string {
+	if c.declaration != nil && ref.Start == *c.declaration {
+		return ReferenceDeclaration
+	}
+
+	var line, after string
+	if int(ref.Start.Line) < len(c.lines) {
+		line = strings.TrimRight(c.lines[ref.Start.Line], "\r")
+		if ref.End.Line == ref.Start.Line && int(ref.End.Character) <= len(line) {
+			after = line[ref.End.Character:]
+		}
+	}
+
+	if importLine.MatchString(strings.TrimSpace(line)) {
+		return ReferenceImport
+	}
+
+	highlight, highlighted := c.highlights[ref.Start]
+	if highlight == protocol.Write {
+		return ReferenceWrite
+	}
+	if !highlighted || highlight == protocol.Text {
+		// Without read and write kinds from the server, fall back to
+		// looking for an assignment
+		if assignmentOperator.MatchString(after) {
+			return ReferenceWrite
+		}
+	}
+
+	if isCallSite(after) {
+		return ReferenceCall
+	}
+
+	if tokenType, ok := c.tokenTypes[ref.Start]; ok {
+		if typeTokenTypes[tokenType] {
+			return ReferenceTypeUse
+		}
+	} else if typeSymbolKinds[c.symbolKind] {
+		return ReferenceTypeUse
+	}
+
+	return ReferenceRead
+}
+
+// isCallSite reports whether the text after a name calls it, skipping
+// generic arguments and Rust's macro bang
+func isCallSite(after string) bool {
+	after = strings.TrimPrefix(after, "!")
+	after = callGenericArgs.ReplaceAllString(after, "")
+	return strings.HasPrefix(strings.TrimSpace(after), "(")
+}
+
+// semanticTokensLegend returns the token types and modifiers the server
+// encodes semantic tokens with, if it supports full document requests
+func semanticTokensLegend(capabilities protocol.ServerCapabilities) (protocol.SemanticTokensLegend, bool) {
+	if capabilities.SemanticTokensProvider == nil {
+		return protocol.SemanticTokensLegend{}, false
+	}
+
+	// The provider is either options or registration options, which
+	// both hold the legend
+	data, err := json.Marshal(capabilities.SemanticTokensProvider)
+	if err != nil {
+		return protocol.SemanticTokensLegend{}, false
+	}
+	var options struct {
+		Legend protocol.SemanticTokensLegend `json:"legend"`
+		Full   any                           `json:"full"`
+	}
+	if err := json.Unmarshal(data, &options); err != nil {
+		return protocol.SemanticTokensLegend{}, false
+	}
+	if len(options.Legend.TokenTypes) == 0 || options.Full == nil || options.Full == false {
+		return protocol.SemanticTokensLegend{}, false
+	}
+	return options.Legend, true
+}
+
+// decodeSemanticTokens returns the type of the token starting at each
+// position, from the relative encoding of textDocument/semanticTokens
+func decodeSemanticTokens(data []uint32, legend protocol.SemanticTokensLegend) map[protocol.Position]string {
+	types := make(map[protocol.Position]string)
+	var line, character uint32
+	for i := 0; i+4 < len(data); i += 5 {
+		deltaLine, deltaStart, tokenType := data[i], data[i+1], data[i+3]
+		if deltaLine > 0 {
+			line += deltaLine
+			character = deltaStart
+		} else {
+			character += deltaStart
+		}
+		if int(tokenType) < len(legend.TokenTypes) {
+			types[protocol.Position{Line: line, Character: character}] = legend.TokenTypes[tokenType]
+		}
+	}
+	return types
+}
@@ -0,0 +1,109 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassifyReference(t *testing.T) {
+	lines := []string{
+		`import "fmt"`,
+		`from helpers import parse`,
+		`use crate::types::Point;`,
+		`	total = compute(x)`,
+		`	total += 1`,
+		`	fmt.Println(total)`,
+		`	var p Point`,
+		`	items := Map[string](values)`,
+		`	println!("{}", total);`,
+		`	if total == 3 {`,
+	}
+	at := func(line, start, end uint32) protocol.Range {
+		return protocol.Range{
+			Start: protocol.Position{Line: line, Character: start},
+			End:   protocol.Position{Line: line, Character: end},
+		}
+	}
+	declaration := protocol.Position{Line: 6, Character: 1}
+
+	classifier := &referenceClassifier{
+		lines:       lines,
+		symbolKind:  protocol.Variable,
+		declaration: &declaration,
+		highlights: map[protocol.Position]protocol.DocumentHighlightKind{
+			{Line: 5, Character: 13}: protocol.Write,
+		},
+		tokenTypes: map[protocol.Position]string{
+			{Line: 6, Character: 7}: "type",
+		},
+	}
+
+	tests := []struct {
+		name     string
+		ref      protocol.Range
+		expected string
+	}{
+		{"go import", at(0, 8, 11), ReferenceImport},
+		{"python import", at(1, 20, 25), ReferenceImport},
+		{"rust use", at(2, 18, 23), ReferenceImport},
+		{"assignment", at(3, 1, 6), ReferenceWrite},
+		{"call", at(3, 9, 16), ReferenceCall},
+		{"compound assignment", at(4, 1, 6), ReferenceWrite},
+		{"highlighted as written", at(5, 13, 18), ReferenceWrite},
+		{"type from semantic tokens", at(6, 7, 12), ReferenceTypeUse},
+		{"generic call", at(7, 10, 13), ReferenceCall},
+		{"macro", at(8, 1, 8), ReferenceCall},
+		{"comparison", at(9, 4, 9), ReferenceRead},
+		{"declaration", at(6, 1, 4), ReferenceDeclaration},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, classifier.classify(tc.ref))
+		})
+	}
+
+	// Without semantic tokens, references to types are type uses
+	classifier = &referenceClassifier{lines: lines, symbolKind: protocol.Struct}
+	assert.Equal(t, ReferenceTypeUse, classifier.classify(at(6, 7, 12)))
+}
+
+func TestDecodeSemanticTokens(t *testing.T) {
+	legend := protocol.SemanticTokensLegend{TokenTypes: []string{"namespace", "type", "function"}}
+	data := []uint32{
+		1, 2, 3, 0, 0, // line 1, character 2: namespace
+		0, 5, 4, 2, 0, // line 1, character 7: function
+		2, 4, 5, 1, 0, // line 3, character 4: type
+		0, 1, 1, 9, 0, // unknown type
+	}
+
+	assert.Equal(t, map[protocol.Position]string{
+		{Line: 1, Character: 2}: "namespace",
+		{Line: 1, Character: 7}: "function",
+		{Line: 3, Character: 4}: "type",
+	}, decodeSemanticTokens(data, legend))
+}
+
+func TestSemanticTokensLegend(t *testing.T) {
+	capabilities := protocol.ServerCapabilities{
+		SemanticTokensProvider: map[string]any{
+			"legend": map[string]any{"tokenTypes": []any{"type"}, "tokenModifiers": []any{}},
+			"full":   true,
+		},
+	}
+	legend, ok := semanticTokensLegend(capabilities)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"type"}, legend.TokenTypes)
+
+	capabilities.SemanticTokensProvider = map[string]any{
+		"legend": map[string]any{"tokenTypes": []any{"type"}},
+		"range":  true,
+	}
+	_, ok = semanticTokensLegend(capabilities)
+	assert.False(t, ok, "servers without full document tokens are skipped")
+
+	_, ok = semanticTokensLegend(protocol.ServerCapabilities{})
+	assert.False(t, ok)
+}
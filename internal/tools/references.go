@@ -4,48 +4,89 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"slices"
 	"sort"
-	"strconv"
 	"strings"
 
 	"github.com/isaacphi/mcp-language-server/internal/lsp"
 	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
 )
 
-func FindReferences(ctx context.Context, client *lsp.Client, symbolName string) (string, error) {
-	// Get context lines from environment variable
-	contextLines := 5
-	if envLines := os.Getenv("LSP_CONTEXT_LINES"); envLines != "" {
-		if val, err := strconv.Atoi(envLines); err == nil && val >= 0 {
-			contextLines = val
-		}
-	}
+// ReferencesResult is the outcome of the references tool
+type ReferencesResult struct {
+	Symbol string           `json:"symbol"`
+	Files  []FileReferences `json:"files"`
+	// Candidates are the symbols the name matches when it is ambiguous, in
+	// which case no references are looked up
+	Candidates []SymbolCandidate `json:"candidates,omitempty"`
+	// Page is set when the result is split into pages
+	Page *Page `json:"page,omitempty"`
+}
 
-	// First get the symbol location like ReadDefinition does
-	symbolName, results, err := QuerySymbol(ctx, client, symbolName)
+// FileReferences are the references to a symbol in one file
+type FileReferences struct {
+	File       string      `json:"file"`
+	References []Reference `json:"references"`
+	// Snippet shows the references with surrounding lines
+	Snippet string `json:"snippet,omitempty"`
+	// Error explains why the file could not be read
+	Error string `json:"error,omitempty"`
+}
+
+// Reference is one reference to a symbol
+type Reference struct {
+	Range Range `json:"range"`
+	// Kind is read, write, call, import, type-use or declaration, if
+	// references were classified
+	Kind string `json:"kind,omitempty"`
+}
+
+// ReferencesOptions narrows down the references FindReferencesResult returns
+type ReferencesOptions struct {
+	// IncludeDeclaration also lists the declaration of the symbol
+	IncludeDeclaration bool
+	// Include only keeps files matching one of these globs, if set
+	Include []string
+	// Exclude drops files matching any of these globs
+	Exclude []string
+	// Classify tells reads, writes, calls, imports, type uses and the
+	// declaration apart, at the cost of asking the server about each file
+	Classify bool
+	// Kinds only keeps references of these kinds, and implies Classify
+	Kinds []string
+}
+
+// FindReferences returns the references to every symbol matching a name
+func FindReferences(ctx context.Context, client *lsp.Client, symbolName string) (string, error) {
+	query := ParseSymbolQuery(symbolName)
+	query.All = true
+	result, err := FindReferencesResult(ctx, client, query, ReferencesOptions{})
 	if err != nil {
 		return "", err
 	}
+	return result.Text(), nil
+}
 
-	var allReferences []string
-	for _, symbol := range results {
-		// Handle different matching strategies based on the search term
-		if strings.Contains(symbolName, ".") {
-			// For qualified names like "Type.Method", check for various matches
-			parts := strings.Split(symbolName, ".")
-			methodName := parts[len(parts)-1]
+// FindReferencesResult finds the references to the symbols matching a query,
+// grouped by file, or the candidates to choose from if the query is ambiguous
+func FindReferencesResult(ctx context.Context, client *lsp.Client, query SymbolQuery, options ReferencesOptions) (*ReferencesResult, error) {
+	if err := options.validate(); err != nil {
+		return nil, err
+	}
 
-			// Try matching the unqualified method name for languages that don't use qualified names in symbols
-			if symbol.GetName() != symbolName && symbol.GetName() != methodName {
-				continue
-			}
-		} else if symbol.GetName() != symbolName {
-			// For unqualified names, exact match only
-			continue
-		}
+	contextLines := contextLinesSetting()
+
+	// First get the symbol location like ReadDefinition does
+	symbols, candidates, err := resolveUnambiguous(ctx, client, query)
+	if err != nil {
+		return nil, err
+	}
 
+	result := &ReferencesResult{Symbol: query.Name, Files: []FileReferences{}, Candidates: candidates}
+	for _, symbol := range symbols {
 		// Get the location of the symbol
-		loc := symbol.GetLocation()
+		loc := symbol.Location
 
 		// Use LSP references request with correct params structure
 		refsParams := protocol.ReferenceParams{
@@ -56,7 +97,7 @@ func FindReferences(ctx context.Context, client *lsp.Client, symbolName string)
 				Position: loc.Range.Start,
 			},
 			Context: protocol.ReferenceContext{
-				IncludeDeclaration: false,
+				IncludeDeclaration: options.IncludeDeclaration,
 			},
 		}
 		// File is likely to be opened already, but may not be.
@@ -67,12 +108,17 @@ func FindReferences(ctx context.Context, client *lsp.Client, symbolName string)
 		}
 		refs, err := client.References(ctx, refsParams)
 		if err != nil {
-			return "", fmt.Errorf("failed to get references: %v", err)
+			return nil, fmt.Errorf("failed to get references: %v", err)
 		}
 
 		// Group references by file
 		refsByFile := make(map[protocol.DocumentUri][]protocol.Location)
 		for _, ref := range refs {
+			if keep, err := options.keepFile(ref.URI.Path()); err != nil {
+				return nil, err
+			} else if !keep {
+				continue
+			}
 			refsByFile[ref.URI] = append(refsByFile[ref.URI], ref)
 		}
 
@@ -87,31 +133,55 @@ func FindReferences(ctx context.Context, client *lsp.Client, symbolName string)
 		for _, uriStr := range uris {
 			uri := protocol.DocumentUri(uriStr)
 			fileRefs := refsByFile[uri]
+			// Keep the order stable so that pages line up across calls
+			sort.SliceStable(fileRefs, func(i, j int) bool {
+				return positionBefore(fileRefs[i].Range.Start, fileRefs[j].Range.Start)
+			})
 			filePath := strings.TrimPrefix(uriStr, "file://")
 
-			// Format file header
-			fileInfo := fmt.Sprintf("---\n\n%s\nReferences in File: %d\n",
-				filePath,
-				len(fileRefs),
-			)
+			file := FileReferences{File: utilities.DisplayPath(filePath)}
+			for _, ref := range fileRefs {
+				file.References = append(file.References, Reference{Range: newRange(ref.Range)})
+			}
 
 			// Format locations with context
+			if err := utilities.CheckReadAccess(filePath); err != nil {
+				if len(options.Kinds) > 0 {
+					// The kinds of these references cannot be told
+					continue
+				}
+				file.Error = err.Error()
+				result.Files = append(result.Files, file)
+				continue
+			}
 			fileContent, err := os.ReadFile(filePath)
 			if err != nil {
 				// Log error but continue with other files
-				allReferences = append(allReferences, fileInfo+"\nError reading file: "+err.Error())
+				file.Error = "Error reading file: " + err.Error()
+				result.Files = append(result.Files, file)
 				continue
 			}
 
 			lines := strings.Split(string(fileContent), "\n")
 
-			// Track reference locations for header display
-			var locStrings []string
-			for _, ref := range fileRefs {
-				locStr := fmt.Sprintf("L%d:C%d",
-					ref.Range.Start.Line+1,
-					ref.Range.Start.Character+1)
-				locStrings = append(locStrings, locStr)
+			if options.Classify || len(options.Kinds) > 0 {
+				classifier := newReferenceClassifier(ctx, client, uri, fileRefs, lines, symbol)
+				var kept []protocol.Location
+				var keptReferences []Reference
+				for i, ref := range fileRefs {
+					kind := classifier.classify(ref.Range)
+					if len(options.Kinds) > 0 && !slices.Contains(options.Kinds, kind) {
+						continue
+					}
+					file.References[i].Kind = kind
+					kept = append(kept, ref)
+					keptReferences = append(keptReferences, file.References[i])
+				}
+				if len(kept) == 0 {
+					continue
+				}
+				fileRefs = kept
+				file.References = keptReferences
 			}
 
 			// Collect lines to display using the utility function
@@ -124,21 +194,103 @@ func FindReferences(ctx context.Context, client *lsp.Client, symbolName string)
 			// Convert to line ranges using the utility function
 			lineRanges := ConvertLinesToRanges(linesToShow, len(lines))
 
-			// Format with locations in header
-			formattedOutput := fileInfo
-			if len(locStrings) > 0 {
-				formattedOutput += "At: " + strings.Join(locStrings, ", ") + "\n"
+			file.Snippet = FormatLinesWithRanges(lines, lineRanges)
+			result.Files = append(result.Files, file)
+		}
+	}
+
+	return result, nil
+}
+
+// Text renders the references of each file with surrounding lines
+func (r *ReferencesResult) Text() string {
+	if len(r.Candidates) > 0 {
+		return ambiguityText(r.Symbol, r.Candidates)
+	}
+	if len(r.Files) == 0 {
+		return fmt.Sprintf("No references found for symbol: %s", r.Symbol)
+	}
+
+	allReferences := make([]string, 0, len(r.Files))
+	for _, file := range r.Files {
+		// Format file header
+		formattedOutput := fmt.Sprintf("---\n\n%s\nReferences in File: %d\n",
+			file.File,
+			len(file.References),
+		)
+		if file.Error != "" {
+			allReferences = append(allReferences, formattedOutput+"\n"+file.Error)
+			continue
+		}
+
+		// Format with locations in header
+		if len(file.References) > 0 {
+			locStrings := make([]string, len(file.References))
+			for i, ref := range file.References {
+				locStrings[i] = ref.Range.Start.String()
+				if ref.Kind != "" {
+					locStrings[i] += " (" + ref.Kind + ")"
+				}
 			}
+			formattedOutput += "At: " + strings.Join(locStrings, ", ") + "\n"
+		}
+
+		// Format the content with ranges
+		formattedOutput += "\n" + file.Snippet
+		allReferences = append(allReferences, formattedOutput)
+	}
 
-			// Format the content with ranges
-			formattedOutput += "\n" + FormatLinesWithRanges(lines, lineRanges)
-			allReferences = append(allReferences, formattedOutput)
+	return strings.Join(allReferences, "\n")
+}
+
+// validate checks the globs and kinds of the options
+func (o ReferencesOptions) validate() error {
+	for _, pattern := range slices.Concat(o.Include, o.Exclude) {
+		if _, err := utilities.MatchPathGlob(pattern, ""); err != nil {
+			return err
+		}
+	}
+	for _, kind := range o.Kinds {
+		if !slices.Contains(ReferenceKinds, kind) {
+			return fmt.Errorf("unknown reference kind %q, expected one of %s", kind, strings.Join(ReferenceKinds, ", "))
 		}
 	}
+	return nil
+}
 
-	if len(allReferences) == 0 {
-		return fmt.Sprintf("No references found for symbol: %s", symbolName), nil
+// keepFile reports whether the references in a file pass the include and
+// exclude globs
+func (o ReferencesOptions) keepFile(path string) (bool, error) {
+	for _, pattern := range o.Exclude {
+		if match, err := utilities.MatchPathGlob(pattern, path); err != nil || match {
+			return false, err
+		}
+	}
+	if len(o.Include) == 0 {
+		return true, nil
 	}
+	for _, pattern := range o.Include {
+		if match, err := utilities.MatchPathGlob(pattern, path); err != nil || match {
+			return match, err
+		}
+	}
+	return false, nil
+}
+
+// Len returns the number of files
+func (r *ReferencesResult) Len() int {
+	return len(r.Files)
+}
+
+// Slice returns a copy holding only the given files
+func (r *ReferencesResult) Slice(start, end int, page *Page) PagedResult {
+	sliced := *r
+	sliced.Files = r.Files[start:end]
+	sliced.Page = page
+	return &sliced
+}
 
-	return strings.Join(allReferences, "\n"), nil
+// ItemName names the items a page is made of
+func (r *ReferencesResult) ItemName() string {
+	return "files"
 }
@@ -0,0 +1,464 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
+)
+
+// Test frameworks tests are detected for
+const (
+	TestFrameworkGo     = "go"
+	TestFrameworkPytest = "pytest"
+	TestFrameworkCargo  = "cargo"
+	TestFrameworkJest   = "jest"
+)
+
+// Go test, fuzz and example functions. Benchmarks are left out, as go test
+// -run does not run them and they tell nothing about correctness.
+var goTestName = regexp.MustCompile(`^(Test|Fuzz|Example)([^a-z].*)?$`)
+
+// Rust test attributes, as in #[test], #[tokio::test] and #[rstest]
+var rustTestAttribute = regexp.MustCompile(`^#\[((\w+::)*test|rstest|test_case)\b`)
+
+// Jest and Vitest test blocks and the name they are given, as in
+// it("parses", ...), test.only('parses', ...) or describe(`parser`, ...)
+var jestTestCall = regexp.MustCompile("\\b(it|test|describe)(\\.\\w+)*\\s*\\(\\s*(['\"`])(.+?)['\"`]")
+
+// Rust inline module declarations, to name the tests inside them
+var rustModule = regexp.MustCompile(`^\s*(pub(\([^)]*\))?\s+)?mod\s+(\w+)\s*\{`)
+
+// Rust string and character literals and line comments, whose braces do not
+// open or close blocks
+var rustNonCode = regexp.MustCompile(`"(\\.|[^"\\])*"|'(\\.|[^'\\])'|//.*`)
+
+// Python class declarations, to name the tests of test classes
+var pythonClass = regexp.MustCompile(`^(\s*)class\s+(\w+)`)
+
+// RelatedTestsResult is the tests that call the symbols matching a name,
+// directly or through other functions
+type RelatedTestsResult struct {
+	Symbol string `json:"symbol"`
+	// Depth is how many levels of callers were followed
+	Depth int           `json:"depth"`
+	Tests []RelatedTest `json:"tests"`
+	// Commands run exactly the tests found, one for each framework and, for
+	// Go, each package. They run from the workspace directory.
+	Commands []TestCommand `json:"commands"`
+	// Errors are the lookups that failed, leaving the result incomplete
+	Errors []string `json:"errors,omitempty"`
+	// Candidates are the symbols the name matches when it is ambiguous, in
+	// which case nothing is looked up
+	Candidates []SymbolCandidate `json:"candidates,omitempty"`
+}
+
+// RelatedTest is a test calling the symbol
+type RelatedTest struct {
+	// Name is how the framework selects the test, such as TestParse,
+	// TestParser::test_parse, parser::tests::parses or the description
+	// given to it()
+	Name string `json:"name"`
+	// Framework is one of the test frameworks, or empty for tests found by
+	// their file and name alone
+	Framework string   `json:"framework,omitempty"`
+	Location  Location `json:"location"`
+	// Distance is how many calls the test is from the symbol
+	Distance int `json:"distance"`
+	// Via is the chain of calls from the test to the symbol
+	Via []string `json:"via"`
+
+	path        string
+	declaration protocol.Range
+}
+
+// TestCommand is a shell command that runs some of the tests
+type TestCommand struct {
+	Framework string   `json:"framework"`
+	Command   string   `json:"command"`
+	Tests     []string `json:"tests"`
+}
+
+// FindRelatedTests returns the tests calling every symbol matching a name,
+// up to maxDepth levels of callers away
+func FindRelatedTests(ctx context.Context, client *lsp.Client, symbolName string, maxDepth int) (string, error) {
+	query := ParseSymbolQuery(symbolName)
+	query.All = true
+	result, err := FindRelatedTestsResult(ctx, client, query, maxDepth)
+	if err != nil {
+		return "", err
+	}
+	return result.Text(), nil
+}
+
+// FindRelatedTestsResult walks the callers of the symbols matching a query
+// and returns the ones that are tests, along with the commands to run them
+func FindRelatedTestsResult(ctx context.Context, client *lsp.Client, query SymbolQuery, maxDepth int) (*RelatedTestsResult, error) {
+	if maxDepth < 1 || maxDepth > MaxCallHierarchyDepth {
+		return nil, fmt.Errorf("depth must be between 1 and %d, got %d", MaxCallHierarchyDepth, maxDepth)
+	}
+
+	symbols, candidates, err := resolveUnambiguous(ctx, client, query)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &RelatedTestsResult{Symbol: query.Name, Depth: maxDepth, Tests: []RelatedTest{}, Commands: []TestCommand{}, Candidates: candidates}
+	detector := &testDetector{files: make(map[string][]string)}
+	seen := make(map[string]bool)
+	for _, symbol := range symbols {
+		items, err := client.PrepareCallHierarchy(ctx, protocol.CallHierarchyPrepareParams{
+			TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+				TextDocument: protocol.TextDocumentIdentifier{URI: symbol.Location.URI},
+				Position:     symbol.Location.Range.Start,
+			},
+		})
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: failed to prepare call hierarchy: %v", symbol.Name, err))
+			continue
+		}
+
+		match := CallHierarchyMatch{}
+		walker := newCallHierarchyWalker(client, CallHierarchyOptions{MaxDepth: maxDepth, ExcludeExternal: true}, incomingCalls)
+		for _, item := range items {
+			walker.walk(ctx, hierarchyCall{item: item}, &match, -1, 0)
+		}
+
+		for index, call := range match.Calls {
+			if call.Error != "" {
+				result.Errors = append(result.Errors, fmt.Sprintf("%s: failed to get callers: %s", call.Name, call.Error))
+			}
+			framework, name, ok := detector.detect(call.item)
+			if !ok {
+				continue
+			}
+			path := call.item.URI.Path()
+			key := framework + "\x00" + path + "\x00" + name
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			var via []string
+			for i := index; i >= 0; i = match.Calls[i].Parent {
+				via = append(via, match.Calls[i].Name)
+			}
+			result.Tests = append(result.Tests, RelatedTest{
+				Name:        name,
+				Framework:   framework,
+				Location:    call.Location,
+				Distance:    call.Depth,
+				Via:         via,
+				path:        path,
+				declaration: call.item.Range,
+			})
+		}
+	}
+
+	sort.SliceStable(result.Tests, func(i, j int) bool {
+		a, b := result.Tests[i], result.Tests[j]
+		if a.Distance != b.Distance {
+			return a.Distance < b.Distance
+		}
+		if a.Location.File != b.Location.File {
+			return a.Location.File < b.Location.File
+		}
+		return a.Location.Range.Start.Line < b.Location.Range.Start.Line
+	})
+	result.Commands = commandsForTests(result.Tests)
+	return result, nil
+}
+
+// testDetector tells test functions apart by their file, name and, for
+// Rust and Jest, the code around them
+type testDetector struct {
+	files map[string][]string
+}
+
+// detect returns the framework of a function and the name the framework
+// selects it by, if it is a test
+func (d *testDetector) detect(item protocol.CallHierarchyItem) (framework string, name string, ok bool) {
+	path := item.URI.Path()
+	switch filepath.Ext(path) {
+	case ".go":
+		if isTestFile(path) && goTestName.MatchString(item.Name) {
+			return TestFrameworkGo, item.Name, true
+		}
+		return "", "", false
+
+	case ".py":
+		if !isTestFile(path) || !strings.HasPrefix(item.Name, "test") {
+			return "", "", false
+		}
+		if class := d.pythonClass(path, int(item.SelectionRange.Start.Line)); class != "" {
+			return TestFrameworkPytest, class + "::" + item.Name, true
+		}
+		return TestFrameworkPytest, item.Name, true
+
+	case ".rs":
+		if d.hasRustTestAttribute(path, item) {
+			modules := append(rustFileModules(path), d.rustInlineModules(path, int(item.SelectionRange.Start.Line))...)
+			return TestFrameworkCargo, strings.Join(append(modules, item.Name), "::"), true
+		}
+		return "", "", false
+
+	case ".js", ".jsx", ".ts", ".tsx", ".mjs", ".cjs", ".mts", ".cts":
+		if !isTestFile(path) {
+			return "", "", false
+		}
+		lines := d.lines(path)
+		line := int(item.Range.Start.Line)
+		if line < len(lines) {
+			if match := jestTestCall.FindStringSubmatch(lines[line]); match != nil {
+				return TestFrameworkJest, match[4], true
+			}
+		}
+		return "", "", false
+	}
+
+	if isTestFile(path) && strings.HasPrefix(strings.ToLower(item.Name), "test") {
+		return "", item.Name, true
+	}
+	return "", "", false
+}
+
+// lines returns the lines of a file, or none if it cannot be read
+func (d *testDetector) lines(path string) []string {
+	lines, ok := d.files[path]
+	if !ok {
+		if err := utilities.CheckReadAccess(path); err == nil {
+			if content, err := os.ReadFile(path); err == nil {
+				lines = strings.Split(string(content), "\n")
+			}
+		}
+		d.files[path] = lines
+	}
+	return lines
+}
+
+// pythonClass returns the class a method declared at a line belongs to
+func (d *testDetector) pythonClass(path string, line int) string {
+	lines := d.lines(path)
+	if line >= len(lines) {
+		return ""
+	}
+	indent := len(lines[line]) - len(strings.TrimLeft(lines[line], " \t"))
+	for i := line - 1; i >= 0 && indent > 0; i-- {
+		if match := pythonClass.FindStringSubmatch(lines[i]); match != nil && len(match[1]) < indent {
+			return match[2]
+		}
+		trimmed := strings.TrimSpace(lines[i])
+		if trimmed != "" && !strings.HasPrefix(trimmed, "#") {
+			indent = min(indent, len(lines[i])-len(strings.TrimLeft(lines[i], " \t")))
+		}
+	}
+	return ""
+}
+
+// hasRustTestAttribute looks for a test attribute among the attributes and
+// comments between the start of a function and its name
+func (d *testDetector) hasRustTestAttribute(path string, item protocol.CallHierarchyItem) bool {
+	lines := d.lines(path)
+	last := int(item.SelectionRange.Start.Line)
+	for i := last; i >= 0 && i < len(lines); i-- {
+		trimmed := strings.TrimSpace(lines[i])
+		if rustTestAttribute.MatchString(trimmed) {
+			return true
+		}
+		// Attributes and doc comments directly precede the function
+		if i < last && i < int(item.Range.Start.Line) && !strings.HasPrefix(trimmed, "#[") && !strings.HasPrefix(trimmed, "//") {
+			return false
+		}
+	}
+	return false
+}
+
+// rustFileModules returns the module path of a Rust source file within its
+// crate, such as [parser lexer] for src/parser/lexer.rs. Crate roots, such as
+// src/lib.rs, binaries and the files in tests, have none.
+func rustFileModules(path string) []string {
+	parts := strings.Split(filepath.ToSlash(path), "/")
+	i := -1
+	for j := len(parts) - 2; j >= 0; j-- {
+		if parts[j] == "src" {
+			i = j
+			break
+		}
+	}
+	if i < 0 {
+		return nil
+	}
+	modules := slices.Clone(parts[i+1:])
+	if len(modules) > 0 && modules[0] == "bin" {
+		return nil
+	}
+	last := strings.TrimSuffix(modules[len(modules)-1], ".rs")
+	modules = modules[:len(modules)-1]
+	if last != "mod" && !(len(modules) == 0 && (last == "lib" || last == "main")) {
+		modules = append(modules, last)
+	}
+	return modules
+}
+
+// rustInlineModules returns the mod blocks of a file that enclose a line,
+// outermost first. Braces are counted line by line outside of literals and
+// line comments, which is close enough for the modules tests live in.
+func (d *testDetector) rustInlineModules(path string, line int) []string {
+	type module struct {
+		name  string
+		depth int
+	}
+	var open []module
+	depth := 0
+	lines := d.lines(path)
+	for i := 0; i < line && i < len(lines); i++ {
+		if match := rustModule.FindStringSubmatch(lines[i]); match != nil {
+			open = append(open, module{name: match[3], depth: depth})
+		}
+		code := rustNonCode.ReplaceAllString(lines[i], "")
+		depth += strings.Count(code, "{") - strings.Count(code, "}")
+		for len(open) > 0 && open[len(open)-1].depth >= depth {
+			open = open[:len(open)-1]
+		}
+	}
+	names := make([]string, len(open))
+	for i, m := range open {
+		names[i] = m.name
+	}
+	return names
+}
+
+// commandsForTests returns the commands that run exactly the given tests
+func commandsForTests(tests []RelatedTest) []TestCommand {
+	// Go tests are run per package, the other frameworks take every test at once
+	type group struct {
+		framework string
+		dir       string
+	}
+	var order []group
+	byGroup := make(map[group][]RelatedTest)
+	for _, test := range tests {
+		if test.Framework == "" {
+			continue
+		}
+		g := group{framework: test.Framework}
+		if test.Framework == TestFrameworkGo {
+			g.dir = commandDir(test.path)
+		}
+		if _, ok := byGroup[g]; !ok {
+			order = append(order, g)
+		}
+		byGroup[g] = append(byGroup[g], test)
+	}
+
+	commands := []TestCommand{}
+	for _, g := range order {
+		groupTests := byGroup[g]
+		var names, files []string
+		for _, test := range groupTests {
+			if !slices.Contains(names, test.Name) {
+				names = append(names, test.Name)
+			}
+			file := utilities.WorkspaceRelativePath(test.path)
+			if !slices.Contains(files, file) {
+				files = append(files, file)
+			}
+		}
+
+		var command string
+		switch g.framework {
+		case TestFrameworkGo:
+			command = fmt.Sprintf("go test %s -run %s", shellQuote(g.dir), shellQuote("^("+strings.Join(names, "|")+")$"))
+		case TestFrameworkPytest:
+			ids := make([]string, len(groupTests))
+			for i, test := range groupTests {
+				ids[i] = shellQuote(utilities.WorkspaceRelativePath(test.path) + "::" + test.Name)
+			}
+			command = "pytest " + strings.Join(ids, " ")
+		case TestFrameworkCargo:
+			quoted := make([]string, len(names))
+			for i, name := range names {
+				quoted[i] = shellQuote(name)
+			}
+			command = "cargo test -- --exact " + strings.Join(quoted, " ")
+		case TestFrameworkJest:
+			patterns := make([]string, len(names))
+			for i, name := range names {
+				patterns[i] = regexp.QuoteMeta(name)
+			}
+			quoted := make([]string, len(files))
+			for i, file := range files {
+				quoted[i] = shellQuote(file)
+			}
+			command = fmt.Sprintf("npx jest %s -t %s", strings.Join(quoted, " "), shellQuote(strings.Join(patterns, "|")))
+		}
+		commands = append(commands, TestCommand{Framework: g.framework, Command: command, Tests: names})
+	}
+	return commands
+}
+
+// commandDir returns the directory of a file the way commands run from the
+// workspace directory refer to it, as in ./internal/tools
+func commandDir(path string) string {
+	dir := filepath.Dir(utilities.WorkspaceRelativePath(path))
+	if !filepath.IsAbs(dir) && dir != "." {
+		dir = "./" + dir
+	}
+	return dir
+}
+
+// Arguments that need no quoting in a POSIX shell
+var shellSafe = regexp.MustCompile(`^[\w@%+=:,./-]+$`)
+
+// shellQuote quotes an argument for a POSIX shell
+func shellQuote(arg string) string {
+	if shellSafe.MatchString(arg) {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
+
+// Text renders the tests and the commands to run them
+func (r *RelatedTestsResult) Text() string {
+	if len(r.Candidates) > 0 {
+		return ambiguityText(r.Symbol, r.Candidates)
+	}
+
+	var result strings.Builder
+	for _, err := range r.Errors {
+		fmt.Fprintf(&result, "Error: %s\n", err)
+	}
+	if len(r.Tests) == 0 {
+		fmt.Fprintf(&result, "No tests call %s within %d levels of callers\n", r.Symbol, r.Depth)
+		return result.String()
+	}
+
+	fmt.Fprintf(&result, "%s call %s:\n", countOf(len(r.Tests), "test"), r.Symbol)
+	for _, test := range r.Tests {
+		framework := test.Framework
+		if framework == "" {
+			framework = "unknown framework"
+		}
+		fmt.Fprintf(&result, "\n%s (%s, distance %d)\n", test.Name, framework, test.Distance)
+		fmt.Fprintf(&result, "  %s:%d\n", test.Location.File, test.Location.Range.Start.Line)
+		if len(test.Via) > 2 {
+			fmt.Fprintf(&result, "  Via: %s\n", strings.Join(test.Via, " -> "))
+		}
+	}
+
+	if len(r.Commands) > 0 {
+		result.WriteString("\nRun them with:\n")
+		for _, command := range r.Commands {
+			fmt.Fprintf(&result, "  %s\n", command.Command)
+		}
+	}
+	return result.String()
+}
@@ -0,0 +1,119 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectTest(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"parse_test.go": "package parse\n\nfunc TestParse(t *testing.T) {}\n\nfunc testHelper() {}\n\nfunc BenchmarkParse(b *testing.B) {}\n",
+		"parse.go":      "package parse\n\nfunc TestLike() {}\n",
+		"tests/test_parse.py": "import parse\n\n" +
+			"def test_module():\n    parse.run()\n\n" +
+			"class TestParser:\n    def setup_method(self):\n        pass\n\n    def test_method(self):\n        parse.run()\n",
+		"src/lib.rs": "mod tests {\n" +
+			"    #[test]\n    fn parses() {\n        run();\n    }\n\n" +
+			"    #[tokio::test]\n    /// Runs asynchronously\n    async fn parses_async() {}\n\n" +
+			"    fn not_a_test() {}\n}\n",
+		"src/parser/lexer.rs": "fn lex() {}\n\n" +
+			"#[cfg(test)]\nmod tests {\n    mod unicode {\n        #[test]\n        fn lexes() { let s = \"{\"; }\n    }\n\n" +
+			"    #[test]\n    fn lexes_ascii() {}\n}\n",
+		"src/parse.test.ts": "describe('parser', () => {\n  it('parses numbers', () => {\n    parse('1');\n  });\n});\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+
+	item := func(file, name string, start, selection uint32) protocol.CallHierarchyItem {
+		return protocol.CallHierarchyItem{
+			Name:           name,
+			URI:            protocol.DocumentUri("file://" + filepath.Join(dir, file)),
+			Range:          protocol.Range{Start: protocol.Position{Line: start}, End: protocol.Position{Line: selection + 2}},
+			SelectionRange: protocol.Range{Start: protocol.Position{Line: selection}, End: protocol.Position{Line: selection}},
+		}
+	}
+
+	tests := []struct {
+		name      string
+		item      protocol.CallHierarchyItem
+		framework string
+		testName  string
+		ok        bool
+	}{
+		{"go test", item("parse_test.go", "TestParse", 2, 2), TestFrameworkGo, "TestParse", true},
+		{"go helper in test file", item("parse_test.go", "testHelper", 4, 4), "", "", false},
+		{"go benchmark", item("parse_test.go", "BenchmarkParse", 6, 6), "", "", false},
+		{"go function outside test file", item("parse.go", "TestLike", 2, 2), "", "", false},
+		{"pytest function", item("tests/test_parse.py", "test_module", 2, 2), TestFrameworkPytest, "test_module", true},
+		{"pytest method", item("tests/test_parse.py", "test_method", 9, 9), TestFrameworkPytest, "TestParser::test_method", true},
+		{"pytest setup", item("tests/test_parse.py", "setup_method", 6, 6), "", "", false},
+		{"rust test", item("src/lib.rs", "parses", 2, 2), TestFrameworkCargo, "tests::parses", true},
+		{"rust attribute in range", item("src/lib.rs", "parses_async", 6, 8), TestFrameworkCargo, "tests::parses_async", true},
+		{"rust test in nested module", item("src/parser/lexer.rs", "lexes", 5, 6), TestFrameworkCargo, "parser::lexer::tests::unicode::lexes", true},
+		{"rust test after nested module", item("src/parser/lexer.rs", "lexes_ascii", 9, 10), TestFrameworkCargo, "parser::lexer::tests::lexes_ascii", true},
+		{"rust function", item("src/lib.rs", "not_a_test", 10, 10), "", "", false},
+		{"jest callback", item("src/parse.test.ts", "it('parses numbers') callback", 1, 1), TestFrameworkJest, "parses numbers", true},
+	}
+
+	detector := &testDetector{files: make(map[string][]string)}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			framework, name, ok := detector.detect(tc.item)
+			assert.Equal(t, tc.ok, ok)
+			assert.Equal(t, tc.framework, framework)
+			assert.Equal(t, tc.testName, name)
+		})
+	}
+}
+
+func TestCommandsForTests(t *testing.T) {
+	utilities.SetWorkspacePaths("/ws", false, nil)
+	defer utilities.SetWorkspacePaths("", false, nil)
+
+	tests := []RelatedTest{
+		{Name: "TestParse", Framework: TestFrameworkGo, path: "/ws/parse/parse_test.go"},
+		{Name: "TestEval", Framework: TestFrameworkGo, path: "/ws/eval_test.go"},
+		{Name: "TestLex", Framework: TestFrameworkGo, path: "/ws/parse/lex_test.go"},
+		{Name: "TestParser::test_method", Framework: TestFrameworkPytest, path: "/ws/tests/test_parse.py"},
+		{Name: "tests::parses", Framework: TestFrameworkCargo, path: "/ws/src/lib.rs"},
+		{Name: "parses (1 + 2)", Framework: TestFrameworkJest, path: "/ws/src/parse.test.ts"},
+		{Name: "TestSomething", path: "/ws/test/Something.php"},
+	}
+
+	var commands []string
+	for _, command := range commandsForTests(tests) {
+		commands = append(commands, command.Command)
+	}
+	assert.Equal(t, []string{
+		"go test ./parse -run '^(TestParse|TestLex)$'",
+		"go test . -run '^(TestEval)$'",
+		"pytest tests/test_parse.py::TestParser::test_method",
+		"cargo test -- --exact tests::parses",
+		"npx jest src/parse.test.ts -t 'parses \\(1 \\+ 2\\)'",
+	}, commands)
+}
+
+func TestRustFileModules(t *testing.T) {
+	assert.Empty(t, rustFileModules("/ws/src/lib.rs"))
+	assert.Empty(t, rustFileModules("/ws/calc/src/main.rs"))
+	assert.Empty(t, rustFileModules("/ws/src/bin/tool.rs"))
+	assert.Empty(t, rustFileModules("/ws/tests/integration.rs"))
+	assert.Equal(t, []string{"parser"}, rustFileModules("/ws/src/parser/mod.rs"))
+	assert.Equal(t, []string{"parser", "lexer"}, rustFileModules("/ws/src/parser/lexer.rs"))
+}
+
+func TestShellQuote(t *testing.T) {
+	assert.Equal(t, "./pkg/...", shellQuote("./pkg/..."))
+	assert.Equal(t, "'^(TestA|TestB)$'", shellQuote("^(TestA|TestB)$"))
+	assert.Equal(t, `'it'\''s'`, shellQuote("it's"))
+}
@@ -11,13 +11,41 @@ import (
 	"github.com/isaacphi/mcp-language-server/internal/utilities"
 )
 
+// RenameResult is the outcome of renaming a symbol
+type RenameResult struct {
+	NewName     string        `json:"newName"`
+	Occurrences int           `json:"occurrences"`
+	Files       []RenamedFile `json:"files"`
+}
+
+// RenamedFile lists where a symbol was renamed in one file
+type RenamedFile struct {
+	File      string     `json:"file"`
+	Positions []Position `json:"positions"`
+}
+
 // RenameSymbol renames a symbol (variable, function, class, etc.) at the specified position
 // It uses the LSP rename functionality to handle all references across files
 func RenameSymbol(ctx context.Context, client *lsp.Client, filePath string, line, column int, newName string) (string, error) {
+	result, err := RenameSymbolResult(ctx, client, filePath, line, column, newName)
+	if err != nil {
+		return "", err
+	}
+	return result.Text(), nil
+}
+
+// RenameSymbolResult renames the symbol at the one-indexed position and
+// reports where it changed
+func RenameSymbolResult(ctx context.Context, client *lsp.Client, filePath string, line, column int, newName string) (*RenameResult, error) {
+	filePath, err := readablePath(filePath)
+	if err != nil {
+		return nil, err
+	}
+
 	// Open the file if not already open
-	err := client.OpenFile(ctx, filePath)
+	err = client.OpenFile(ctx, filePath)
 	if err != nil {
-		return "", fmt.Errorf("could not open file: %v", err)
+		return nil, fmt.Errorf("could not open file: %v", err)
 	}
 
 	// Convert 1-indexed line/column to 0-indexed for LSP protocol
@@ -42,86 +70,78 @@ func RenameSymbol(ctx context.Context, client *lsp.Client, filePath string, line
 	// Execute the rename operation
 	workspaceEdit, err := client.Rename(ctx, params)
 	if err != nil {
-		return "", fmt.Errorf("failed to rename symbol: %v", err)
+		return nil, fmt.Errorf("failed to rename symbol: %v", err)
 	}
 
-	// Count the changes that will be made
-	changeCount := 0
-	fileCount := 0
-
-	// Build output
-	var locationsBuilder strings.Builder
+	result := &RenameResult{NewName: newName, Files: []RenamedFile{}}
 
-	// Create a slice to store all changes before sorting and writing
-	type FileChanges struct {
-		URI       string
-		Locations string
+	// Collect all changes before sorting them
+	type fileChanges struct {
+		uri       protocol.DocumentUri
+		positions []Position
 	}
-	var allChanges []FileChanges
+	var allChanges []fileChanges
 
 	// Count changes in Changes field
-	if workspaceEdit.Changes != nil {
-		fileCount = len(workspaceEdit.Changes)
-		for uri, edits := range workspaceEdit.Changes {
-			changeCount += len(edits)
-			var locs strings.Builder
-			for i, change := range edits {
-				locs.WriteString(
-					fmt.Sprintf("L%d:C%d", change.Range.Start.Line+1, change.Range.Start.Character+1),
-				)
-				if i != len(edits)-1 {
-					locs.WriteString(", ")
-				}
-			}
-			allChanges = append(allChanges, FileChanges{
-				URI:       string(uri),
-				Locations: locs.String(),
-			})
+	for uri, edits := range workspaceEdit.Changes {
+		result.Occurrences += len(edits)
+		change := fileChanges{uri: uri, positions: []Position{}}
+		for _, edit := range edits {
+			change.positions = append(change.positions, newPosition(edit.Range.Start))
 		}
+		allChanges = append(allChanges, change)
 	}
 
 	// Count changes in DocumentChanges field
-	for _, change := range workspaceEdit.DocumentChanges {
-		if change.TextDocumentEdit != nil {
-			var locs strings.Builder
-			for i, edit := range change.TextDocumentEdit.Edits {
+	for _, documentChange := range workspaceEdit.DocumentChanges {
+		if documentChange.TextDocumentEdit != nil {
+			change := fileChanges{uri: documentChange.TextDocumentEdit.TextDocument.URI, positions: []Position{}}
+			for _, edit := range documentChange.TextDocumentEdit.Edits {
 				textEdit, err := edit.AsTextEdit()
 				if err == nil {
-					locs.WriteString(fmt.Sprintf("L%d:C%d", textEdit.Range.Start.Line+1, textEdit.Range.Start.Character+1))
-					if i != len(change.TextDocumentEdit.Edits)-1 {
-						locs.WriteString(", ")
-					}
+					change.positions = append(change.positions, newPosition(textEdit.Range.Start))
 				}
 			}
-			allChanges = append(allChanges, FileChanges{
-				URI:       string(change.TextDocumentEdit.TextDocument.URI),
-				Locations: locs.String(),
-			})
-			fileCount++
-			changeCount += len(change.TextDocumentEdit.Edits)
+			allChanges = append(allChanges, change)
+			result.Occurrences += len(documentChange.TextDocumentEdit.Edits)
 		}
 	}
 
 	// Sort changes by filename for consistent output
 	sort.Slice(allChanges, func(i, j int) bool {
-		return allChanges[i].URI < allChanges[j].URI
+		return allChanges[i].uri < allChanges[j].uri
 	})
-
-	// Write sorted changes to the output builder
 	for _, change := range allChanges {
-		locationsBuilder.WriteString(fmt.Sprintf("%s: %s\n", change.URI, change.Locations))
+		result.Files = append(result.Files, RenamedFile{
+			File:      utilities.DisplayURI(change.uri),
+			Positions: change.positions,
+		})
 	}
 
 	// Apply the workspace edit to files:workspaceEdit
-	if err := utilities.ApplyWorkspaceEdit(workspaceEdit); err != nil {
-		return "", fmt.Errorf("failed to apply changes: %v", err)
+	if err := utilities.ApplyWorkspaceEdit(workspaceEdit, fmt.Sprintf("rename_symbol %s:%d:%d to %s", utilities.DisplayPath(filePath), line, column, newName)); err != nil {
+		return nil, fmt.Errorf("failed to apply changes: %v", err)
 	}
 
-	if fileCount == 0 || changeCount == 0 {
-		return "Failed to rename symbol. 0 occurrences found.", nil
+	return result, nil
+}
+
+// Text summarizes the rename with the renamed positions in each file
+func (r *RenameResult) Text() string {
+	if len(r.Files) == 0 || r.Occurrences == 0 {
+		return "Failed to rename symbol. 0 occurrences found."
+	}
+
+	var locations strings.Builder
+	for _, file := range r.Files {
+		positions := make([]string, len(file.Positions))
+		for i, pos := range file.Positions {
+			positions[i] = pos.String()
+		}
+		locations.WriteString(fmt.Sprintf("%s: %s\n", file.File, strings.Join(positions, ", ")))
 	}
 
 	// Generate a summary of changes made
 	return fmt.Sprintf("Successfully renamed symbol to '%s'.\nUpdated %d occurrences across %d files:\n%s",
-		newName, changeCount, fileCount, locationsBuilder.String()), nil
+		r.NewName, r.Occurrences, len(r.Files), locations.String())
 }
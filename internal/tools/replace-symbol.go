@@ -0,0 +1,176 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
+)
+
+// SymbolReplacement is the outcome of replacing a symbol's definition
+type SymbolReplacement struct {
+	Symbol string `json:"symbol"`
+	File   string `json:"file"`
+	// OldStartLine and OldEndLine are the replaced lines, one-indexed
+	OldStartLine int `json:"oldStartLine"`
+	OldEndLine   int `json:"oldEndLine"`
+	// NewStartLine and NewEndLine are the lines of the new source
+	NewStartLine int `json:"newStartLine"`
+	NewEndLine   int `json:"newEndLine"`
+	// Hash is the SHA-256 of the file after the change
+	Hash string `json:"hash"`
+}
+
+// ReplaceSymbol replaces the whole definition of a symbol with new source.
+// The symbol is looked up in filePath if it is set, otherwise across the
+// workspace, and must resolve to a single location. If the new source starts
+// with a comment, it also replaces the doc comment above the definition,
+// otherwise the existing doc comment is kept.
+func ReplaceSymbol(ctx context.Context, client *lsp.Client, symbolName string, filePath string, newText string) (string, error) {
+	result, err := ReplaceSymbolResult(ctx, client, ParseSymbolQuery(symbolName), filePath, newText)
+	if err != nil {
+		return "", err
+	}
+	return result.Text(), nil
+}
+
+// ReplaceSymbolResult replaces the definition of a symbol and reports which
+// lines changed
+func ReplaceSymbolResult(ctx context.Context, client *lsp.Client, query SymbolQuery, filePath string, newText string) (*SymbolReplacement, error) {
+	symbolName := query.Name
+	var location protocol.Location
+	var err error
+	if filePath != "" {
+		location, err = locateSymbolInFile(ctx, client, filePath, symbolName)
+	} else {
+		location, err = locateSymbolInWorkspace(ctx, client, query)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	path := strings.TrimPrefix(string(location.URI), "file://")
+	if err := utilities.CheckWriteAccess(path); err != nil {
+		return nil, fmt.Errorf("cannot replace %s: %w", symbolName, err)
+	}
+	if err := client.OpenFile(ctx, path); err != nil {
+		return nil, fmt.Errorf("could not open file: %v", err)
+	}
+
+	_, definition, _, err := GetFullDefinition(ctx, client, location)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get definition of %s: %v", symbolName, err)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %v", err)
+	}
+	lines := strings.Split(strings.ReplaceAll(string(content), "\r\n", "\n"), "\n")
+
+	newText = strings.TrimSuffix(strings.ReplaceAll(newText, "\r\n", "\n"), "\n")
+	if strings.TrimSpace(newText) == "" {
+		return nil, fmt.Errorf("new source for %s is empty", symbolName)
+	}
+
+	startLine := int(definition.Range.Start.Line)
+	endLine := min(int(definition.Range.End.Line), len(lines)-1)
+
+	// Replace the attached doc comment when the new source brings its own,
+	// and leave it alone when it does not
+	firstNewLine := strings.TrimSpace(strings.SplitN(strings.TrimLeft(newText, "\n"), "\n", 2)[0])
+	if isCommentOrAttribute(firstNewLine) {
+		startLine = declarationStart(lines, startLine)
+	} else {
+		for startLine < endLine && isCommentLine(strings.TrimSpace(lines[startLine])) {
+			startLine++
+		}
+	}
+
+	edit := protocol.WorkspaceEdit{
+		Changes: map[protocol.DocumentUri][]protocol.TextEdit{
+			location.URI: {{
+				Range: protocol.Range{
+					Start: protocol.Position{Line: uint32(startLine), Character: 0},
+					End:   protocol.Position{Line: uint32(endLine), Character: uint32(len(lines[endLine]))},
+				},
+				NewText: newText,
+			}},
+		},
+	}
+
+	if err := utilities.ApplyWorkspaceEdit(edit, "replace_symbol "+symbolName); err != nil {
+		return nil, fmt.Errorf("failed to replace %s: %v", symbolName, err)
+	}
+
+	hash, err := FileHash(path)
+	if err != nil {
+		return nil, err
+	}
+
+	newLineCount := strings.Count(newText, "\n") + 1
+	return &SymbolReplacement{
+		Symbol:       symbolName,
+		File:         utilities.DisplayPath(path),
+		OldStartLine: startLine + 1,
+		OldEndLine:   endLine + 1,
+		NewStartLine: startLine + 1,
+		NewEndLine:   startLine + newLineCount,
+		Hash:         hash,
+	}, nil
+}
+
+// Text reports the replaced and new line ranges and the file hash
+func (r *SymbolReplacement) Text() string {
+	return fmt.Sprintf("Replaced %s in %s. Lines %d-%d are now lines %d-%d.\nFile hash: %s",
+		r.Symbol, r.File, r.OldStartLine, r.OldEndLine, r.NewStartLine, r.NewEndLine, r.Hash)
+}
+
+// locateSymbolInFile finds the declaration of a symbol among the symbols of a file
+func locateSymbolInFile(ctx context.Context, client *lsp.Client, filePath string, symbolName string) (protocol.Location, error) {
+	filePath, err := readablePath(filePath)
+	if err != nil {
+		return protocol.Location{}, err
+	}
+	if err := client.OpenFile(ctx, filePath); err != nil {
+		return protocol.Location{}, fmt.Errorf("could not open file: %v", err)
+	}
+
+	sym, err := findDocumentSymbol(ctx, client, filePath, symbolName)
+	if err != nil {
+		return protocol.Location{}, err
+	}
+
+	return protocol.Location{
+		URI:   protocol.DocumentUri("file://" + filePath),
+		Range: sym.Range,
+	}, nil
+}
+
+// locateSymbolInWorkspace finds the single declaration of a symbol across
+// the workspace. It is an error if the symbol is declared in several places
+// and the query does not pick one by ID.
+func locateSymbolInWorkspace(ctx context.Context, client *lsp.Client, query SymbolQuery) (protocol.Location, error) {
+	symbols, err := ResolveSymbol(ctx, client, query)
+	if err != nil {
+		return protocol.Location{}, fmt.Errorf("failed to search for symbol: %v", err)
+	}
+
+	switch len(symbols) {
+	case 0:
+		return protocol.Location{}, fmt.Errorf("%s not found", query.Name)
+	case 1:
+		return symbols[0].Location, nil
+	}
+
+	candidates := make([]SymbolCandidate, len(symbols))
+	for i, symbol := range symbols {
+		candidates[i] = newSymbolCandidate(symbol)
+	}
+	return protocol.Location{}, fmt.Errorf("%s resolves to %d locations, no changes were made. Pass filePath, or symbolId set to the ID of the one you mean:\n%s",
+		query.Name, len(symbols), strings.TrimSuffix(candidatesText(candidates), "\n"))
+}
@@ -0,0 +1,63 @@
+package tools
+
+import (
+	"strconv"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
+)
+
+// Result is the outcome of a tool. Text renders it for agents, while the
+// struct itself is what programmatic clients receive as JSON.
+type Result interface {
+	Text() string
+}
+
+// Position is a one-indexed line and column in a file
+type Position struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// Range is a span between two positions in a file
+type Range struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+// Location is a range in a file. The file is shown the same way as in text
+// output, so it may be relative to the workspace.
+type Location struct {
+	File  string `json:"file"`
+	Range Range  `json:"range"`
+}
+
+func newPosition(pos protocol.Position) Position {
+	return Position{Line: int(pos.Line) + 1, Column: int(pos.Character) + 1}
+}
+
+func newRange(rng protocol.Range) Range {
+	return Range{Start: newPosition(rng.Start), End: newPosition(rng.End)}
+}
+
+func newLocation(loc protocol.Location) Location {
+	return Location{File: utilities.DisplayURI(loc.URI), Range: newRange(loc.Range)}
+}
+
+// positionBefore reports whether a comes before b in a document
+func positionBefore(a, b protocol.Position) bool {
+	if a.Line != b.Line {
+		return a.Line < b.Line
+	}
+	return a.Character < b.Character
+}
+
+// String renders a position as L<line>:C<column>
+func (p Position) String() string {
+	return "L" + strconv.Itoa(p.Line) + ":C" + strconv.Itoa(p.Column)
+}
+
+// String renders a range as L<line>:C<column> - L<line>:C<column>
+func (r Range) String() string {
+	return r.Start.String() + " - " + r.End.String()
+}
@@ -0,0 +1,97 @@
+package tools
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewRange(t *testing.T) {
+	rng := newRange(protocol.Range{
+		Start: protocol.Position{Line: 0, Character: 4},
+		End:   protocol.Position{Line: 2, Character: 0},
+	})
+
+	assert.Equal(t, Range{Start: Position{Line: 1, Column: 5}, End: Position{Line: 3, Column: 1}}, rng)
+	assert.Equal(t, "L1:C5 - L3:C1", rng.String())
+}
+
+func TestDiagnosticsResultText(t *testing.T) {
+	result := &DiagnosticsResult{
+		File: "main.go",
+		Diagnostics: []Diagnostic{
+			{
+				Severity: "ERROR",
+				Range:    Range{Start: Position{Line: 9, Column: 9}, End: Position{Line: 9, Column: 10}},
+				Message:  "cannot use 3",
+				Source:   "compiler",
+				Code:     "IncompatibleAssign",
+			},
+			{
+				Severity: "HINT",
+				Range:    Range{Start: Position{Line: 3, Column: 1}, End: Position{Line: 3, Column: 2}},
+				Message:  "unused",
+				Code:     "U1000",
+			},
+		},
+		Snippet: "9|\treturn 3\n",
+	}
+
+	assert.Equal(t, "main.go\nDiagnostics in File: 2\n"+
+		"ERROR at L9:C9: cannot use 3 (Source: compiler, Code: IncompatibleAssign)\n"+
+		"HINT at L3:C1: unused (Code: U1000)\n"+
+		"\n9|\treturn 3\n", result.Text())
+
+	assert.Equal(t, "No diagnostics found for main.go", (&DiagnosticsResult{File: "main.go"}).Text())
+}
+
+func TestDiagnosticsResultJSON(t *testing.T) {
+	result := &DiagnosticsResult{
+		File: "main.go",
+		Diagnostics: []Diagnostic{{
+			Severity: "WARNING",
+			Range:    Range{Start: Position{Line: 8, Column: 2}, End: Position{Line: 8, Column: 33}},
+			Message:  "unreachable code",
+		}},
+	}
+
+	data, err := json.Marshal(result)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"file": "main.go",
+		"diagnostics": [{
+			"severity": "WARNING",
+			"range": {"start": {"line": 8, "column": 2}, "end": {"line": 8, "column": 33}},
+			"message": "unreachable code"
+		}]
+	}`, string(data))
+}
+
+func TestCallHierarchyResultText(t *testing.T) {
+	location := func(file string, line int) Location {
+		return Location{File: file, Range: Range{Start: Position{Line: line, Column: 6}, End: Position{Line: line, Column: 10}}}
+	}
+	result := &CallHierarchyResult{
+		Symbol:    "Helper",
+		Direction: "incoming",
+		Matches: []CallHierarchyMatch{
+			{
+				Name: "Helper",
+				Calls: []CallItem{
+					{Name: "Helper", Detail: "pkg", Location: location("helper.go", 4), Depth: 0, Parent: -1},
+					{Name: "Main", Detail: "pkg", Location: location("main.go", 7), Depth: 1, Parent: 0, Error: "no calls"},
+				},
+			},
+			{Name: "Other", Error: "not a function"},
+		},
+	}
+
+	assert.Equal(t, "\n---\n"+
+		"Name: Helper\nDetail: pkg\nFile: helper.go\nRange: L4:C6 - L4:C10\n"+
+		"- Called By: Main\n  Detail: pkg\n  File: main.go\n  Range: L7:C6 - L7:C10\n  Error: no calls\n"+
+		"\n---\n"+
+		"Other: Error: not a function\n", result.Text())
+}
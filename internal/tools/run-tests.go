@@ -0,0 +1,826 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
+)
+
+// Test outcomes
+const (
+	TestPassed  = "passed"
+	TestFailed  = "failed"
+	TestSkipped = "skipped"
+)
+
+// Where the command of a test run comes from
+const (
+	TestSourceLens     = "lens"
+	TestSourceFallback = "fallback"
+)
+
+// DefaultTestCommands are the commands that run the tests of a language the
+// server has no test lens for, keyed by language. A language without a
+// default is keyed by its file extension, such as "rb". These placeholders
+// are replaced by the tests to run:
+//
+//	{file}     the test file, relative to the workspace
+//	{dir}      the directory of the test file, as in ./internal/tools
+//	{tests}    the test names, each quoted for the shell
+//	{ids}      file::name for each test, the way pytest selects tests
+//	{pattern}  a regular expression matching any of the names, escaped to
+//	           be put between single quotes
+var DefaultTestCommands = map[string]string{
+	"go":         "go test -json {dir} -run '^({pattern})$'",
+	"python":     "pytest -rA --tb=short {ids}",
+	"rust":       "cargo test -- --exact {tests}",
+	"javascript": "npx jest --json --testLocationInResults {file} -t '{pattern}'",
+	"typescript": "npx jest --json --testLocationInResults {file} -t '{pattern}'",
+}
+
+// The frameworks whose output the commands of a language are parsed as
+var languageFrameworks = map[string]string{
+	"go":         TestFrameworkGo,
+	"python":     TestFrameworkPytest,
+	"rust":       TestFrameworkCargo,
+	"javascript": TestFrameworkJest,
+	"typescript": TestFrameworkJest,
+}
+
+// How much of the output of a test command is kept when its results could
+// not be told from it
+const testOutputTail = 4000
+
+// RunTestsOptions controls which tests are run and how
+type RunTestsOptions struct {
+	// Commands override DefaultTestCommands for some languages
+	Commands map[string]string
+	// Timeout limits how long the test commands may take altogether, zero
+	// for no limit
+	Timeout time.Duration
+	// Depth is how many levels of callers are followed to find the tests of
+	// a symbol that is not a test itself
+	Depth int
+}
+
+// command returns the test command configured for a language
+func (o RunTestsOptions) command(language string) (string, bool) {
+	if command, ok := o.Commands[language]; ok {
+		return command, command != ""
+	}
+	command, ok := DefaultTestCommands[language]
+	return command, ok
+}
+
+// RunTestsResult is the outcome of running the tests of a file or symbol
+type RunTestsResult struct {
+	// Target is the file or symbol the tests were selected by
+	Target  string        `json:"target"`
+	Runs    []TestRun     `json:"runs"`
+	Tests   []TestOutcome `json:"tests"`
+	Passed  int           `json:"passed"`
+	Failed  int           `json:"failed"`
+	Skipped int           `json:"skipped"`
+	// Errors are the steps that failed, leaving the result incomplete
+	Errors []string `json:"errors,omitempty"`
+	// Candidates are the symbols the name matches when it is ambiguous, in
+	// which case nothing is run
+	Candidates []SymbolCandidate `json:"candidates,omitempty"`
+}
+
+// TestRun is a test command that was run
+type TestRun struct {
+	// Source is "lens" for a command taken from a test code lens of the
+	// server and "fallback" for the command configured for the language
+	Source string `json:"source"`
+	// Lens is the title of the code lens the command comes from
+	Lens      string `json:"lens,omitempty"`
+	Framework string `json:"framework,omitempty"`
+	Command   string `json:"command"`
+	Dir       string `json:"dir"`
+	ExitCode  int    `json:"exitCode"`
+	TimedOut  bool   `json:"timedOut,omitempty"`
+	// Problems are errors reported outside of any test, such as build errors
+	Problems []TestFailure `json:"problems,omitempty"`
+	// Output is the end of the output, kept when the results of the tests
+	// could not be told from it
+	Output string `json:"output,omitempty"`
+}
+
+// TestOutcome is the result of a single test
+type TestOutcome struct {
+	// Name is the name the framework reported the test under
+	Name      string    `json:"name"`
+	Framework string    `json:"framework,omitempty"`
+	Status    string    `json:"status"`
+	Location  *Location `json:"location,omitempty"`
+	// Failures are the failed assertions and panics of the test
+	Failures []TestFailure `json:"failures,omitempty"`
+	// Run is the index of the run that ran the test
+	Run int `json:"run"`
+}
+
+// TestFailure is a failure message and, when the output tells, the line
+// it was reported at
+type TestFailure struct {
+	Message  string    `json:"message"`
+	Location *Location `json:"location,omitempty"`
+}
+
+// RunTests runs the tests of a file, or of every symbol matching a name when
+// symbolName is set, and reports the outcome of each test
+func RunTests(ctx context.Context, client *lsp.Client, filePath string, symbolName string, options RunTestsOptions) (string, error) {
+	var query SymbolQuery
+	if symbolName != "" {
+		query = ParseSymbolQuery(symbolName)
+		query.All = true
+	}
+	result, err := RunTestsResultFor(ctx, client, filePath, query, options)
+	if err != nil {
+		return "", err
+	}
+	return result.Text(), nil
+}
+
+// RunTestsResultFor runs the tests of a file, or of the symbols matching a
+// query when it has a name. Tests are run the way the code lenses of the
+// server run them where it has any, and otherwise with the test command of
+// their language. The tests of a symbol that is not a test itself are the
+// ones calling it, as found by related_tests.
+func RunTestsResultFor(ctx context.Context, client *lsp.Client, filePath string, query SymbolQuery, options RunTestsOptions) (*RunTestsResult, error) {
+	if options.Depth < 1 || options.Depth > MaxCallHierarchyDepth {
+		return nil, fmt.Errorf("depth must be between 1 and %d, got %d", MaxCallHierarchyDepth, options.Depth)
+	}
+
+	planner := newTestPlanner(client, options)
+	result := &RunTestsResult{Runs: []TestRun{}, Tests: []TestOutcome{}}
+
+	if query.Name == "" {
+		if filePath == "" {
+			return nil, fmt.Errorf("either a file path or a symbol name is required")
+		}
+		path, err := readablePath(filePath)
+		if err != nil {
+			return nil, err
+		}
+		result.Target = utilities.DisplayPath(path)
+		if err := planner.addFile(ctx, path); err != nil {
+			return nil, err
+		}
+	} else {
+		result.Target = query.Name
+		symbols, candidates, err := resolveUnambiguous(ctx, client, query)
+		if err != nil {
+			return nil, err
+		}
+		if len(candidates) > 0 {
+			result.Candidates = candidates
+			return result, nil
+		}
+		for _, symbol := range symbols {
+			planner.addSymbol(ctx, symbol)
+		}
+		if planner.empty() {
+			related, err := FindRelatedTestsResult(ctx, client, query, options.Depth)
+			if err != nil {
+				return nil, err
+			}
+			planner.errors = append(planner.errors, related.Errors...)
+			for _, test := range related.Tests {
+				planner.addRelatedTest(ctx, test)
+			}
+		}
+	}
+
+	runs := planner.plan()
+	result.Errors = planner.errors
+
+	if options.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, options.Timeout)
+		defer cancel()
+	}
+	for index, run := range runs {
+		testRun, outcomes := run.execute(ctx)
+		for _, outcome := range outcomes {
+			outcome.Run = index
+			if outcome.Framework == "" {
+				outcome.Framework = run.framework
+			}
+			if outcome.Location == nil {
+				outcome.Location = planner.locate(outcome.Name)
+			}
+			switch outcome.Status {
+			case TestPassed:
+				result.Passed++
+			case TestFailed:
+				result.Failed++
+			case TestSkipped:
+				result.Skipped++
+			}
+			result.Tests = append(result.Tests, outcome)
+		}
+		result.Runs = append(result.Runs, testRun)
+	}
+	return result, nil
+}
+
+// plannedTest is a test expected to run, used to pick the command for it
+// and to locate its result
+type plannedTest struct {
+	name        string
+	framework   string
+	path        string
+	declaration protocol.Range
+}
+
+// plannedRun is a test command to run
+type plannedRun struct {
+	source    string
+	lens      string
+	framework string
+	command   string
+	dir       string
+	// pkgDir is the directory the file names in Go test output are
+	// relative to
+	pkgDir string
+}
+
+// goLensTests are the Go tests of one package that lenses run
+type goLensTests struct {
+	path  string
+	lens  string
+	names []string
+}
+
+// testPlanner collects the tests of the files and symbols it is given and
+// the commands that run them
+type testPlanner struct {
+	client   *lsp.Client
+	options  RunTestsOptions
+	detector *testDetector
+	tests    []plannedTest
+	// Go lenses are merged into one run per package
+	goLenses   []*goLensTests
+	runs       []plannedRun
+	lensRanges map[string][]protocol.Range
+	lensFiles  map[string][]protocol.CodeLens
+	errors     []string
+}
+
+func newTestPlanner(client *lsp.Client, options RunTestsOptions) *testPlanner {
+	return &testPlanner{
+		client:     client,
+		options:    options,
+		detector:   &testDetector{files: make(map[string][]string)},
+		lensRanges: make(map[string][]protocol.Range),
+		lensFiles:  make(map[string][]protocol.CodeLens),
+	}
+}
+
+// empty reports whether no tests or test lenses were found yet
+func (p *testPlanner) empty() bool {
+	return len(p.tests) == 0 && len(p.goLenses) == 0 && len(p.runs) == 0
+}
+
+// addFile adds every test in a file
+func (p *testPlanner) addFile(ctx context.Context, path string) error {
+	if err := p.client.OpenFile(ctx, path); err != nil {
+		return fmt.Errorf("could not open file: %v", err)
+	}
+	symbols, err := listDocumentSymbols(ctx, p.client, path)
+	if err != nil {
+		return err
+	}
+	uri := protocol.DocumentUri("file://" + path)
+	for _, symbol := range symbols {
+		p.addTest(protocol.CallHierarchyItem{Name: symbol.Name, URI: uri, Range: symbol.Range, SelectionRange: symbol.Range})
+	}
+	p.addLenses(ctx, path, nil)
+	return nil
+}
+
+// addSymbol adds the test lenses within a symbol, and the symbol itself if
+// it is a test
+func (p *testPlanner) addSymbol(ctx context.Context, symbol ResolvedSymbol) {
+	path := symbol.Location.URI.Path()
+	if err := p.client.OpenFile(ctx, path); err != nil {
+		p.errors = append(p.errors, fmt.Sprintf("%s: could not open file: %v", symbol.Name, err))
+		return
+	}
+	declaration := p.declaration(ctx, path, symbol.Location.Range)
+	p.addTest(protocol.CallHierarchyItem{Name: symbol.Name, URI: symbol.Location.URI, Range: declaration, SelectionRange: symbol.Location.Range})
+	p.addLenses(ctx, path, &declaration)
+}
+
+// addRelatedTest adds a test found calling a symbol
+func (p *testPlanner) addRelatedTest(ctx context.Context, test RelatedTest) {
+	if test.Framework == "" {
+		return
+	}
+	p.tests = append(p.tests, plannedTest{name: test.Name, framework: test.Framework, path: test.path, declaration: test.declaration})
+	if err := p.client.OpenFile(ctx, test.path); err != nil {
+		p.errors = append(p.errors, fmt.Sprintf("%s: could not open file: %v", test.Name, err))
+		return
+	}
+	p.addLenses(ctx, test.path, &test.declaration)
+}
+
+// addTest adds a function if it is a test
+func (p *testPlanner) addTest(item protocol.CallHierarchyItem) {
+	framework, name, ok := p.detector.detect(item)
+	if !ok || framework == "" {
+		return
+	}
+	p.tests = append(p.tests, plannedTest{name: name, framework: framework, path: item.URI.Path(), declaration: item.Range})
+}
+
+// declaration returns the range of the whole declaration of a symbol whose
+// name is at the given range, as workspace symbols may only span the name
+func (p *testPlanner) declaration(ctx context.Context, path string, name protocol.Range) protocol.Range {
+	symbols, err := listDocumentSymbols(ctx, p.client, path)
+	if err != nil {
+		return name
+	}
+	declaration := name
+	found := false
+	for _, symbol := range symbols {
+		if !rangeContains(symbol.Range, name) {
+			continue
+		}
+		if !found || rangeContains(declaration, symbol.Range) {
+			declaration = symbol.Range
+			found = true
+		}
+	}
+	return declaration
+}
+
+// addLenses adds the runs for the test lenses of a file that start within
+// a range, or anywhere in the file if the range is nil
+func (p *testPlanner) addLenses(ctx context.Context, path string, within *protocol.Range) {
+	lenses, ok := p.lensFiles[path]
+	if !ok {
+		var err error
+		lenses, err = fetchCodeLenses(ctx, p.client, path)
+		if err != nil {
+			p.errors = append(p.errors, fmt.Sprintf("%s: failed to get code lenses: %v", utilities.DisplayPath(path), err))
+		}
+		p.lensFiles[path] = lenses
+	}
+
+	var rust []rustTestLens
+	for _, lens := range lenses {
+		if lens.Command == nil || len(lens.Command.Arguments) == 0 {
+			continue
+		}
+		if within != nil && (lens.Range.Start.Line < within.Start.Line || lens.Range.Start.Line > within.End.Line) {
+			continue
+		}
+		switch lens.Command.Command {
+		case "gopls.run_tests", "gopls.test":
+			p.addGoLens(path, lens)
+		case "rust-analyzer.runSingle":
+			if runnable, ok := parseRustRunnable(lens.Command.Arguments[0]); ok {
+				rust = append(rust, rustTestLens{lens: lens, runnable: runnable})
+			}
+		}
+	}
+
+	// A lens running a whole module makes the lenses of its tests redundant
+	sort.SliceStable(rust, func(i, j int) bool {
+		return positionBefore(rust[i].lens.Range.Start, rust[j].lens.Range.Start)
+	})
+	var kept []protocol.Range
+	for _, lens := range rust {
+		redundant := false
+		for _, outer := range kept {
+			if rangeContains(outer, lens.lens.Range) {
+				redundant = true
+				break
+			}
+		}
+		if redundant || p.hasLensRange(path, lens.lens.Range) {
+			continue
+		}
+		kept = append(kept, lens.lens.Range)
+		p.lensRanges[path] = append(p.lensRanges[path], lens.lens.Range)
+		p.runs = append(p.runs, lens.run())
+	}
+}
+
+// addGoLens merges the tests a gopls lens runs into the run of its package
+func (p *testPlanner) addGoLens(path string, lens protocol.CodeLens) {
+	var args struct {
+		URI   protocol.DocumentUri
+		Tests []string
+	}
+	if err := json.Unmarshal(lens.Command.Arguments[0], &args); err != nil || len(args.Tests) == 0 {
+		return
+	}
+	if args.URI != "" {
+		path = args.URI.Path()
+	}
+	if len(args.Tests) == 1 {
+		p.lensRanges[path] = append(p.lensRanges[path], lens.Range)
+	}
+
+	dir := filepath.Dir(path)
+	var group *goLensTests
+	for _, g := range p.goLenses {
+		if filepath.Dir(g.path) == dir {
+			group = g
+		}
+	}
+	if group == nil {
+		group = &goLensTests{path: path, lens: lens.Command.Title}
+		p.goLenses = append(p.goLenses, group)
+	}
+	for _, name := range args.Tests {
+		if !slices.Contains(group.names, name) {
+			group.names = append(group.names, name)
+		}
+	}
+}
+
+// hasLensRange reports whether a lens with the same range was added before
+func (p *testPlanner) hasLensRange(path string, rng protocol.Range) bool {
+	return slices.Contains(p.lensRanges[path], rng)
+}
+
+// covered reports whether a lens runs a test
+func (p *testPlanner) covered(test plannedTest) bool {
+	if test.framework == TestFrameworkGo {
+		for _, group := range p.goLenses {
+			if filepath.Dir(group.path) == filepath.Dir(test.path) && slices.Contains(group.names, test.name) {
+				return true
+			}
+		}
+	}
+	for _, rng := range p.lensRanges[test.path] {
+		if rangeContains(test.declaration, rng) || rangeContains(rng, test.declaration) {
+			return true
+		}
+	}
+	return false
+}
+
+// plan returns the runs of the lenses found and the fallback runs of the
+// tests no lens runs
+func (p *testPlanner) plan() []plannedRun {
+	var runs []plannedRun
+	for _, group := range p.goLenses {
+		runs = append(runs, plannedRun{
+			source:    TestSourceLens,
+			lens:      group.lens,
+			framework: TestFrameworkGo,
+			command:   fmt.Sprintf("go test -json %s -run %s", shellQuote(commandDir(group.path)), shellQuote("^("+strings.Join(group.names, "|")+")$")),
+			dir:       utilities.WorkspaceDir(),
+			pkgDir:    filepath.Dir(group.path),
+		})
+	}
+	runs = append(runs, p.runs...)
+
+	// The remaining tests are run per directory, or per file when the
+	// command takes a file
+	type group struct {
+		language string
+		dir      string
+		file     string
+	}
+	var order []group
+	byGroup := make(map[group][]plannedTest)
+	missing := make(map[string]bool)
+	for _, test := range p.tests {
+		if p.covered(test) {
+			continue
+		}
+		language := testLanguage(test.path)
+		command, ok := p.options.command(language)
+		if !ok {
+			if !missing[language] {
+				missing[language] = true
+				p.errors = append(p.errors, fmt.Sprintf("no test command is configured for %s files", language))
+			}
+			continue
+		}
+		g := group{language: language, dir: filepath.Dir(test.path)}
+		if strings.Contains(command, "{file}") {
+			g.file = test.path
+		}
+		if _, ok := byGroup[g]; !ok {
+			order = append(order, g)
+		}
+		byGroup[g] = append(byGroup[g], test)
+	}
+	for _, g := range order {
+		command, _ := p.options.command(g.language)
+		runs = append(runs, plannedRun{
+			source:    TestSourceFallback,
+			framework: languageFrameworks[g.language],
+			command:   expandTestCommand(command, byGroup[g]),
+			dir:       utilities.WorkspaceDir(),
+			pkgDir:    g.dir,
+		})
+	}
+	return runs
+}
+
+// locate returns where a test reported under a name is declared, if it was
+// planned. Frameworks report tests under longer names than they are
+// declared with, such as module paths, subtests or describe blocks.
+func (p *testPlanner) locate(name string) *Location {
+	for _, test := range p.tests {
+		if name == test.name || strings.HasSuffix(name, "::"+test.name) || strings.HasPrefix(name, test.name+"/") || strings.HasSuffix(name, " "+test.name) {
+			location := newLocation(protocol.Location{URI: protocol.DocumentUri("file://" + test.path), Range: test.declaration})
+			return &location
+		}
+	}
+	return nil
+}
+
+// testLanguage returns the language test commands are configured by for a
+// file, or its extension for languages without a default command
+func testLanguage(path string) string {
+	switch ext := filepath.Ext(path); ext {
+	case ".go":
+		return "go"
+	case ".py":
+		return "python"
+	case ".rs":
+		return "rust"
+	case ".js", ".jsx", ".mjs", ".cjs":
+		return "javascript"
+	case ".ts", ".tsx", ".mts", ".cts":
+		return "typescript"
+	default:
+		return strings.TrimPrefix(ext, ".")
+	}
+}
+
+// expandTestCommand replaces the placeholders of a test command with the
+// tests to run, which share a directory
+func expandTestCommand(command string, tests []plannedTest) string {
+	var names, quoted, ids, patterns []string
+	for _, test := range tests {
+		id := shellQuote(utilities.WorkspaceRelativePath(test.path) + "::" + test.name)
+		if !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+		if slices.Contains(names, test.name) {
+			continue
+		}
+		names = append(names, test.name)
+		quoted = append(quoted, shellQuote(test.name))
+		patterns = append(patterns, regexp.QuoteMeta(test.name))
+	}
+	return strings.NewReplacer(
+		"{file}", shellQuote(utilities.WorkspaceRelativePath(tests[0].path)),
+		"{dir}", shellQuote(commandDir(tests[0].path)),
+		"{tests}", strings.Join(quoted, " "),
+		"{ids}", strings.Join(ids, " "),
+		"{pattern}", strings.ReplaceAll(strings.Join(patterns, "|"), "'", `'\''`),
+	).Replace(command)
+}
+
+// rustRunnable is the argument of rust-analyzer's runSingle lens, which
+// rust-analyzer leaves to the editor to run
+type rustRunnable struct {
+	Label string `json:"label"`
+	Kind  string `json:"kind"`
+	Args  struct {
+		WorkspaceRoot  string   `json:"workspaceRoot"`
+		Cwd            string   `json:"cwd"`
+		CargoArgs      []string `json:"cargoArgs"`
+		CargoExtraArgs []string `json:"cargoExtraArgs"`
+		ExecutableArgs []string `json:"executableArgs"`
+	} `json:"args"`
+}
+
+// rustTestLens is a rust-analyzer lens that runs tests
+type rustTestLens struct {
+	lens     protocol.CodeLens
+	runnable rustRunnable
+}
+
+// parseRustRunnable returns the runnable of a lens if it runs cargo test
+func parseRustRunnable(arg json.RawMessage) (rustRunnable, bool) {
+	var runnable rustRunnable
+	if err := json.Unmarshal(arg, &runnable); err != nil {
+		return runnable, false
+	}
+	args := runnable.Args.CargoArgs
+	return runnable, runnable.Kind == "cargo" && len(args) > 0 && args[0] == "test"
+}
+
+// run returns the cargo command the lens stands for
+func (l rustTestLens) run() plannedRun {
+	args := l.runnable.Args
+	var command strings.Builder
+	command.WriteString("cargo")
+	for _, arg := range append(slices.Clone(args.CargoArgs), args.CargoExtraArgs...) {
+		command.WriteString(" " + shellQuote(arg))
+	}
+	if len(args.ExecutableArgs) > 0 {
+		command.WriteString(" --")
+		for _, arg := range args.ExecutableArgs {
+			command.WriteString(" " + shellQuote(arg))
+		}
+	}
+
+	dir := args.Cwd
+	if dir == "" {
+		dir = args.WorkspaceRoot
+	}
+	if dir == "" {
+		dir = utilities.WorkspaceDir()
+	}
+	return plannedRun{
+		source:    TestSourceLens,
+		lens:      l.lens.Command.Title,
+		framework: TestFrameworkCargo,
+		command:   command.String(),
+		dir:       dir,
+		pkgDir:    args.WorkspaceRoot,
+	}
+}
+
+// rangeContains reports whether inner lies within outer
+func rangeContains(outer, inner protocol.Range) bool {
+	return !positionBefore(inner.Start, outer.Start) && !positionBefore(outer.End, inner.End)
+}
+
+// lockedBuffer is a buffer that the output and error streams of a command
+// can be written to at the same time
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+// execute runs the command and parses the outcome of each test from its
+// output. Commands are only run inside the workspace, since lenses name the
+// directory they run in.
+func (r plannedRun) execute(ctx context.Context) (TestRun, []TestOutcome) {
+	run := TestRun{
+		Source:    r.source,
+		Lens:      r.lens,
+		Framework: r.framework,
+		Command:   r.command,
+		Dir:       utilities.DisplayPath(r.dir),
+	}
+
+	if err := utilities.CheckPathWithinRoot(utilities.WorkspaceDir(), r.dir); err != nil {
+		run.ExitCode = -1
+		run.Problems = []TestFailure{{Message: fmt.Sprintf("not run: %v", err)}}
+		return run, nil
+	}
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", r.command)
+	cmd.Dir = r.dir
+	// Test binaries left running by the shell must not keep the output open
+	cmd.WaitDelay = 5 * time.Second
+	var output lockedBuffer
+	var stdout bytes.Buffer
+	cmd.Stdout = io.MultiWriter(&stdout, &output)
+	cmd.Stderr = &output
+
+	toolsLogger.Debug("Running tests: %s (in %s)", r.command, r.dir)
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	switch {
+	case ctx.Err() != nil:
+		run.ExitCode = -1
+		run.TimedOut = errors.Is(ctx.Err(), context.DeadlineExceeded)
+	case errors.As(err, &exitErr):
+		run.ExitCode = exitErr.ExitCode()
+	case err != nil:
+		run.ExitCode = -1
+		output.Write([]byte(err.Error()))
+	}
+
+	combined := output.buf.String()
+	paths := outputPaths{r.pkgDir, r.dir, utilities.WorkspaceDir()}
+	outcomes, problems := parseTestOutput(r.framework, combined, stdout.String(), paths)
+	run.Problems = problems
+
+	failed := slices.ContainsFunc(outcomes, func(outcome TestOutcome) bool { return outcome.Status == TestFailed })
+	if run.TimedOut || (len(problems) == 0 && (len(outcomes) == 0 || run.ExitCode != 0 && !failed)) {
+		run.Output = outputTail(combined)
+	}
+	return run, outcomes
+}
+
+// outputTail returns the end of a command's output, from the start of a line
+func outputTail(output string) string {
+	output = stripANSI(output)
+	if len(output) <= testOutputTail {
+		return output
+	}
+	tail := output[len(output)-testOutputTail:]
+	if i := strings.IndexByte(tail, '\n'); i >= 0 {
+		tail = tail[i+1:]
+	}
+	return "...\n" + tail
+}
+
+// Text renders the failures with their messages, the other tests and the
+// commands that ran them
+func (r *RunTestsResult) Text() string {
+	if len(r.Candidates) > 0 {
+		return ambiguityText(r.Target, r.Candidates)
+	}
+
+	var result strings.Builder
+	for _, err := range r.Errors {
+		fmt.Fprintf(&result, "Error: %s\n", err)
+	}
+	if len(r.Runs) == 0 {
+		fmt.Fprintf(&result, "No tests found for %s\n", r.Target)
+		return result.String()
+	}
+
+	fmt.Fprintf(&result, "Ran %s for %s: %d passed, %d failed", countOf(len(r.Tests), "test"), r.Target, r.Passed, r.Failed)
+	if r.Skipped > 0 {
+		fmt.Fprintf(&result, ", %d skipped", r.Skipped)
+	}
+	result.WriteString("\n")
+
+	var passed, skipped []string
+	for _, test := range r.Tests {
+		switch test.Status {
+		case TestPassed:
+			passed = append(passed, test.Name)
+		case TestSkipped:
+			skipped = append(skipped, test.Name)
+		case TestFailed:
+			result.WriteString("\nFAIL " + test.Name)
+			if test.Location != nil {
+				fmt.Fprintf(&result, " (%s:%d)", test.Location.File, test.Location.Range.Start.Line)
+			}
+			result.WriteString("\n")
+			for _, failure := range test.Failures {
+				result.WriteString(failureText(failure))
+			}
+		}
+	}
+	if len(passed) > 0 {
+		fmt.Fprintf(&result, "\nPassed: %s\n", strings.Join(passed, ", "))
+	}
+	if len(skipped) > 0 {
+		fmt.Fprintf(&result, "\nSkipped: %s\n", strings.Join(skipped, ", "))
+	}
+
+	result.WriteString("\nCommands:\n")
+	for _, run := range r.Runs {
+		fmt.Fprintf(&result, "  %s\n", run.Command)
+		source := "test command for the language"
+		if run.Source == TestSourceLens {
+			source = fmt.Sprintf("code lens %q", run.Lens)
+		}
+		status := fmt.Sprintf("exit status %d", run.ExitCode)
+		if run.TimedOut {
+			status = "timed out"
+		}
+		fmt.Fprintf(&result, "    from %s, %s\n", source, status)
+		for _, problem := range run.Problems {
+			result.WriteString("  " + failureText(problem))
+		}
+		if run.Output != "" {
+			result.WriteString("    Output:\n")
+			for _, line := range strings.Split(strings.TrimRight(run.Output, "\n"), "\n") {
+				fmt.Fprintf(&result, "      %s\n", line)
+			}
+		}
+	}
+	return result.String()
+}
+
+// failureText renders a failure indented under its test
+func failureText(failure TestFailure) string {
+	prefix := "  "
+	if failure.Location != nil {
+		prefix += fmt.Sprintf("%s:%d: ", failure.Location.File, failure.Location.Range.Start.Line)
+	}
+	return prefix + strings.ReplaceAll(strings.TrimRight(failure.Message, "\n"), "\n", "\n    ") + "\n"
+}
@@ -0,0 +1,135 @@
+package tools
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpandTestCommand(t *testing.T) {
+	utilities.SetWorkspacePaths("/ws", true, nil)
+	defer utilities.SetWorkspacePaths("", false, nil)
+
+	goTests := []plannedTest{
+		{name: "TestParse", framework: TestFrameworkGo, path: "/ws/parse/parse_test.go"},
+		{name: "ExampleParse", framework: TestFrameworkGo, path: "/ws/parse/example_test.go"},
+	}
+	assert.Equal(t, "go test -json ./parse -run '^(TestParse|ExampleParse)$'", expandTestCommand(DefaultTestCommands["go"], goTests))
+
+	pyTests := []plannedTest{
+		{name: "TestParser::test_empty", framework: TestFrameworkPytest, path: "/ws/tests/test_parse.py"},
+		{name: "test_module", framework: TestFrameworkPytest, path: "/ws/tests/test_parse.py"},
+	}
+	assert.Equal(t, "pytest -rA --tb=short tests/test_parse.py::TestParser::test_empty tests/test_parse.py::test_module",
+		expandTestCommand(DefaultTestCommands["python"], pyTests))
+
+	jsTests := []plannedTest{{name: "doesn't crash (yet)", framework: TestFrameworkJest, path: "/ws/src/parse.test.js"}}
+	assert.Equal(t, `npx jest --json --testLocationInResults src/parse.test.js -t 'doesn'\''t crash \(yet\)'`,
+		expandTestCommand(DefaultTestCommands["javascript"], jsTests))
+	assert.Equal(t, `mocha 'doesn'\''t crash (yet)'`, expandTestCommand("mocha {tests}", jsTests))
+}
+
+func TestRunTestsOptionsCommand(t *testing.T) {
+	options := RunTestsOptions{Commands: map[string]string{"python": "python -m unittest {ids}", "rust": "", "rb": "bundle exec rspec {file}"}}
+
+	command, ok := options.command("python")
+	assert.True(t, ok)
+	assert.Equal(t, "python -m unittest {ids}", command)
+
+	_, ok = options.command("rust")
+	assert.False(t, ok, "an empty command disables the fallback")
+
+	command, _ = options.command(testLanguage("/ws/spec/parse_spec.rb"))
+	assert.Equal(t, "bundle exec rspec {file}", command)
+
+	command, _ = options.command(testLanguage("/ws/src/parse.test.tsx"))
+	assert.Equal(t, DefaultTestCommands["typescript"], command)
+}
+
+func TestRustTestLens(t *testing.T) {
+	arg := json.RawMessage(`{"label":"test tests::parses","kind":"cargo","args":{` +
+		`"workspaceRoot":"/ws","cwd":"/ws/calc",` +
+		`"cargoArgs":["test","--package","calc","--lib"],` +
+		`"executableArgs":["tests::parses","--exact","--show-output"]}}`)
+	runnable, ok := parseRustRunnable(arg)
+	assert.True(t, ok)
+
+	lens := rustTestLens{lens: protocol.CodeLens{Command: &protocol.Command{Title: "▶︎ Run Test"}}, runnable: runnable}
+	assert.Equal(t, plannedRun{
+		source:    TestSourceLens,
+		lens:      "▶︎ Run Test",
+		framework: TestFrameworkCargo,
+		command:   "cargo test --package calc --lib -- tests::parses --exact --show-output",
+		dir:       "/ws/calc",
+		pkgDir:    "/ws",
+	}, lens.run())
+
+	// Binaries are run, not tested
+	_, ok = parseRustRunnable(json.RawMessage(`{"label":"run calc","kind":"cargo","args":{"cargoArgs":["run","--bin","calc"]}}`))
+	assert.False(t, ok)
+}
+
+func TestRunTestsText(t *testing.T) {
+	result := &RunTestsResult{
+		Target: "calc_test.go",
+		Runs: []TestRun{
+			{Source: TestSourceLens, Lens: "run test", Command: "go test -json . -run '^(TestAdd|TestBroken)$'", ExitCode: 1},
+			{Source: TestSourceFallback, Command: "go test -json . -run '^(ExampleAdd)$'"},
+		},
+		Tests: []TestOutcome{
+			{Name: "TestAdd", Status: TestPassed},
+			{Name: "TestBroken", Status: TestFailed, Location: at("calc_test.go", 11, 1), Failures: []TestFailure{
+				{Message: "got 4\nwant 5", Location: at("calc_test.go", 12, 1)},
+			}},
+			{Name: "ExampleAdd", Status: TestPassed, Run: 1},
+		},
+		Passed: 2,
+		Failed: 1,
+	}
+
+	assert.Equal(t, "Ran 3 tests for calc_test.go: 2 passed, 1 failed\n"+
+		"\n"+
+		"FAIL TestBroken (calc_test.go:11)\n"+
+		"  calc_test.go:12: got 4\n"+
+		"    want 5\n"+
+		"\n"+
+		"Passed: TestAdd, ExampleAdd\n"+
+		"\n"+
+		"Commands:\n"+
+		"  go test -json . -run '^(TestAdd|TestBroken)$'\n"+
+		"    from code lens \"run test\", exit status 1\n"+
+		"  go test -json . -run '^(ExampleAdd)$'\n"+
+		"    from test command for the language, exit status 0\n", result.Text())
+}
+
+func TestExecuteOutsideWorkspace(t *testing.T) {
+	workspace := t.TempDir()
+	outside := t.TempDir()
+	utilities.SetWorkspacePaths(workspace, false, nil)
+	defer utilities.SetWorkspacePaths("", false, nil)
+
+	run := plannedRun{source: TestSourceLens, framework: TestFrameworkCargo, command: "touch ran", dir: outside}
+	result, outcomes := run.execute(t.Context())
+	assert.Empty(t, outcomes)
+	assert.Equal(t, -1, result.ExitCode)
+	require.Len(t, result.Problems, 1)
+	assert.Contains(t, result.Problems[0].Message, "outside of "+workspace)
+	assert.NoFileExists(t, filepath.Join(outside, "ran"))
+
+	run.dir = workspace
+	result, _ = run.execute(t.Context())
+	assert.Equal(t, 0, result.ExitCode)
+	assert.FileExists(t, filepath.Join(workspace, "ran"))
+}
+
+func TestRunTestsDepth(t *testing.T) {
+	for _, depth := range []int{0, -1, MaxCallHierarchyDepth + 1} {
+		_, err := RunTestsResultFor(t.Context(), nil, "", SymbolQuery{Name: "Parse"}, RunTestsOptions{Depth: depth})
+		assert.ErrorContains(t, err, "depth must be between 1 and")
+	}
+}
@@ -0,0 +1,397 @@
+package tools
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
+)
+
+// SymbolQuery is a symbol name split into its qualifiers, along with
+// optional filters on the kind and location of the symbol
+type SymbolQuery struct {
+	// Name is the symbol name as given by the caller
+	Name string
+	// Parts are the qualifiers of the name, outermost first, ending with the
+	// name of the symbol itself
+	Parts []string
+	// Kind only matches symbols of this kind, such as "method", if set
+	Kind string
+	// Path only matches symbols declared in this file or directory, if set
+	Path string
+	// ID picks one of several matching symbols by the ID listed when the
+	// name was ambiguous
+	ID string
+	// All uses every matching symbol instead of asking which one was meant
+	All bool
+}
+
+// ResolvedSymbol is a workspace symbol matching a query
+type ResolvedSymbol struct {
+	// ID identifies the symbol among the matches of the same query
+	ID        string
+	Name      string
+	Kind      protocol.SymbolKind
+	Container string
+	Location  protocol.Location
+}
+
+// SymbolCandidate is one of the symbols an ambiguous name matches
+type SymbolCandidate struct {
+	// ID picks this symbol when passed as symbolId
+	ID        string   `json:"id"`
+	Name      string   `json:"name"`
+	Kind      string   `json:"kind,omitempty"`
+	Container string   `json:"container,omitempty"`
+	Location  Location `json:"location"`
+}
+
+// Keywords clangd accepts in front of a type name
+var typeKeywords = []string{"struct ", "class ", "enum ", "union "}
+
+// Generic arguments, as in Vec<T>, Map[K, V] or List[int]
+var genericArgs = regexp.MustCompile(`<[^<>]*>|\[[^\[\]]*\]`)
+
+// Major version directories of Go modules, as in example.com/mod/v2
+var goMajorVersion = regexp.MustCompile(`^v[0-9]+$`)
+
+// ParseSymbolQuery splits a possibly qualified symbol name into its parts.
+// Names with "::" are split the way Rust and C++ qualify them, as in
+// crate::module::Type::method or ns::Class::method, and other names on "."
+// as in Go's pkg.Type.Method or Python's module.Class.method. Receivers such
+// as (*Type).Method, generic arguments and an import path in front of a Go
+// package are ignored.
+func ParseSymbolQuery(name string) SymbolQuery {
+	query := SymbolQuery{Name: name}
+
+	name = strings.TrimSpace(name)
+	for _, keyword := range typeKeywords {
+		name = strings.TrimPrefix(name, keyword)
+	}
+
+	if !strings.Contains(name, "::") {
+		// github.com/org/repo/pkg.Type qualifies with the package import path
+		if i := strings.LastIndex(name, "/"); i >= 0 {
+			name = name[i+1:]
+		}
+	}
+	parts := splitQualifiedName(name)
+	// Paths relative to the crate or module say nothing about the names
+	for len(parts) > 1 && (parts[0] == "" || parts[0] == "crate" || parts[0] == "self" || parts[0] == "super") {
+		parts = parts[1:]
+	}
+
+	for _, part := range parts {
+		if part = normalizeSymbolPart(part); part != "" {
+			query.Parts = append(query.Parts, part)
+		}
+	}
+	return query
+}
+
+// Qualified reports whether the query names the symbol's containers too
+func (q SymbolQuery) Qualified() bool {
+	return len(q.Parts) > 1
+}
+
+// Base returns the name of the symbol itself, without qualifiers
+func (q SymbolQuery) Base() string {
+	if len(q.Parts) == 0 {
+		return q.Name
+	}
+	return q.Parts[len(q.Parts)-1]
+}
+
+// ResolveSymbol finds the workspace symbols matching a query. Servers differ
+// in how they match qualified names, so if the qualified name finds nothing
+// the server is asked for the bare name and the results are filtered here.
+func ResolveSymbol(ctx context.Context, client *lsp.Client, query SymbolQuery) ([]ResolvedSymbol, error) {
+	if len(query.Parts) == 0 {
+		return nil, fmt.Errorf("symbol name is empty")
+	}
+
+	_, results, err := QuerySymbol(ctx, client, query.Name)
+	if err != nil {
+		return nil, err
+	}
+	matches := query.filter(results)
+
+	if len(matches) == 0 && query.Qualified() {
+		_, results, err := QuerySymbol(ctx, client, query.Base())
+		if err != nil {
+			return nil, err
+		}
+		matches = query.filter(results)
+	}
+
+	assignSymbolIDs(matches)
+	if query.ID == "" {
+		return matches, nil
+	}
+	for _, symbol := range matches {
+		if symbol.ID == query.ID {
+			return []ResolvedSymbol{symbol}, nil
+		}
+	}
+	return nil, fmt.Errorf("no symbol matching %s has ID %s, the symbol may have changed. Call again without symbolId to list the candidates", query.Name, query.ID)
+}
+
+// resolveUnambiguous resolves a query to the symbols a tool acts on. When
+// several symbols match and the query neither picks one by ID nor asks for
+// all of them, it returns the candidates to choose from instead.
+func resolveUnambiguous(ctx context.Context, client *lsp.Client, query SymbolQuery) ([]ResolvedSymbol, []SymbolCandidate, error) {
+	symbols, err := ResolveSymbol(ctx, client, query)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(symbols) <= 1 || query.All || query.ID != "" {
+		return symbols, nil, nil
+	}
+
+	candidates := make([]SymbolCandidate, len(symbols))
+	for i, symbol := range symbols {
+		candidates[i] = newSymbolCandidate(symbol)
+	}
+	return nil, candidates, nil
+}
+
+func newSymbolCandidate(symbol ResolvedSymbol) SymbolCandidate {
+	return SymbolCandidate{
+		ID:        symbol.ID,
+		Name:      symbol.Name,
+		Kind:      protocol.TableKindMap[symbol.Kind],
+		Container: symbol.Container,
+		Location:  newLocation(symbol.Location),
+	}
+}
+
+// assignSymbolIDs sorts symbols by location and gives each an ID that stays
+// the same across calls as long as the symbol keeps its name, kind, container
+// and file, even if lines move. Symbols that are otherwise identical, such
+// as overloads, are told apart by their order in the file.
+func assignSymbolIDs(symbols []ResolvedSymbol) {
+	sort.SliceStable(symbols, func(i, j int) bool {
+		a, b := symbols[i].Location, symbols[j].Location
+		if a.URI != b.URI {
+			return a.URI < b.URI
+		}
+		return positionBefore(a.Range.Start, b.Range.Start)
+	})
+
+	seen := make(map[string]int)
+	for i := range symbols {
+		symbol := &symbols[i]
+		key := fmt.Sprintf("%s\x00%d\x00%s\x00%s", symbol.Name, symbol.Kind, symbol.Container, symbol.Location.URI)
+		sum := sha1.Sum([]byte(key))
+		id := hex.EncodeToString(sum[:4])
+
+		seen[id]++
+		if seen[id] > 1 {
+			id = fmt.Sprintf("%s-%d", id, seen[id])
+		}
+		symbol.ID = id
+	}
+}
+
+// candidatesText renders a numbered list of the symbols a name matches
+func candidatesText(candidates []SymbolCandidate) string {
+	var output strings.Builder
+	for i, candidate := range candidates {
+		output.WriteString(fmt.Sprintf("%d. [%s] ", i+1, candidate.ID))
+		if candidate.Kind != "" {
+			output.WriteString(candidate.Kind + " ")
+		}
+		output.WriteString(candidate.Name)
+		if candidate.Container != "" {
+			output.WriteString(" in " + candidate.Container)
+		}
+		output.WriteString(fmt.Sprintf(" at %s:%d\n", candidate.Location.File, candidate.Location.Range.Start.Line))
+	}
+	return output.String()
+}
+
+// ambiguityText explains that a name matches several symbols and how to
+// pick one of them
+func ambiguityText(name string, candidates []SymbolCandidate) string {
+	return fmt.Sprintf("%s is ambiguous, it matches %d symbols. Call again with symbolId set to the ID of the one you mean, or with all set to true to use every match:\n%s",
+		name, len(candidates), candidatesText(candidates))
+}
+
+// filter returns the results that match the query, without duplicates
+func (q SymbolQuery) filter(results []protocol.WorkspaceSymbolResult) []ResolvedSymbol {
+	var path string
+	if q.Path != "" {
+		path = filepath.Clean(utilities.ResolvePath(q.Path))
+	}
+
+	var matches []ResolvedSymbol
+	seen := make(map[string]bool)
+	for _, result := range results {
+		symbol := ResolvedSymbol{Name: result.GetName(), Location: result.GetLocation()}
+		switch v := result.(type) {
+		case *protocol.SymbolInformation:
+			symbol.Kind = v.Kind
+			symbol.Container = v.ContainerName
+		case *protocol.WorkspaceSymbol:
+			symbol.Kind = v.Kind
+			symbol.Container = v.ContainerName
+		}
+
+		filePath := symbol.Location.URI.Path()
+		if q.Kind != "" && !strings.EqualFold(q.Kind, protocol.TableKindMap[symbol.Kind]) {
+			continue
+		}
+		if path != "" && filePath != path && !strings.HasPrefix(filePath, path+string(filepath.Separator)) {
+			continue
+		}
+		if !q.matches(symbolParts(symbol.Name, symbol.Container), filePath) {
+			continue
+		}
+
+		key := fmt.Sprintf("%s:%d:%d", symbol.Location.URI, symbol.Location.Range.Start.Line, symbol.Location.Range.Start.Character)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		matches = append(matches, symbol)
+	}
+	return matches
+}
+
+// qualifiedPart is a part of a symbol's qualified name. Scope parts come
+// from a package or module path rather than from the declarations the
+// symbol is nested in.
+type qualifiedPart struct {
+	name  string
+	scope bool
+}
+
+// symbolParts qualifies a symbol's name with its container. Go containers
+// are package import paths, others name the enclosing types or modules,
+// and some servers also qualify the name itself, as in Type.Method.
+func symbolParts(name string, container string) []qualifiedPart {
+	var parts []qualifiedPart
+	for _, part := range splitQualifiedName(name) {
+		if part = normalizeSymbolPart(part); part != "" {
+			parts = append(parts, qualifiedPart{name: part})
+		}
+	}
+
+	container = strings.TrimSpace(container)
+	if container == "" {
+		return parts
+	}
+
+	var containerParts []qualifiedPart
+	if strings.Contains(container, "/") {
+		// A Go package import path, named after its last element
+		elements := strings.Split(strings.Trim(container, "/"), "/")
+		pkg := elements[len(elements)-1]
+		if goMajorVersion.MatchString(pkg) && len(elements) > 1 {
+			pkg = elements[len(elements)-2]
+		}
+		// gopkg.in/yaml.v3 is package yaml
+		if i := strings.Index(pkg, ".v"); i > 0 {
+			pkg = pkg[:i]
+		}
+		containerParts = append(containerParts, qualifiedPart{name: pkg, scope: true})
+	} else {
+		// rust-analyzer names methods after their impl block
+		container = strings.TrimPrefix(container, "impl ")
+		if i := strings.LastIndex(container, " for "); i >= 0 {
+			container = container[i+len(" for "):]
+		}
+		for _, part := range splitQualifiedName(container) {
+			if part = normalizeSymbolPart(part); part != "" {
+				containerParts = append(containerParts, qualifiedPart{name: part})
+			}
+		}
+	}
+
+	// Do not repeat the containers the name is already qualified with
+	for overlap := min(len(containerParts), len(parts)-1); overlap > 0; overlap-- {
+		if samePartNames(containerParts[len(containerParts)-overlap:], parts[:overlap]) {
+			return append(containerParts, parts[overlap:]...)
+		}
+	}
+	return append(containerParts, parts...)
+}
+
+// matches reports whether a symbol with the given qualified name and file is
+// the one the query names. The query may leave out outer qualifiers. Outer
+// qualifiers the server does not report, such as Rust modules or Python
+// packages, must name the symbol's package or a directory or file it is
+// declared in.
+func (q SymbolQuery) matches(parts []qualifiedPart, filePath string) bool {
+	matched := 0
+	for matched < len(q.Parts) && matched < len(parts) &&
+		q.Parts[len(q.Parts)-1-matched] == parts[len(parts)-1-matched].name {
+		matched++
+	}
+	if matched == 0 {
+		return false
+	}
+
+	rest := q.Parts[:len(q.Parts)-matched]
+	if len(rest) == 0 {
+		return true
+	}
+
+	// A different type or enclosing declaration rules the symbol out
+	if matched < len(parts) && !parts[len(parts)-1-matched].scope {
+		return false
+	}
+
+	scopes := make(map[string]bool)
+	for _, part := range parts[:len(parts)-matched] {
+		scopes[part.name] = true
+	}
+	dir, file := filepath.Split(filePath)
+	scopes[strings.TrimSuffix(file, filepath.Ext(file))] = true
+	for _, element := range strings.Split(filepath.ToSlash(dir), "/") {
+		scopes[element] = true
+	}
+
+	for _, part := range rest {
+		if !scopes[part] {
+			return false
+		}
+	}
+	return true
+}
+
+// splitQualifiedName splits a name on "::" or, if it has none, on "." after
+// removing generic arguments, which may be qualified themselves
+func splitQualifiedName(name string) []string {
+	for genericArgs.MatchString(name) {
+		name = genericArgs.ReplaceAllString(name, "")
+	}
+	if strings.Contains(name, "::") {
+		return strings.Split(name, "::")
+	}
+	return strings.Split(name, ".")
+}
+
+// normalizeSymbolPart strips receiver decorations such as (*T) from one part
+// of a qualified name
+func normalizeSymbolPart(part string) string {
+	part = strings.NewReplacer("(", "", ")", "", "*", "", "&", "").Replace(part)
+	return strings.TrimSpace(part)
+}
+
+func samePartNames(a, b []qualifiedPart) bool {
+	for i := range a {
+		if a[i].name != b[i].name {
+			return false
+		}
+	}
+	return true
+}
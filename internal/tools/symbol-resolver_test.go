@@ -0,0 +1,136 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSymbolQuery(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected []string
+	}{
+		{"Foo", []string{"Foo"}},
+		{"pkg.Type.Method", []string{"pkg", "Type", "Method"}},
+		{"(*Type).Method", []string{"Type", "Method"}},
+		{"github.com/org/repo/pkg.Type", []string{"pkg", "Type"}},
+		{"crate::module::Type::method", []string{"module", "Type", "method"}},
+		{"::ns::Class::method", []string{"ns", "Class", "method"}},
+		{"Vec<T>::push", []string{"Vec", "push"}},
+		{"module.Class.method", []string{"module", "Class", "method"}},
+		{"struct Point", []string{"Point"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, ParseSymbolQuery(tc.name).Parts)
+		})
+	}
+}
+
+func TestSymbolParts(t *testing.T) {
+	tests := []struct {
+		name      string
+		symbol    string
+		container string
+		expected  []qualifiedPart
+	}{
+		{
+			name:      "go package path",
+			symbol:    "Type.Method",
+			container: "example.com/repo/pkg",
+			expected:  []qualifiedPart{{"pkg", true}, {"Type", false}, {"Method", false}},
+		},
+		{
+			name:      "go major version",
+			symbol:    "Parse",
+			container: "example.com/repo/v2",
+			expected:  []qualifiedPart{{"repo", true}, {"Parse", false}},
+		},
+		{
+			name:      "rust impl block",
+			symbol:    "method",
+			container: "impl Display for Type",
+			expected:  []qualifiedPart{{"Type", false}, {"method", false}},
+		},
+		{
+			name:      "container repeated in name",
+			symbol:    "Class.method",
+			container: "Outer.Class",
+			expected:  []qualifiedPart{{"Outer", false}, {"Class", false}, {"method", false}},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, symbolParts(tc.symbol, tc.container))
+		})
+	}
+}
+
+func TestSymbolQueryMatches(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		symbol    string
+		container string
+		file      string
+		expected  bool
+	}{
+		{"bare name", "Method", "TestStruct.Method", "example.com/repo/pkg", "/ws/pkg/a.go", true},
+		{"go type and method", "TestStruct.Method", "TestStruct.Method", "example.com/repo/pkg", "/ws/pkg/a.go", true},
+		{"go package", "pkg.TestStruct.Method", "TestStruct.Method", "example.com/repo/pkg", "/ws/pkg/a.go", true},
+		{"other go package", "other.TestStruct.Method", "TestStruct.Method", "example.com/repo/pkg", "/ws/pkg/a.go", false},
+		{"other type", "Other.Method", "TestStruct.Method", "example.com/repo/pkg", "/ws/pkg/a.go", false},
+		{"rust method", "Type::method", "method", "impl Type", "/ws/src/lib.rs", true},
+		{"rust module from file", "types::Type::method", "method", "Type", "/ws/src/types.rs", true},
+		{"rust other module", "other::Type::method", "method", "Type", "/ws/src/types.rs", false},
+		{"python module", "helpers.TestClass.method", "method", "TestClass", "/ws/helpers.py", true},
+		{"cpp namespace from directory", "geometry::Shape::area", "area", "Shape", "/ws/geometry/shape.cpp", true},
+		{"different name", "Method", "OtherMethod", "", "/ws/a.go", false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			query := ParseSymbolQuery(tc.query)
+			assert.Equal(t, tc.expected, query.matches(symbolParts(tc.symbol, tc.container), tc.file))
+		})
+	}
+}
+
+func TestAssignSymbolIDs(t *testing.T) {
+	symbol := func(name string, line uint32) ResolvedSymbol {
+		return ResolvedSymbol{
+			Name:     name,
+			Location: protocol.Location{URI: "file:///ws/a.cpp", Range: protocol.Range{Start: protocol.Position{Line: line}}},
+		}
+	}
+
+	symbols := []ResolvedSymbol{symbol("area", 20), symbol("Shape", 3), symbol("area", 10)}
+	assignSymbolIDs(symbols)
+
+	assert.Equal(t, "Shape", symbols[0].Name)
+	assert.Equal(t, uint32(10), symbols[1].Location.Range.Start.Line)
+	assert.Equal(t, symbols[1].ID+"-2", symbols[2].ID, "overloads are told apart by their order")
+
+	// Moving symbols within the file keeps their IDs
+	moved := []ResolvedSymbol{symbol("Shape", 5), symbol("area", 12), symbol("area", 22)}
+	assignSymbolIDs(moved)
+	for i := range symbols {
+		assert.Equal(t, symbols[i].ID, moved[i].ID)
+	}
+}
+
+func TestAmbiguityText(t *testing.T) {
+	candidates := []SymbolCandidate{
+		{ID: "1a2b3c4d", Name: "TestStruct.Method", Kind: "Method", Container: "example.com/pkg", Location: Location{File: "clean.go", Range: Range{Start: Position{Line: 12, Column: 22}}}},
+		{ID: "5e6f7a8b", Name: "Method", Kind: "Struct", Location: Location{File: "types.go", Range: Range{Start: Position{Line: 3, Column: 6}}}},
+	}
+
+	assert.Equal(t, "Method is ambiguous, it matches 2 symbols. Call again with symbolId set to the ID of the one you mean, or with all set to true to use every match:\n"+
+		"1. [1a2b3c4d] Method TestStruct.Method in example.com/pkg at clean.go:12\n"+
+		"2. [5e6f7a8b] Struct Method at types.go:3\n",
+		ambiguityText("Method", candidates))
+}
@@ -0,0 +1,471 @@
+package tools
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
+)
+
+// Terminal colour and style sequences
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+func stripANSI(text string) string {
+	return ansiEscape.ReplaceAllString(text, "")
+}
+
+// outputPaths are the directories relative file names in test output are
+// looked up in, in order
+type outputPaths []string
+
+// location returns the location of a line in a file named in test output
+func (p outputPaths) location(file string, line, column int) *Location {
+	path := file
+	if !filepath.IsAbs(path) {
+		for i, dir := range p {
+			candidate := filepath.Join(dir, file)
+			if i == 0 {
+				path = candidate
+			}
+			if _, err := os.Stat(candidate); err == nil {
+				path = candidate
+				break
+			}
+		}
+	}
+	position := Position{Line: line, Column: max(column, 1)}
+	return &Location{File: utilities.DisplayPath(path), Range: Range{Start: position, End: position}}
+}
+
+// contains reports whether a file is below one of the directories
+func (p outputPaths) contains(path string) bool {
+	for _, dir := range p {
+		if dir != "" && strings.HasPrefix(path, dir+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseTestOutput returns the outcome of each test and the problems outside
+// of tests reported in the output of a test command. Jest reports are read
+// from the standard output alone, as they are JSON.
+func parseTestOutput(framework, output, stdout string, paths outputPaths) ([]TestOutcome, []TestFailure) {
+	output = stripANSI(output)
+	switch framework {
+	case TestFrameworkGo:
+		return parseGoTestOutput(output, paths)
+	case TestFrameworkCargo:
+		return parseLibtestOutput(output, paths)
+	case TestFrameworkPytest:
+		return parsePytestOutput(output, paths), nil
+	case TestFrameworkJest:
+		return parseJestOutput(stdout, paths)
+	}
+	return nil, nil
+}
+
+// goTestEvent is a line of go test -json output
+type goTestEvent struct {
+	Action string
+	Test   string
+	Output string
+}
+
+var (
+	// Go test results, as in "--- FAIL: TestParse (0.00s)"
+	goTestResult = regexp.MustCompile(`^\s*--- (PASS|FAIL|SKIP): (\S+)`)
+	// Go tests starting, as in "=== RUN   TestParse/empty"
+	goTestRun = regexp.MustCompile(`^=== (RUN|CONT|NAME)\s+(\S+)`)
+	// Messages logged by tests, as in "    parse_test.go:12: got 4, want 5"
+	goTestMessage = regexp.MustCompile(`^(\s+)(\S+\.go):(\d+): (.*)$`)
+	// Compiler and vet errors, as in "./parse.go:3:30: undefined: x"
+	goBuildError = regexp.MustCompile(`^(\S+\.go):(\d+):(\d+): (.*)$`)
+	goPanic      = regexp.MustCompile(`^panic: (.*?)( \[recovered.*\])?$`)
+	// Stack frames of a panic, as in "\t/src/parse.go:12 +0x1d"
+	goStackFrame = regexp.MustCompile(`^\t(\S+\.go):(\d+)`)
+)
+
+// parseGoTestOutput reads the output of go test, with or without -json
+func parseGoTestOutput(output string, paths outputPaths) ([]TestOutcome, []TestFailure) {
+	var order []string
+	status := make(map[string]string)
+	lines := make(map[string][]string)
+	var problems []TestFailure
+
+	addLine := func(test, line string) {
+		if test == "" {
+			if match := goBuildError.FindStringSubmatch(line); match != nil {
+				lineNumber, _ := strconv.Atoi(match[2])
+				column, _ := strconv.Atoi(match[3])
+				problems = append(problems, TestFailure{Message: match[4], Location: paths.location(match[1], lineNumber, column)})
+			}
+			return
+		}
+		if _, ok := status[test]; !ok {
+			order = append(order, test)
+			status[test] = ""
+		}
+		lines[test] = append(lines[test], line)
+	}
+
+	current := ""
+	for _, line := range strings.Split(output, "\n") {
+		var event goTestEvent
+		if strings.HasPrefix(line, "{") && json.Unmarshal([]byte(line), &event) == nil && event.Action != "" {
+			switch event.Action {
+			case "output", "build-output":
+				addLine(event.Test, strings.TrimSuffix(event.Output, "\n"))
+			case "run":
+				addLine(event.Test, "")
+			case "pass", "fail", "skip":
+				if event.Test != "" {
+					addLine(event.Test, "")
+					status[event.Test] = event.Action
+				}
+			}
+			continue
+		}
+
+		// Plain go test output
+		if match := goTestRun.FindStringSubmatch(line); match != nil {
+			current = match[2]
+			addLine(current, "")
+			continue
+		}
+		if match := goTestResult.FindStringSubmatch(line); match != nil {
+			current = match[2]
+			addLine(current, "")
+			status[current] = strings.ToLower(match[1])
+			continue
+		}
+		if line == "PASS" || line == "FAIL" || strings.HasPrefix(line, "ok  \t") || strings.HasPrefix(line, "FAIL\t") {
+			current = ""
+		}
+		addLine(current, line)
+	}
+
+	var outcomes []TestOutcome
+	for _, test := range order {
+		outcome := TestOutcome{Name: test, Framework: TestFrameworkGo}
+		switch status[test] {
+		case "pass":
+			outcome.Status = TestPassed
+		case "skip":
+			outcome.Status = TestSkipped
+		case "fail":
+			outcome.Status = TestFailed
+			outcome.Failures = goFailures(lines[test], paths)
+		default:
+			// Tests still running when the binary died, usually of a panic
+			if !strings.Contains(strings.Join(lines[test], "\n"), "panic: ") {
+				continue
+			}
+			outcome.Status = TestFailed
+			outcome.Failures = goFailures(lines[test], paths)
+		}
+		outcomes = append(outcomes, outcome)
+	}
+	return outcomes, problems
+}
+
+// goFailures returns the messages a test logged and the panic that ended it
+func goFailures(lines []string, paths outputPaths) []TestFailure {
+	var failures []TestFailure
+	current, indent := -1, 0
+	panicked := -1
+	for _, line := range lines {
+		if match := goTestMessage.FindStringSubmatch(line); match != nil {
+			lineNumber, _ := strconv.Atoi(match[3])
+			failures = append(failures, TestFailure{Message: match[4], Location: paths.location(match[2], lineNumber, 0)})
+			current, indent = len(failures)-1, len(match[1])
+			continue
+		}
+		trimmed := strings.TrimLeft(line, " \t")
+		if current >= 0 && trimmed != "" && len(line)-len(trimmed) > indent && !strings.HasPrefix(trimmed, "--- ") {
+			failures[current].Message += "\n" + trimmed
+			continue
+		}
+		current = -1
+
+		if match := goPanic.FindStringSubmatch(line); match != nil {
+			failures = append(failures, TestFailure{Message: "panic: " + match[1]})
+			panicked = len(failures) - 1
+			continue
+		}
+		// The panic happened at the first frame in the project
+		if panicked >= 0 && failures[panicked].Location == nil {
+			if match := goStackFrame.FindStringSubmatch(line); match != nil && paths.contains(match[1]) {
+				lineNumber, _ := strconv.Atoi(match[2])
+				failures[panicked].Location = paths.location(match[1], lineNumber, 0)
+			}
+		}
+	}
+	return failures
+}
+
+var (
+	// Rust test results, as in "test parser::tests::parses ... ok"
+	libtestResult = regexp.MustCompile(`^test (.+?) \.\.\. (ok|FAILED|ignored)`)
+	// The captured output of a failed test, as in "---- tests::parses stdout ----"
+	libtestOutput = regexp.MustCompile(`^---- (.+?) stdout ----$`)
+	// Panics since Rust 1.73, with the message on the following lines
+	rustPanic = regexp.MustCompile(`^thread '(.+?)' panicked at (.+?):(\d+):(\d+):$`)
+	// Panics before Rust 1.73
+	rustOldPanic = regexp.MustCompile(`^thread '(.+?)' panicked at '(.*)', (.+?):(\d+):(\d+)$`)
+	rustError    = regexp.MustCompile(`^error(\[\w+\])?: (.*)$`)
+	// Where a compiler error is, as in "  --> src/lib.rs:3:5"
+	rustErrorLocation = regexp.MustCompile(`^\s*--> (.+?):(\d+):(\d+)$`)
+)
+
+// parseLibtestOutput reads the output of cargo test
+func parseLibtestOutput(output string, paths outputPaths) ([]TestOutcome, []TestFailure) {
+	var outcomes []TestOutcome
+	failures := make(map[string][]TestFailure)
+	var problems []TestFailure
+
+	lines := strings.Split(output, "\n")
+	section := ""
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if match := libtestResult.FindStringSubmatch(line); match != nil {
+			status := TestPassed
+			switch match[2] {
+			case "FAILED":
+				status = TestFailed
+			case "ignored":
+				status = TestSkipped
+			}
+			outcomes = append(outcomes, TestOutcome{Name: match[1], Framework: TestFrameworkCargo, Status: status})
+			continue
+		}
+		if match := libtestOutput.FindStringSubmatch(line); match != nil {
+			section = match[1]
+			continue
+		}
+		if line == "failures:" || line == "successes:" || strings.HasPrefix(line, "test result: ") {
+			section = ""
+			continue
+		}
+
+		if match := rustPanic.FindStringSubmatch(line); match != nil {
+			var message []string
+			for i+1 < len(lines) && lines[i+1] != "" && !strings.HasPrefix(lines[i+1], "note: ") && !strings.HasPrefix(lines[i+1], "stack backtrace:") {
+				i++
+				message = append(message, lines[i])
+			}
+			lineNumber, _ := strconv.Atoi(match[3])
+			column, _ := strconv.Atoi(match[4])
+			name := section
+			if name == "" {
+				name = match[1]
+			}
+			failures[name] = append(failures[name], TestFailure{Message: strings.Join(message, "\n"), Location: paths.location(match[2], lineNumber, column)})
+			continue
+		}
+		if match := rustOldPanic.FindStringSubmatch(line); match != nil {
+			lineNumber, _ := strconv.Atoi(match[4])
+			column, _ := strconv.Atoi(match[5])
+			name := section
+			if name == "" {
+				name = match[1]
+			}
+			failures[name] = append(failures[name], TestFailure{Message: match[2], Location: paths.location(match[3], lineNumber, column)})
+			continue
+		}
+
+		// Build errors are the ones that point at code
+		if match := rustError.FindStringSubmatch(line); match != nil && section == "" {
+			for j := i + 1; j < len(lines) && j <= i+3; j++ {
+				if location := rustErrorLocation.FindStringSubmatch(lines[j]); location != nil {
+					lineNumber, _ := strconv.Atoi(location[2])
+					column, _ := strconv.Atoi(location[3])
+					problems = append(problems, TestFailure{Message: match[2], Location: paths.location(location[1], lineNumber, column)})
+					break
+				}
+			}
+		}
+	}
+
+	for i := range outcomes {
+		if outcomes[i].Status == TestFailed {
+			outcomes[i].Failures = failures[outcomes[i].Name]
+		}
+	}
+	return outcomes, problems
+}
+
+var (
+	// The short test summary of pytest -rA, as in
+	// "FAILED tests/test_parse.py::test_empty - assert 4 == 5"
+	pytestSummary = regexp.MustCompile(`^(PASSED|FAILED|ERROR|SKIPPED|XFAIL|XPASS) (\S+::\S+)(?: - (.*))?$`)
+	// The header of the traceback of a failed test, as in
+	// "______ TestParser.test_empty ______"
+	pytestSection = regexp.MustCompile(`^_{3,} (.+?) _{3,}$`)
+	// Traceback entries, as in "tests/test_parse.py:12: in test_empty"
+	pytestTraceback = regexp.MustCompile(`^(\S+\.py):(\d+): `)
+)
+
+// parsePytestOutput reads the output of pytest run with -rA, using the
+// tracebacks of --tb=short or --tb=long for the failures
+func parsePytestOutput(output string, paths outputPaths) []TestOutcome {
+	type traceback struct {
+		location *Location
+		message  []string
+	}
+	tracebacks := make(map[string]*traceback)
+	var outcomes []TestOutcome
+
+	section := ""
+	for _, line := range strings.Split(output, "\n") {
+		if match := pytestSection.FindStringSubmatch(line); match != nil {
+			section = match[1]
+			if _, ok := tracebacks[section]; !ok {
+				tracebacks[section] = &traceback{}
+			}
+			continue
+		}
+		if strings.HasPrefix(line, "=====") {
+			section = ""
+			continue
+		}
+		if match := pytestSummary.FindStringSubmatch(line); match != nil {
+			outcome := TestOutcome{Name: match[2], Framework: TestFrameworkPytest}
+			switch match[1] {
+			case "PASSED", "XPASS":
+				outcome.Status = TestPassed
+			case "SKIPPED", "XFAIL":
+				outcome.Status = TestSkipped
+			default:
+				outcome.Status = TestFailed
+				if match[3] != "" {
+					outcome.Failures = []TestFailure{{Message: match[3]}}
+				}
+			}
+			outcomes = append(outcomes, outcome)
+			continue
+		}
+		if section == "" {
+			continue
+		}
+
+		tb := tracebacks[section]
+		if match := pytestTraceback.FindStringSubmatch(line); match != nil {
+			lineNumber, _ := strconv.Atoi(match[2])
+			tb.location = paths.location(match[1], lineNumber, 0)
+		} else if message, ok := strings.CutPrefix(line, "E "); ok {
+			tb.message = append(tb.message, strings.TrimSpace(message))
+		}
+	}
+
+	// Sections are headed by the test name without its file, and with
+	// a dot between a class and its methods
+	for i, outcome := range outcomes {
+		if outcome.Status != TestFailed {
+			continue
+		}
+		for name, tb := range tracebacks {
+			if len(tb.message) == 0 || !strings.HasSuffix(outcome.Name, "::"+strings.ReplaceAll(name, ".", "::")) {
+				continue
+			}
+			outcomes[i].Failures = []TestFailure{{Message: strings.Join(tb.message, "\n"), Location: tb.location}}
+		}
+	}
+	return outcomes
+}
+
+// jestReport is the report of jest --json
+type jestReport struct {
+	TestResults []struct {
+		Name             string `json:"name"`
+		Message          string `json:"message"`
+		AssertionResults []struct {
+			FullName        string   `json:"fullName"`
+			Status          string   `json:"status"`
+			FailureMessages []string `json:"failureMessages"`
+			Location        *struct {
+				Line   int `json:"line"`
+				Column int `json:"column"`
+			} `json:"location"`
+		} `json:"assertionResults"`
+	} `json:"testResults"`
+}
+
+// Stack frames in Jest failures, as in "at Object.<anonymous> (/src/parse.test.js:12:15)"
+var jestStackFrame = regexp.MustCompile(`^\s*at (?:.*\()?(/[^():]+):(\d+):(\d+)\)?$`)
+
+// parseJestOutput reads the report of jest --json
+func parseJestOutput(stdout string, paths outputPaths) ([]TestOutcome, []TestFailure) {
+	var report jestReport
+	found := false
+	for _, line := range strings.Split(stdout, "\n") {
+		if strings.HasPrefix(line, "{") && json.Unmarshal([]byte(line), &report) == nil {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, nil
+	}
+
+	var outcomes []TestOutcome
+	var problems []TestFailure
+	for _, suite := range report.TestResults {
+		if len(suite.AssertionResults) == 0 && suite.Message != "" {
+			problems = append(problems, jestFailure(suite.Message, suite.Name, paths))
+			continue
+		}
+		for _, assertion := range suite.AssertionResults {
+			outcome := TestOutcome{Name: assertion.FullName, Framework: TestFrameworkJest}
+			switch assertion.Status {
+			case "passed":
+				outcome.Status = TestPassed
+			case "failed":
+				outcome.Status = TestFailed
+			default:
+				outcome.Status = TestSkipped
+			}
+			if assertion.Location != nil {
+				// Jest columns start at 0
+				outcome.Location = paths.location(suite.Name, assertion.Location.Line, assertion.Location.Column+1)
+			}
+			for _, message := range assertion.FailureMessages {
+				outcome.Failures = append(outcome.Failures, jestFailure(message, suite.Name, paths))
+			}
+			outcomes = append(outcomes, outcome)
+		}
+	}
+	return outcomes, problems
+}
+
+// jestFailure splits a failure message from its stack, locating it at the
+// first frame in the test file, or else in the project
+func jestFailure(message, testFile string, paths outputPaths) TestFailure {
+	message = stripANSI(message)
+	var text []string
+	var location, fallback *Location
+	for _, line := range strings.Split(message, "\n") {
+		match := jestStackFrame.FindStringSubmatch(line)
+		if match == nil {
+			if location == nil && fallback == nil {
+				text = append(text, line)
+			}
+			continue
+		}
+		lineNumber, _ := strconv.Atoi(match[2])
+		column, _ := strconv.Atoi(match[3])
+		if match[1] == testFile && location == nil {
+			location = paths.location(match[1], lineNumber, column)
+		} else if fallback == nil && !strings.Contains(match[1], "/node_modules/") {
+			fallback = paths.location(match[1], lineNumber, column)
+		}
+	}
+	if location == nil {
+		location = fallback
+	}
+	return TestFailure{Message: strings.TrimSpace(strings.Join(text, "\n")), Location: location}
+}
@@ -0,0 +1,176 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// at is the location of a line in test output, shown relative to the
+// workspace
+func at(file string, line, column int) *Location {
+	position := Position{Line: line, Column: column}
+	return &Location{File: file, Range: Range{Start: position, End: position}}
+}
+
+func TestParseGoTestJSON(t *testing.T) {
+	utilities.SetWorkspacePaths("/ws", true, nil)
+	defer utilities.SetWorkspacePaths("", false, nil)
+
+	output := strings.Join([]string{
+		`{"Action":"run","Package":"example.com/rt","Test":"TestAdd"}`,
+		`{"Action":"output","Package":"example.com/rt","Test":"TestAdd","Output":"--- PASS: TestAdd (0.00s)\n"}`,
+		`{"Action":"pass","Package":"example.com/rt","Test":"TestAdd"}`,
+		`{"Action":"run","Package":"example.com/rt","Test":"TestTable/2"}`,
+		`{"Action":"output","Package":"example.com/rt","Test":"TestTable/2","Output":"    more_test.go:17: got 2\n"}`,
+		`{"Action":"output","Package":"example.com/rt","Test":"TestTable/2","Output":"        want 3\n"}`,
+		`{"Action":"output","Package":"example.com/rt","Test":"TestTable/2","Output":"--- FAIL: TestTable/2 (0.00s)\n"}`,
+		`{"Action":"fail","Package":"example.com/rt","Test":"TestTable/2"}`,
+		`{"Action":"skip","Package":"example.com/rt","Test":"TestLater"}`,
+		`{"Action":"run","Package":"example.com/rt","Test":"TestPanics"}`,
+		`{"Action":"output","Package":"example.com/rt","Test":"TestPanics","Output":"panic: assignment to entry in nil map [recovered, repanicked]\n"}`,
+		`{"Action":"output","Package":"example.com/rt","Test":"TestPanics","Output":"\t/usr/local/go/src/testing/testing.go:1872 +0x37f\n"}`,
+		`{"Action":"output","Package":"example.com/rt","Test":"TestPanics","Output":"\t/ws/rt/more_test.go:10 +0x2e\n"}`,
+		`{"Action":"fail","Package":"example.com/rt","Test":"TestPanics"}`,
+		`{"Action":"output","Package":"example.com/rt","Output":"FAIL\texample.com/rt\t0.003s\n"}`,
+	}, "\n")
+
+	outcomes, problems := parseGoTestOutput(output, outputPaths{"/ws/rt", "/ws"})
+	assert.Empty(t, problems)
+	require.Len(t, outcomes, 4)
+	assert.Equal(t, TestOutcome{Name: "TestAdd", Framework: TestFrameworkGo, Status: TestPassed}, outcomes[0])
+	assert.Equal(t, TestOutcome{Name: "TestTable/2", Framework: TestFrameworkGo, Status: TestFailed, Failures: []TestFailure{
+		{Message: "got 2\nwant 3", Location: at("rt/more_test.go", 17, 1)},
+	}}, outcomes[1])
+	assert.Equal(t, TestSkipped, outcomes[2].Status)
+	assert.Equal(t, []TestFailure{
+		{Message: "panic: assignment to entry in nil map", Location: at("rt/more_test.go", 10, 1)},
+	}, outcomes[3].Failures, "panics are located at the first frame in the project")
+}
+
+func TestParseGoTestBuildFailure(t *testing.T) {
+	utilities.SetWorkspacePaths("/ws", true, nil)
+	defer utilities.SetWorkspacePaths("", false, nil)
+
+	output := strings.Join([]string{
+		`{"ImportPath":"example.com/rt [example.com/rt.test]","Action":"build-output","Output":"# example.com/rt [example.com/rt.test]\n"}`,
+		`{"ImportPath":"example.com/rt [example.com/rt.test]","Action":"build-output","Output":"./calc.go:4:25: undefined: undefinedThing\n"}`,
+		`{"ImportPath":"example.com/rt [example.com/rt.test]","Action":"build-fail"}`,
+		`{"Action":"fail","Package":"example.com/rt","FailedBuild":"example.com/rt [example.com/rt.test]"}`,
+	}, "\n")
+
+	outcomes, problems := parseGoTestOutput(output, outputPaths{"/ws"})
+	assert.Empty(t, outcomes)
+	assert.Equal(t, []TestFailure{{Message: "undefined: undefinedThing", Location: at("calc.go", 4, 25)}}, problems)
+}
+
+func TestParseGoTestText(t *testing.T) {
+	utilities.SetWorkspacePaths("/ws", true, nil)
+	defer utilities.SetWorkspacePaths("", false, nil)
+
+	output := "--- FAIL: TestBroken (0.00s)\n" +
+		"    calc_test.go:12: Add(2, 2) = 4, want 5\n" +
+		"--- PASS: TestAdd (0.00s)\n" +
+		"FAIL\n" +
+		"FAIL\texample.com/rt\t0.003s\n"
+
+	outcomes, _ := parseGoTestOutput(output, outputPaths{"/ws"})
+	require.Len(t, outcomes, 2)
+	assert.Equal(t, "TestBroken", outcomes[0].Name)
+	assert.Equal(t, []TestFailure{{Message: "Add(2, 2) = 4, want 5", Location: at("calc_test.go", 12, 1)}}, outcomes[0].Failures)
+	assert.Equal(t, TestPassed, outcomes[1].Status)
+}
+
+func TestParseLibtestOutput(t *testing.T) {
+	utilities.SetWorkspacePaths("/ws", true, nil)
+	defer utilities.SetWorkspacePaths("", false, nil)
+
+	output := "running 3 tests\n" +
+		"test tests::adds ... ok\n" +
+		"test tests::later ... ignored\n" +
+		"test tests::subtracts ... FAILED\n" +
+		"\n" +
+		"failures:\n" +
+		"\n" +
+		"---- tests::subtracts stdout ----\n" +
+		"\n" +
+		"thread 'tests::subtracts' panicked at src/lib.rs:20:9:\n" +
+		"assertion `left == right` failed\n" +
+		"  left: 1\n" +
+		" right: 2\n" +
+		"note: run with `RUST_BACKTRACE=1` environment variable to display a backtrace\n" +
+		"\n" +
+		"failures:\n" +
+		"    tests::subtracts\n" +
+		"\n" +
+		"test result: FAILED. 1 passed; 1 failed; 1 ignored; 0 measured; 0 filtered out\n"
+
+	outcomes, problems := parseLibtestOutput(output, outputPaths{"/ws"})
+	assert.Empty(t, problems)
+	require.Len(t, outcomes, 3)
+	assert.Equal(t, []string{TestPassed, TestSkipped, TestFailed}, []string{outcomes[0].Status, outcomes[1].Status, outcomes[2].Status})
+	assert.Equal(t, []TestFailure{{
+		Message:  "assertion `left == right` failed\n  left: 1\n right: 2",
+		Location: at("src/lib.rs", 20, 9),
+	}}, outcomes[2].Failures)
+
+	// Build errors
+	output = "error[E0425]: cannot find value `y` in this scope\n" +
+		" --> src/lib.rs:3:5\n" +
+		"  |\n" +
+		"3 |     y\n" +
+		"error: could not compile `calc` (lib test) due to 1 previous error\n"
+	outcomes, problems = parseLibtestOutput(output, outputPaths{"/ws"})
+	assert.Empty(t, outcomes)
+	assert.Equal(t, []TestFailure{{Message: "cannot find value `y` in this scope", Location: at("src/lib.rs", 3, 5)}}, problems)
+}
+
+func TestParsePytestOutput(t *testing.T) {
+	utilities.SetWorkspacePaths("/ws", true, nil)
+	defer utilities.SetWorkspacePaths("", false, nil)
+
+	output := "=================================== FAILURES ===================================\n" +
+		"___________________________ TestParser.test_empty ____________________________\n" +
+		"tests/test_parse.py:12: in test_empty\n" +
+		"    assert parse('') == []\n" +
+		"E   AssertionError: assert None == []\n" +
+		"E    +  where None = parse('')\n" +
+		"=========================== short test summary info ============================\n" +
+		"PASSED tests/test_parse.py::test_module\n" +
+		"SKIPPED [1] tests/test_parse.py:20: later\n" +
+		"FAILED tests/test_parse.py::TestParser::test_empty - AssertionError: assert None == []\n" +
+		"========================= 1 failed, 1 passed, 1 skipped in 0.02s ==================\n"
+
+	outcomes := parsePytestOutput(output, outputPaths{"/ws"})
+	require.Len(t, outcomes, 2)
+	assert.Equal(t, TestOutcome{Name: "tests/test_parse.py::test_module", Framework: TestFrameworkPytest, Status: TestPassed}, outcomes[0])
+	assert.Equal(t, TestFailed, outcomes[1].Status)
+	assert.Equal(t, []TestFailure{{
+		Message:  "AssertionError: assert None == []\n+  where None = parse('')",
+		Location: at("tests/test_parse.py", 12, 1),
+	}}, outcomes[1].Failures)
+}
+
+func TestParseJestOutput(t *testing.T) {
+	utilities.SetWorkspacePaths("/ws", true, nil)
+	defer utilities.SetWorkspacePaths("", false, nil)
+
+	stdout := `{"numFailedTests":1,"testResults":[{"name":"/ws/src/parse.test.js","message":"","assertionResults":[` +
+		`{"fullName":"parse handles empty input","status":"passed","failureMessages":[],"location":{"line":3,"column":2}},` +
+		`{"fullName":"parse rejects junk","status":"failed","failureMessages":["Error: \u001b[2mexpect(\u001b[22mreceived\u001b[2m).\u001b[22mtoThrow()\n\nReceived function did not throw\n    at Object.toThrow (/ws/src/parse.test.js:9:28)\n    at Promise.then.completed (/ws/node_modules/jest-circus/build/utils.js:298:28)"],"location":{"line":8,"column":2}},` +
+		`{"fullName":"parse later","status":"pending","failureMessages":[]}]},` +
+		`{"name":"/ws/src/broken.test.js","message":"SyntaxError: Unexpected token (3:4)\n    at Parser.raise (/ws/node_modules/@babel/parser/lib/index.js:1:2)\n    at Object.<anonymous> (/ws/src/broken.test.js:3:4)","assertionResults":[]}]}`
+
+	outcomes, problems := parseJestOutput("Determining test suites to run...\n"+stdout+"\n", outputPaths{"/ws"})
+	require.Len(t, outcomes, 3)
+	assert.Equal(t, TestOutcome{Name: "parse handles empty input", Framework: TestFrameworkJest, Status: TestPassed, Location: at("src/parse.test.js", 3, 3)}, outcomes[0])
+	assert.Equal(t, []TestFailure{{
+		Message:  "Error: expect(received).toThrow()\n\nReceived function did not throw",
+		Location: at("src/parse.test.js", 9, 28),
+	}}, outcomes[1].Failures)
+	assert.Equal(t, TestSkipped, outcomes[2].Status)
+	assert.Equal(t, []TestFailure{{Message: "SyntaxError: Unexpected token (3:4)", Location: at("src/broken.test.js", 3, 4)}}, problems)
+}
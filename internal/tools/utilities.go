@@ -10,10 +10,14 @@ import (
 
 	"github.com/isaacphi/mcp-language-server/internal/lsp"
 	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
 )
 
 func ExtractTextFromLocation(loc protocol.Location) (string, error) {
 	path := strings.TrimPrefix(string(loc.URI), "file://")
+	if err := utilities.CheckReadAccess(path); err != nil {
+		return "", err
+	}
 
 	content, err := os.ReadFile(path)
 	if err != nil {
@@ -100,6 +104,18 @@ func addLineNumbers(text string, startLine int) string {
 	return result.String()
 }
 
+// contextLinesSetting returns how many lines to show around each location
+// in a snippet, from the LSP_CONTEXT_LINES environment variable
+func contextLinesSetting() int {
+	contextLines := 5
+	if envLines := os.Getenv("LSP_CONTEXT_LINES"); envLines != "" {
+		if val, err := strconv.Atoi(envLines); err == nil && val >= 0 {
+			contextLines = val
+		}
+	}
+	return contextLines
+}
+
 // LineRange represents a continuous range of lines to display
 type LineRange struct {
 	Start int
@@ -199,3 +215,23 @@ func QuerySymbol(ctx context.Context, client *lsp.Client, symbolName string) (st
 
 	return symbolName, results, err
 }
+
+// readablePath resolves a file path given to a tool, which may be relative to
+// the workspace, and checks that the sandbox allows reading it
+func readablePath(filePath string) (string, error) {
+	filePath = utilities.ResolvePath(filePath)
+	if err := utilities.CheckReadAccess(filePath); err != nil {
+		return "", err
+	}
+	return filePath, nil
+}
+
+// writablePath resolves a file path given to a tool, which may be relative to
+// the workspace, and checks that the sandbox allows modifying it
+func writablePath(filePath string) (string, error) {
+	filePath = utilities.ResolvePath(filePath)
+	if err := utilities.CheckWriteAccess(filePath); err != nil {
+		return "", err
+	}
+	return filePath, nil
+}
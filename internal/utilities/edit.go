@@ -7,7 +7,6 @@ import (
 	"sort"
 	"strings"
 
-	"github.com/davecgh/go-spew/spew"
 	"github.com/isaacphi/mcp-language-server/internal/protocol"
 )
 
@@ -20,16 +19,9 @@ var (
 	osRename    = os.Rename
 )
 
-// ApplyTextEdits applies a sequence of text edits to a file specified by URI
-func ApplyTextEdits(uri protocol.DocumentUri, edits []protocol.TextEdit) error {
-	path := strings.TrimPrefix(string(uri), "file://")
-
-	// Read the file content
-	content, err := osReadFile(path)
-	if err != nil {
-		return fmt.Errorf("failed to read file: %w", err)
-	}
-
+// applyTextEditsToContent applies a sequence of text edits to file content
+// and returns the new content
+func applyTextEditsToContent(content []byte, edits []protocol.TextEdit) ([]byte, error) {
 	// Detect line ending style
 	var lineEnding string
 	if bytes.Contains(content, []byte("\r\n")) {
@@ -48,7 +40,7 @@ func ApplyTextEdits(uri protocol.DocumentUri, edits []protocol.TextEdit) error {
 	for i, edit1 := range edits {
 		for j := i + 1; j < len(edits); j++ {
 			if RangesOverlap(edit1.Range, edits[j].Range) {
-				return fmt.Errorf("overlapping edits detected between edit %d and %d", i, j)
+				return nil, fmt.Errorf("overlapping edits detected between edit %d and %d", i, j)
 			}
 		}
 	}
@@ -67,7 +59,7 @@ func ApplyTextEdits(uri protocol.DocumentUri, edits []protocol.TextEdit) error {
 	for _, edit := range sortedEdits {
 		newLines, err := ApplyTextEdit(lines, edit, lineEnding)
 		if err != nil {
-			return fmt.Errorf("failed to apply edit: %w", err)
+			return nil, fmt.Errorf("failed to apply edit: %w", err)
 		}
 		lines = newLines
 	}
@@ -86,11 +78,7 @@ func ApplyTextEdits(uri protocol.DocumentUri, edits []protocol.TextEdit) error {
 		newContent.WriteString(lineEnding)
 	}
 
-	if err := osWriteFile(path, []byte(newContent.String()), 0644); err != nil {
-		return fmt.Errorf("failed to write file: %w", err)
-	}
-
-	return nil
+	return []byte(newContent.String()), nil
 }
 
 // ApplyTextEdit applies a single text edit to a set of lines
@@ -170,87 +158,6 @@ func ApplyTextEdit(lines []string, edit protocol.TextEdit, lineEnding string) ([
 	return result, nil
 }
 
-// ApplyDocumentChange applies a DocumentChange (create/rename/delete operations)
-func ApplyDocumentChange(change protocol.DocumentChange) error {
-	if change.CreateFile != nil {
-		path := strings.TrimPrefix(string(change.CreateFile.URI), "file://")
-		if change.CreateFile.Options != nil {
-			if change.CreateFile.Options.Overwrite {
-				// Proceed with overwrite
-			} else if change.CreateFile.Options.IgnoreIfExists {
-				if _, err := osStat(path); err == nil {
-					return nil // File exists and we're ignoring it
-				}
-			}
-		}
-		if err := osWriteFile(path, []byte(""), 0644); err != nil {
-			return fmt.Errorf("failed to create file: %w", err)
-		}
-	}
-
-	if change.DeleteFile != nil {
-		path := strings.TrimPrefix(string(change.DeleteFile.URI), "file://")
-		if change.DeleteFile.Options != nil && change.DeleteFile.Options.Recursive {
-			if err := osRemoveAll(path); err != nil {
-				return fmt.Errorf("failed to delete directory recursively: %w", err)
-			}
-		} else {
-			if err := osRemove(path); err != nil {
-				return fmt.Errorf("failed to delete file: %w", err)
-			}
-		}
-	}
-
-	if change.RenameFile != nil {
-		oldPath := strings.TrimPrefix(string(change.RenameFile.OldURI), "file://")
-		newPath := strings.TrimPrefix(string(change.RenameFile.NewURI), "file://")
-		if change.RenameFile.Options != nil {
-			if !change.RenameFile.Options.Overwrite {
-				if _, err := osStat(newPath); err == nil {
-					return fmt.Errorf("target file already exists and overwrite is not allowed: %s", newPath)
-				}
-			}
-		}
-		if err := osRename(oldPath, newPath); err != nil {
-			return fmt.Errorf("failed to rename file: %w", err)
-		}
-	}
-
-	if change.TextDocumentEdit != nil {
-		textEdits := make([]protocol.TextEdit, len(change.TextDocumentEdit.Edits))
-		for i, edit := range change.TextDocumentEdit.Edits {
-			var err error
-			textEdits[i], err = edit.AsTextEdit()
-			if err != nil {
-				return fmt.Errorf("invalid edit type: %w", err)
-			}
-		}
-		return ApplyTextEdits(change.TextDocumentEdit.TextDocument.URI, textEdits)
-	}
-
-	return nil
-}
-
-// ApplyWorkspaceEdit applies the given WorkspaceEdit to the filesystem
-func ApplyWorkspaceEdit(edit protocol.WorkspaceEdit) error {
-	// Handle Changes field
-	for uri, textEdits := range edit.Changes {
-		if err := ApplyTextEdits(uri, textEdits); err != nil {
-			return fmt.Errorf("failed to apply text edits: %w", err)
-		}
-	}
-
-	// Handle DocumentChanges field
-	for _, change := range edit.DocumentChanges {
-		coreLogger.Warn("Document change: %v", spew.Sdump(change))
-		if err := ApplyDocumentChange(change); err != nil {
-			return fmt.Errorf("failed to apply document change: %w", err)
-		}
-	}
-
-	return nil
-}
-
 // RangesOverlap checks if two ranges overlap in position
 func RangesOverlap(r1, r2 protocol.Range) bool {
 	if r1.Start.Line > r2.End.Line || r2.Start.Line > r1.End.Line {
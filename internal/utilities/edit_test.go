@@ -93,6 +93,18 @@ func setupMockFileSystem(_ *testing.T, mfs *mockFileSystem) func() {
 			delete(mfs.files, oldpath)
 			return nil
 		}
+		// Directories move every file under them
+		if info, ok := mfs.fileStats[oldpath]; ok && info.IsDir() {
+			for k, content := range mfs.files {
+				if rest, ok := strings.CutPrefix(k, oldpath+"/"); ok {
+					mfs.files[newpath+"/"+rest] = content
+					delete(mfs.files, k)
+				}
+			}
+			mfs.fileStats[newpath] = info
+			delete(mfs.fileStats, oldpath)
+			return nil
+		}
 		return os.ErrNotExist
 	}
 
@@ -631,7 +643,7 @@ func TestApplyTextEdits(t *testing.T) {
 					"/test/file.txt": []byte("This is a test line"),
 				}
 				mfs.errors = map[string]error{
-					"/test/file.txt_write": errors.New("write error"),
+					"/test/.file.txt.mcp-edit.tmp_write": errors.New("write error"),
 				}
 			},
 		},
@@ -644,7 +656,9 @@ func TestApplyTextEdits(t *testing.T) {
 			cleanup := setupMockFileSystem(t, mfs)
 			defer cleanup()
 
-			err := ApplyTextEdits(tt.uri, tt.edits)
+			err := ApplyWorkspaceEdit(protocol.WorkspaceEdit{
+				Changes: map[protocol.DocumentUri][]protocol.TextEdit{tt.uri: tt.edits},
+			}, "")
 			if tt.expectErr {
 				if err == nil {
 					t.Errorf("Expected error but got none")
@@ -780,6 +794,9 @@ func TestApplyDocumentChange(t *testing.T) {
 					"/test/dir/subdir/file3.txt": []byte("content 3"),
 					"/test/other.txt":            []byte("other content"),
 				}
+				mfs.fileStats = map[string]os.FileInfo{
+					"/test/dir": mockFileInfo{name: "dir", isDir: true},
+				}
 			},
 			checkState: func(t *testing.T, mfs *mockFileSystem) {
 				if _, ok := mfs.files["/test/dir/file1.txt"]; ok {
@@ -896,7 +913,9 @@ func TestApplyDocumentChange(t *testing.T) {
 			cleanup := setupMockFileSystem(t, mfs)
 			defer cleanup()
 
-			err := ApplyDocumentChange(tt.change)
+			err := ApplyWorkspaceEdit(protocol.WorkspaceEdit{
+				DocumentChanges: []protocol.DocumentChange{tt.change},
+			}, "")
 			if tt.expectErr {
 				if err == nil {
 					t.Errorf("Expected error but got none")
@@ -1060,8 +1079,9 @@ func TestApplyWorkspaceEdit(t *testing.T) {
 				// Missing file causes an error
 			},
 			checkState: func(t *testing.T, mfs *mockFileSystem) {
-				// The first edit might or might not be applied depending on implementation
-				// so we don't check it
+				if string(mfs.files["/test/file1.txt"]) != "This is a test line" {
+					t.Errorf("File1 was modified despite the failed edit, content: %s", string(mfs.files["/test/file1.txt"]))
+				}
 			},
 		},
 		{
@@ -1087,8 +1107,192 @@ func TestApplyWorkspaceEdit(t *testing.T) {
 				// Missing file causes an error
 			},
 			checkState: func(t *testing.T, mfs *mockFileSystem) {
-				// The first operation might or might not be applied depending on implementation
-				// so we don't check it
+				if _, ok := mfs.files["/test/newfile.txt"]; ok {
+					t.Errorf("New file was created despite the failed edit")
+				}
+			},
+		},
+		{
+			name: "Invalid edit is rejected before writing",
+			edit: protocol.WorkspaceEdit{
+				Changes: map[protocol.DocumentUri][]protocol.TextEdit{
+					"file:///test/file1.txt": {
+						{
+							Range: protocol.Range{
+								Start: protocol.Position{Line: 0, Character: 5},
+								End:   protocol.Position{Line: 0, Character: 9},
+							},
+							NewText: "was",
+						},
+					},
+					"file:///test/file2.txt": {
+						{
+							Range: protocol.Range{
+								Start: protocol.Position{Line: 0, Character: 0},
+								End:   protocol.Position{Line: 1, Character: 2},
+							},
+							NewText: "first",
+						},
+						{
+							Range: protocol.Range{
+								Start: protocol.Position{Line: 1, Character: 0},
+								End:   protocol.Position{Line: 1, Character: 6},
+							},
+							NewText: "second",
+						},
+					},
+				},
+			},
+			expectErr: true,
+			setupMocks: func(mfs *mockFileSystem) {
+				mfs.files = map[string][]byte{
+					"/test/file1.txt": []byte("This is a test line"),
+					"/test/file2.txt": []byte("Line 1\nLine 2\nLine 3"),
+				}
+			},
+			checkState: func(t *testing.T, mfs *mockFileSystem) {
+				if string(mfs.files["/test/file1.txt"]) != "This is a test line" {
+					t.Errorf("File1 was modified despite the invalid edit, content: %s", string(mfs.files["/test/file1.txt"]))
+				}
+				if len(mfs.files) != 2 {
+					t.Errorf("Expected no staged files to remain, files: %v", len(mfs.files))
+				}
+			},
+		},
+		{
+			name: "Staging failure leaves files untouched",
+			edit: protocol.WorkspaceEdit{
+				Changes: map[protocol.DocumentUri][]protocol.TextEdit{
+					"file:///test/file1.txt": {
+						{
+							Range: protocol.Range{
+								Start: protocol.Position{Line: 0, Character: 5},
+								End:   protocol.Position{Line: 0, Character: 9},
+							},
+							NewText: "was",
+						},
+					},
+					"file:///test/file2.txt": {
+						{
+							Range: protocol.Range{
+								Start: protocol.Position{Line: 1, Character: 0},
+								End:   protocol.Position{Line: 1, Character: 6},
+							},
+							NewText: "Modified",
+						},
+					},
+				},
+			},
+			expectErr: true,
+			setupMocks: func(mfs *mockFileSystem) {
+				mfs.files = map[string][]byte{
+					"/test/file1.txt": []byte("This is a test line"),
+					"/test/file2.txt": []byte("Line 1\nLine 2\nLine 3"),
+				}
+				mfs.errors = map[string]error{
+					"/test/.file2.txt.mcp-edit.tmp_write": errors.New("disk full"),
+				}
+			},
+			checkState: func(t *testing.T, mfs *mockFileSystem) {
+				if string(mfs.files["/test/file1.txt"]) != "This is a test line" {
+					t.Errorf("File1 was modified despite the failed edit, content: %s", string(mfs.files["/test/file1.txt"]))
+				}
+				if _, ok := mfs.files["/test/.file1.txt.mcp-edit.tmp"]; ok {
+					t.Errorf("Staged file was not cleaned up")
+				}
+			},
+		},
+		{
+			name: "Failure while renaming into place rolls back",
+			edit: protocol.WorkspaceEdit{
+				Changes: map[protocol.DocumentUri][]protocol.TextEdit{
+					"file:///test/file1.txt": {
+						{
+							Range: protocol.Range{
+								Start: protocol.Position{Line: 0, Character: 5},
+								End:   protocol.Position{Line: 0, Character: 9},
+							},
+							NewText: "was",
+						},
+					},
+					"file:///test/file2.txt": {
+						{
+							Range: protocol.Range{
+								Start: protocol.Position{Line: 1, Character: 0},
+								End:   protocol.Position{Line: 1, Character: 6},
+							},
+							NewText: "Modified",
+						},
+					},
+				},
+			},
+			expectErr: true,
+			setupMocks: func(mfs *mockFileSystem) {
+				mfs.files = map[string][]byte{
+					"/test/file1.txt": []byte("This is a test line"),
+					"/test/file2.txt": []byte("Line 1\nLine 2\nLine 3"),
+				}
+				mfs.errors = map[string]error{
+					"/test/.file2.txt.mcp-edit.tmp_rename": errors.New("rename failed"),
+				}
+			},
+			checkState: func(t *testing.T, mfs *mockFileSystem) {
+				if string(mfs.files["/test/file1.txt"]) != "This is a test line" {
+					t.Errorf("File1 was not rolled back, content: %s", string(mfs.files["/test/file1.txt"]))
+				}
+				if string(mfs.files["/test/file2.txt"]) != "Line 1\nLine 2\nLine 3" {
+					t.Errorf("File2 was modified, content: %s", string(mfs.files["/test/file2.txt"]))
+				}
+				if len(mfs.files) != 2 {
+					t.Errorf("Expected no staged files to remain, got %d files", len(mfs.files))
+				}
+			},
+		},
+		{
+			name: "Edit a renamed file",
+			edit: protocol.WorkspaceEdit{
+				DocumentChanges: []protocol.DocumentChange{
+					{
+						RenameFile: &protocol.RenameFile{
+							OldURI: "file:///test/oldname.txt",
+							NewURI: "file:///test/newname.txt",
+						},
+					},
+					{
+						TextDocumentEdit: &protocol.TextDocumentEdit{
+							TextDocument: protocol.OptionalVersionedTextDocumentIdentifier{
+								TextDocumentIdentifier: protocol.TextDocumentIdentifier{
+									URI: "file:///test/newname.txt",
+								},
+							},
+							Edits: []protocol.Or_TextDocumentEdit_edits_Elem{
+								{
+									Value: protocol.TextEdit{
+										Range: protocol.Range{
+											Start: protocol.Position{Line: 0, Character: 0},
+											End:   protocol.Position{Line: 0, Character: 4},
+										},
+										NewText: "renamed",
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			expectErr: false,
+			setupMocks: func(mfs *mockFileSystem) {
+				mfs.files = map[string][]byte{
+					"/test/oldname.txt": []byte("file content"),
+				}
+			},
+			checkState: func(t *testing.T, mfs *mockFileSystem) {
+				if _, ok := mfs.files["/test/oldname.txt"]; ok {
+					t.Errorf("Old file still exists")
+				}
+				if content := string(mfs.files["/test/newname.txt"]); content != "renamed content" {
+					t.Errorf("Renamed file has incorrect content: %s", content)
+				}
 			},
 		},
 	}
@@ -1100,7 +1304,7 @@ func TestApplyWorkspaceEdit(t *testing.T) {
 			cleanup := setupMockFileSystem(t, mfs)
 			defer cleanup()
 
-			err := ApplyWorkspaceEdit(tt.edit)
+			err := ApplyWorkspaceEdit(tt.edit, tt.name)
 			if tt.expectErr {
 				if err == nil {
 					t.Errorf("Expected error but got none")
@@ -1109,8 +1313,8 @@ func TestApplyWorkspaceEdit(t *testing.T) {
 				if err != nil {
 					t.Errorf("Unexpected error: %v", err)
 				}
-				tt.checkState(t, mfs)
 			}
+			tt.checkState(t, mfs)
 		})
 	}
 }
@@ -0,0 +1,290 @@
+package utilities
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxEditHistory is the number of edits kept in the history
+const maxEditHistory = 100
+
+// FileRevision is the content of a file before and after an edit
+type FileRevision struct {
+	Path         string `json:"path"`
+	Before       []byte `json:"before"`
+	BeforeExists bool   `json:"beforeExists"`
+	After        []byte `json:"after"`
+	AfterExists  bool   `json:"afterExists"`
+}
+
+// EditRecord is a workspace edit in the history
+type EditRecord struct {
+	ID          int            `json:"id"`
+	Time        time.Time      `json:"time"`
+	Description string         `json:"description"`
+	Files       []FileRevision `json:"files"`
+	// Directories describes the directory renames and deletes of the edit.
+	// Their content is not recorded, so such edits cannot be undone.
+	Directories []string `json:"directories,omitempty"`
+	// Undone edits can be redone until a new edit is applied
+	Undone bool `json:"undone"`
+}
+
+// editHistory is the journal of edits applied through ApplyWorkspaceEdit. It
+// is guarded by workspaceEditMu.
+var editHistory struct {
+	records []*EditRecord
+	nextID  int
+	// dir persists the history across restarts when set
+	dir string
+}
+
+// OpenEditHistory keeps the edit history in dir, loading the edits already
+// recorded there
+func OpenEditHistory(dir string) error {
+	workspaceEditMu.Lock()
+	defer workspaceEditMu.Unlock()
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create edit history directory: %w", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("failed to read edit history: %w", err)
+	}
+
+	var records []*EditRecord
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			coreLogger.Warn("Skipping unreadable edit history entry %s: %v", entry.Name(), err)
+			continue
+		}
+		var record EditRecord
+		if err := json.Unmarshal(data, &record); err != nil {
+			coreLogger.Warn("Skipping invalid edit history entry %s: %v", entry.Name(), err)
+			continue
+		}
+		records = append(records, &record)
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].ID < records[j].ID })
+
+	editHistory.dir = dir
+	editHistory.records = records
+	editHistory.nextID = 1
+	if len(records) > 0 {
+		editHistory.nextID = records[len(records)-1].ID + 1
+	}
+	coreLogger.Info("Loaded %d edits from history in %s", len(records), dir)
+	return nil
+}
+
+// recordEdit adds the changes of a committed transaction to the history
+func recordEdit(description string, tx *workspaceTransaction) {
+	paths := make([]string, 0, len(tx.files))
+	for path, file := range tx.files {
+		if file.changed() {
+			paths = append(paths, path)
+		}
+	}
+	if len(paths) == 0 && len(tx.directories) == 0 {
+		return
+	}
+	sort.Strings(paths)
+
+	if editHistory.nextID == 0 {
+		editHistory.nextID = 1
+	}
+	record := &EditRecord{
+		ID:          editHistory.nextID,
+		Time:        time.Now(),
+		Description: description,
+	}
+	editHistory.nextID++
+	for _, dir := range tx.directories {
+		if dir.deleted {
+			record.Directories = append(record.Directories, "deleted "+dir.oldPath)
+		} else {
+			record.Directories = append(record.Directories, "renamed "+dir.oldPath+" to "+dir.newPath)
+		}
+	}
+	for _, path := range paths {
+		file := tx.files[path]
+		record.Files = append(record.Files, FileRevision{
+			Path:         path,
+			Before:       file.original,
+			BeforeExists: file.originalExists,
+			After:        file.content,
+			AfterExists:  file.exists,
+		})
+	}
+
+	// A new edit replaces the edits that were undone
+	kept := editHistory.records[:0]
+	for _, r := range editHistory.records {
+		if r.Undone {
+			deleteHistoryRecord(r)
+			continue
+		}
+		kept = append(kept, r)
+	}
+	editHistory.records = append(kept, record)
+
+	for len(editHistory.records) > maxEditHistory {
+		deleteHistoryRecord(editHistory.records[0])
+		editHistory.records = editHistory.records[1:]
+	}
+
+	saveHistoryRecord(record)
+}
+
+func historyRecordPath(record *EditRecord) string {
+	return filepath.Join(editHistory.dir, fmt.Sprintf("%08d.json", record.ID))
+}
+
+func saveHistoryRecord(record *EditRecord) {
+	if editHistory.dir == "" {
+		return
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		coreLogger.Error("Failed to encode edit history entry %d: %v", record.ID, err)
+		return
+	}
+	if err := os.WriteFile(historyRecordPath(record), data, 0600); err != nil {
+		coreLogger.Error("Failed to save edit history entry %d: %v", record.ID, err)
+	}
+}
+
+func deleteHistoryRecord(record *EditRecord) {
+	if editHistory.dir == "" {
+		return
+	}
+	if err := os.Remove(historyRecordPath(record)); err != nil && !os.IsNotExist(err) {
+		coreLogger.Warn("Failed to remove edit history entry %d: %v", record.ID, err)
+	}
+}
+
+// EditHistory returns a copy of the recorded edits, oldest first
+func EditHistory() []EditRecord {
+	workspaceEditMu.Lock()
+	defer workspaceEditMu.Unlock()
+
+	records := make([]EditRecord, len(editHistory.records))
+	for i, record := range editHistory.records {
+		records[i] = *record
+	}
+	return records
+}
+
+// UndoLastEdit restores the files changed by the most recent edit that has
+// not been undone. It refuses to undo when any of those files changed since,
+// or when the edit renamed or deleted directories.
+func UndoLastEdit() (EditRecord, error) {
+	workspaceEditMu.Lock()
+	defer workspaceEditMu.Unlock()
+
+	var record *EditRecord
+	for i := len(editHistory.records) - 1; i >= 0; i-- {
+		if !editHistory.records[i].Undone {
+			record = editHistory.records[i]
+			break
+		}
+	}
+	if record == nil {
+		return EditRecord{}, fmt.Errorf("no edits to undo")
+	}
+	if len(record.Directories) > 0 {
+		return EditRecord{}, fmt.Errorf("edit %d cannot be undone because it %s", record.ID, strings.Join(record.Directories, ", "))
+	}
+
+	if err := restoreRevisions(record, false); err != nil {
+		return EditRecord{}, fmt.Errorf("failed to undo edit %d: %w", record.ID, err)
+	}
+	record.Undone = true
+	saveHistoryRecord(record)
+	return *record, nil
+}
+
+// RedoLastEdit applies the most recently undone edit again
+func RedoLastEdit() (EditRecord, error) {
+	workspaceEditMu.Lock()
+	defer workspaceEditMu.Unlock()
+
+	var record *EditRecord
+	for _, r := range editHistory.records {
+		if r.Undone {
+			record = r
+			break
+		}
+	}
+	if record == nil {
+		return EditRecord{}, fmt.Errorf("no undone edits to redo")
+	}
+
+	if err := restoreRevisions(record, true); err != nil {
+		return EditRecord{}, fmt.Errorf("failed to redo edit %d: %w", record.ID, err)
+	}
+	record.Undone = false
+	saveHistoryRecord(record)
+	return *record, nil
+}
+
+// restoreRevisions atomically puts the files of an edit back to their
+// content before the edit, or after it when redoing
+func restoreRevisions(record *EditRecord, redo bool) error {
+	tx := &workspaceTransaction{files: make(map[string]*stagedFile)}
+
+	var conflicts []string
+	for _, revision := range record.Files {
+		expected, expectedExists := revision.After, revision.AfterExists
+		target, targetExists := revision.Before, revision.BeforeExists
+		if redo {
+			expected, expectedExists = revision.Before, revision.BeforeExists
+			target, targetExists = revision.After, revision.AfterExists
+		}
+
+		file, err := tx.load(revision.Path)
+		if err != nil {
+			return err
+		}
+		if file.exists != expectedExists || !bytes.Equal(file.content, expected) {
+			conflicts = append(conflicts, revision.Path)
+			continue
+		}
+		file.content = target
+		file.exists = targetExists
+	}
+
+	if len(conflicts) > 0 {
+		return fmt.Errorf("files changed since the edit, no files were restored: %s", strings.Join(conflicts, ", "))
+	}
+
+	return tx.commit()
+}
+
+// String summarizes an edit record on one line
+func (r EditRecord) String() string {
+	status := ""
+	if r.Undone {
+		status = " [undone]"
+	} else if len(r.Directories) > 0 {
+		status = " [cannot be undone]"
+	}
+	files := strconv.Itoa(len(r.Files)) + " files"
+	if len(r.Files) == 1 {
+		files = "1 file"
+	}
+	return fmt.Sprintf("#%d %s %s (%s)%s", r.ID, r.Time.Format(time.DateTime), r.Description, files, status)
+}
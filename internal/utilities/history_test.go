@@ -0,0 +1,141 @@
+package utilities
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+func TestEditHistoryUndoRedo(t *testing.T) {
+	editHistory.records = nil
+
+	mfs := &mockFileSystem{
+		files: map[string][]byte{
+			"/test/file1.txt": []byte("This is a test line"),
+		},
+	}
+	cleanup := setupMockFileSystem(t, mfs)
+	defer cleanup()
+
+	edit := protocol.WorkspaceEdit{
+		Changes: map[protocol.DocumentUri][]protocol.TextEdit{
+			"file:///test/file1.txt": {
+				{
+					Range: protocol.Range{
+						Start: protocol.Position{Line: 0, Character: 5},
+						End:   protocol.Position{Line: 0, Character: 9},
+					},
+					NewText: "was",
+				},
+			},
+		},
+		DocumentChanges: []protocol.DocumentChange{
+			{CreateFile: &protocol.CreateFile{URI: "file:///test/new.txt"}},
+		},
+	}
+	if err := ApplyWorkspaceEdit(edit, "test edit"); err != nil {
+		t.Fatalf("ApplyWorkspaceEdit failed: %v", err)
+	}
+
+	history := EditHistory()
+	if len(history) != 1 || history[0].Description != "test edit" || len(history[0].Files) != 2 {
+		t.Fatalf("Expected one recorded edit of two files, got %+v", history)
+	}
+
+	if _, err := UndoLastEdit(); err != nil {
+		t.Fatalf("UndoLastEdit failed: %v", err)
+	}
+	if content := string(mfs.files["/test/file1.txt"]); content != "This is a test line" {
+		t.Errorf("Undo did not restore file1, content: %s", content)
+	}
+	if _, ok := mfs.files["/test/new.txt"]; ok {
+		t.Errorf("Undo did not remove the created file")
+	}
+	if _, err := UndoLastEdit(); err == nil {
+		t.Errorf("Expected an error when there is nothing left to undo")
+	}
+
+	if _, err := RedoLastEdit(); err != nil {
+		t.Fatalf("RedoLastEdit failed: %v", err)
+	}
+	if content := string(mfs.files["/test/file1.txt"]); content != "This was test line" {
+		t.Errorf("Redo did not reapply the edit, content: %s", content)
+	}
+
+	// Files changed outside the server are not overwritten
+	mfs.files["/test/file1.txt"] = []byte("Changed by someone else")
+	_, err := UndoLastEdit()
+	if err == nil || !strings.Contains(err.Error(), "/test/file1.txt") {
+		t.Fatalf("Expected a conflict for the changed file, got: %v", err)
+	}
+	if _, ok := mfs.files["/test/new.txt"]; !ok {
+		t.Errorf("Conflicting undo removed the created file")
+	}
+
+	// A new edit discards the edits that were undone
+	mfs.files["/test/file1.txt"] = []byte("This was test line")
+	if _, err := UndoLastEdit(); err != nil {
+		t.Fatalf("UndoLastEdit failed: %v", err)
+	}
+	if err := ApplyWorkspaceEdit(protocol.WorkspaceEdit{
+		DocumentChanges: []protocol.DocumentChange{
+			{CreateFile: &protocol.CreateFile{URI: "file:///test/other.txt"}},
+		},
+	}, "second edit"); err != nil {
+		t.Fatalf("ApplyWorkspaceEdit failed: %v", err)
+	}
+	history = EditHistory()
+	if len(history) != 1 || history[0].Description != "second edit" {
+		t.Errorf("Expected only the new edit in the history, got %+v", history)
+	}
+	if _, err := RedoLastEdit(); err == nil {
+		t.Errorf("Expected nothing to redo after a new edit")
+	}
+}
+
+func TestEditHistoryDirectoryRenameNotUndoable(t *testing.T) {
+	editHistory.records = nil
+
+	dir := t.TempDir()
+	oldDir := filepath.Join(dir, "old")
+	newDir := filepath.Join(dir, "new")
+	if err := os.Mkdir(oldDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(oldDir, "a.txt"), []byte("old line\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	edit := protocol.WorkspaceEdit{DocumentChanges: []protocol.DocumentChange{
+		renameFile(oldDir, newDir),
+		textDocumentEdit(filepath.Join(newDir, "a.txt"), "new line\n"),
+	}}
+	if err := ApplyWorkspaceEdit(edit, "move package"); err != nil {
+		t.Fatalf("ApplyWorkspaceEdit failed: %v", err)
+	}
+
+	history := EditHistory()
+	if len(history) != 1 || len(history[0].Directories) != 1 {
+		t.Fatalf("Expected one recorded edit with a directory rename, got %+v", history)
+	}
+
+	_, err := UndoLastEdit()
+	if err == nil || !strings.Contains(err.Error(), "cannot be undone") {
+		t.Fatalf("Expected undo to be refused, got: %v", err)
+	}
+
+	// Nothing was restored
+	content, err := os.ReadFile(filepath.Join(newDir, "a.txt"))
+	if err != nil || string(content) != "new line\n" {
+		t.Errorf("Refused undo changed the renamed file: %q, %v", content, err)
+	}
+	if _, err := os.Stat(oldDir); !os.IsNotExist(err) {
+		t.Errorf("Refused undo recreated the old directory: %v", err)
+	}
+	if history := EditHistory(); history[0].Undone {
+		t.Errorf("Refused undo marked the edit as undone")
+	}
+}
@@ -0,0 +1,296 @@
+package utilities
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// WorkspaceEditPaths returns every path a workspace edit writes, creates,
+// renames or deletes, in the order they appear
+func WorkspaceEditPaths(edit protocol.WorkspaceEdit) []string {
+	var paths []string
+	for uri := range edit.Changes {
+		paths = append(paths, strings.TrimPrefix(string(uri), "file://"))
+	}
+	for _, change := range edit.DocumentChanges {
+		switch {
+		case change.TextDocumentEdit != nil:
+			paths = append(paths, strings.TrimPrefix(string(change.TextDocumentEdit.TextDocument.URI), "file://"))
+		case change.CreateFile != nil:
+			paths = append(paths, strings.TrimPrefix(string(change.CreateFile.URI), "file://"))
+		case change.RenameFile != nil:
+			paths = append(paths,
+				strings.TrimPrefix(string(change.RenameFile.OldURI), "file://"),
+				strings.TrimPrefix(string(change.RenameFile.NewURI), "file://"))
+		case change.DeleteFile != nil:
+			paths = append(paths, strings.TrimPrefix(string(change.DeleteFile.URI), "file://"))
+		}
+	}
+	return paths
+}
+
+// CheckPathWithinRoot returns an error unless path is inside root once
+// symlinks and ".." elements are resolved, so that a symlink in the workspace
+// cannot be used to reach files outside of it. The path does not need to
+// exist yet.
+func CheckPathWithinRoot(root, path string) error {
+	if !filepath.IsAbs(path) {
+		return fmt.Errorf("%s is not an absolute file path", path)
+	}
+
+	resolvedRoot, err := resolvePath(root)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", root, err)
+	}
+	resolved, err := resolvePath(path)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", path, err)
+	}
+
+	if !isWithin(resolvedRoot, resolved) {
+		if resolved != filepath.Clean(path) {
+			return fmt.Errorf("%s resolves to %s, outside of %s", path, resolved, root)
+		}
+		return fmt.Errorf("%s is outside of %s", path, root)
+	}
+	return nil
+}
+
+// resolvePath resolves the symlinks of a path the way the filesystem would,
+// so ".." after a symlink refers to the parent of the symlink's target rather
+// than of the link. Components that do not exist yet are kept as they are.
+func resolvePath(path string) (string, error) {
+	elements := strings.Split(filepath.ToSlash(path), "/")
+	for i := len(elements); i > 0; i-- {
+		prefix := filepath.FromSlash(strings.Join(elements[:i], "/"))
+		if prefix == "" {
+			prefix = string(filepath.Separator)
+		}
+		resolved, err := filepath.EvalSymlinks(prefix)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return "", err
+		}
+
+		missing := elements[i:]
+		for _, element := range missing {
+			// The parent of a missing directory cannot be known
+			if element == ".." {
+				return "", fmt.Errorf("%s: %w", filepath.FromSlash(strings.Join(elements[:i+1], "/")), os.ErrNotExist)
+			}
+		}
+		return filepath.Join(append([]string{resolved}, missing...)...), nil
+	}
+	return filepath.Clean(path), nil
+}
+
+// PathRoot is a directory outside the workspace that is shown under a label
+// in tool output, such as $GOROOT for the Go standard library
+type PathRoot struct {
+	Label string
+	Dir   string
+}
+
+// dependencyDirs are the directories package managers install dependencies
+// in. Paths inside one at the workspace root are shown under its label.
+var dependencyDirs = []string{"node_modules", "site-packages", "dist-packages"}
+
+// workspacePaths controls how paths from tool input are resolved and how
+// paths are shown in tool output
+var workspacePaths struct {
+	sync.RWMutex
+	workspace string
+	relative  bool
+	roots     []PathRoot
+}
+
+// SetWorkspacePaths resolves relative paths in tool input against the
+// workspace. If relative is set, paths in tool output are shown relative to
+// the workspace, or to a labelled root or dependency directory.
+func SetWorkspacePaths(workspaceDir string, relative bool, roots []PathRoot) {
+	workspacePaths.Lock()
+	defer workspacePaths.Unlock()
+
+	workspacePaths.workspace = workspaceDir
+	workspacePaths.relative = relative
+	workspacePaths.roots = nil
+	for _, root := range roots {
+		if root.Label != "" {
+			workspacePaths.roots = append(workspacePaths.roots, root)
+		}
+	}
+	// Prefer the innermost root when roots are nested
+	sort.SliceStable(workspacePaths.roots, func(i, j int) bool {
+		return len(workspacePaths.roots[i].Dir) > len(workspacePaths.roots[j].Dir)
+	})
+}
+
+// ResolvePath returns the absolute path for a path given to a tool. Relative
+// paths are resolved against the workspace and labelled paths such as
+// "$GOROOT/src/fmt/print.go" against their root.
+func ResolvePath(path string) string {
+	workspacePaths.RLock()
+	defer workspacePaths.RUnlock()
+
+	if path == "" {
+		return path
+	}
+	for _, root := range workspacePaths.roots {
+		if rest, ok := cutPathPrefix(path, root.Label); ok {
+			return filepath.Join(root.Dir, rest)
+		}
+	}
+	if filepath.IsAbs(path) || workspacePaths.workspace == "" {
+		return path
+	}
+	for _, dir := range dependencyDirs {
+		if rest, ok := cutPathPrefix(path, "<"+dir+">"); ok {
+			return filepath.Join(workspacePaths.workspace, dir, rest)
+		}
+	}
+	return filepath.Join(workspacePaths.workspace, path)
+}
+
+// DisplayPath returns how a path is shown in tool output
+func DisplayPath(path string) string {
+	workspacePaths.RLock()
+	defer workspacePaths.RUnlock()
+
+	if !workspacePaths.relative || !filepath.IsAbs(path) {
+		return path
+	}
+
+	if workspacePaths.workspace != "" && isWithin(workspacePaths.workspace, path) {
+		rel, _ := filepath.Rel(workspacePaths.workspace, path)
+		rel = filepath.ToSlash(rel)
+		// Only labels that ResolvePath maps back to the same file are used
+		for _, dir := range dependencyDirs {
+			if rest, ok := strings.CutPrefix(rel, dir+"/"); ok {
+				return "<" + dir + ">/" + rest
+			}
+		}
+		return rel
+	}
+	for _, root := range workspacePaths.roots {
+		if isWithin(root.Dir, path) {
+			rel, _ := filepath.Rel(root.Dir, path)
+			if rel == "." {
+				return root.Label
+			}
+			return root.Label + "/" + filepath.ToSlash(rel)
+		}
+	}
+	return path
+}
+
+// MatchPathGlob reports whether a file matches a glob from tool input. Globs
+// with a slash, such as "internal/**/*.go", match the path relative to the
+// workspace, or the absolute path for files outside it, and also match the
+// files below a matching directory. Globs without a slash, such as
+// "*_test.go" or "vendor", match any element of the path, and a trailing
+// slash as in "vendor/" only matches directories.
+func MatchPathGlob(pattern, path string) (bool, error) {
+	if !doublestar.ValidatePattern(pattern) {
+		return false, fmt.Errorf("invalid glob: %s", pattern)
+	}
+
+	workspacePaths.RLock()
+	workspace := workspacePaths.workspace
+	workspacePaths.RUnlock()
+
+	path = filepath.Clean(path)
+	absolute := filepath.ToSlash(path)
+	if workspace != "" && isWithin(workspace, path) {
+		rel, _ := filepath.Rel(workspace, path)
+		path = rel
+	}
+	path = filepath.ToSlash(path)
+
+	dirOnly := strings.HasSuffix(pattern, "/")
+	pattern = strings.TrimSuffix(pattern, "/")
+	if strings.Contains(pattern, "/") {
+		pattern = strings.TrimPrefix(pattern, "./")
+		for _, name := range []string{path, absolute} {
+			if doublestar.MatchUnvalidated(pattern, name) || doublestar.MatchUnvalidated(pattern+"/**", name) {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+
+	elements := strings.Split(path, "/")
+	if dirOnly {
+		elements = elements[:len(elements)-1]
+	}
+	for _, element := range elements {
+		if doublestar.MatchUnvalidated(pattern, element) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// WorkspaceDir returns the workspace directory, or "" if none is set
+func WorkspaceDir() string {
+	workspacePaths.RLock()
+	defer workspacePaths.RUnlock()
+	return workspacePaths.workspace
+}
+
+// WorkspaceRelativePath returns a path relative to the workspace, with
+// forward slashes, or the path itself when it is outside the workspace
+func WorkspaceRelativePath(path string) string {
+	workspacePaths.RLock()
+	defer workspacePaths.RUnlock()
+
+	if workspacePaths.workspace == "" || !isWithin(workspacePaths.workspace, path) {
+		return path
+	}
+	rel, _ := filepath.Rel(workspacePaths.workspace, path)
+	return filepath.ToSlash(rel)
+}
+
+// IsExternalPath reports whether a file belongs to the standard library or
+// a third-party dependency rather than to the project: it is outside the
+// workspace or inside a directory dependencies are installed or vendored in
+func IsExternalPath(path string) bool {
+	workspacePaths.RLock()
+	workspace := workspacePaths.workspace
+	workspacePaths.RUnlock()
+
+	if workspace == "" || !isWithin(workspace, path) {
+		return workspace != ""
+	}
+	rel, _ := filepath.Rel(workspace, path)
+	for _, element := range strings.Split(filepath.ToSlash(rel), "/") {
+		if element == "vendor" || slices.Contains(dependencyDirs, element) {
+			return true
+		}
+	}
+	return false
+}
+
+// DisplayURI returns how the file a URI refers to is shown in tool output
+func DisplayURI(uri protocol.DocumentUri) string {
+	return DisplayPath(strings.TrimPrefix(string(uri), "file://"))
+}
+
+// cutPathPrefix returns the rest of path after a leading label element
+func cutPathPrefix(path, label string) (string, bool) {
+	if path == label {
+		return "", true
+	}
+	rest, ok := strings.CutPrefix(filepath.ToSlash(path), label+"/")
+	return rest, ok
+}
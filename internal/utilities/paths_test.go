@@ -0,0 +1,195 @@
+package utilities
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckPathWithinRoot(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+
+	if err := os.Mkdir(filepath.Join(root, "pkg"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("secret"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(root, "escape")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(outside, "secret.txt"), filepath.Join(root, "secret.txt")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(root, "pkg"), filepath.Join(root, "alias")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		path      string
+		expectErr bool
+	}{
+		{"File in root", filepath.Join(root, "main.go"), false},
+		{"New file in new directory", filepath.Join(root, "new", "dir", "file.go"), false},
+		{"Symlink within root", filepath.Join(root, "alias", "file.go"), false},
+		{"Path outside root", filepath.Join(outside, "secret.txt"), true},
+		{"Parent directory element", root + "/pkg/../../etc/passwd", true},
+		{"Directory symlink out of root", filepath.Join(root, "escape", "new.txt"), true},
+		{"File symlink out of root", filepath.Join(root, "secret.txt"), true},
+		{"Relative path", "main.go", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckPathWithinRoot(root, tt.path)
+			if tt.expectErr && err == nil {
+				t.Errorf("Expected %s to be rejected", tt.path)
+			}
+			if !tt.expectErr && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestWorkspacePaths(t *testing.T) {
+	SetWorkspacePaths("/home/user/project", true, []PathRoot{
+		{Label: "$GOROOT", Dir: "/usr/local/go"},
+		{Label: "$GOMODCACHE", Dir: "/home/user/go/pkg/mod"},
+		{Dir: "/opt/unlabelled"},
+	})
+	defer SetWorkspacePaths("", false, nil)
+
+	resolveTests := []struct {
+		path     string
+		expected string
+	}{
+		{"main.go", "/home/user/project/main.go"},
+		{"./pkg/util.go", "/home/user/project/pkg/util.go"},
+		{"/tmp/other.go", "/tmp/other.go"},
+		{"$GOROOT/src/fmt/print.go", "/usr/local/go/src/fmt/print.go"},
+		{"<node_modules>/react/index.js", "/home/user/project/node_modules/react/index.js"},
+		{"", ""},
+	}
+	for _, tt := range resolveTests {
+		if got := ResolvePath(tt.path); got != tt.expected {
+			t.Errorf("ResolvePath(%q) = %q, expected %q", tt.path, got, tt.expected)
+		}
+	}
+
+	displayTests := []struct {
+		path     string
+		expected string
+	}{
+		{"/home/user/project/main.go", "main.go"},
+		{"/home/user/project/pkg/util.go", "pkg/util.go"},
+		{"/usr/local/go/src/fmt/print.go", "$GOROOT/src/fmt/print.go"},
+		{"/home/user/go/pkg/mod/golang.org/x/tools@v0.1.0/go.mod", "$GOMODCACHE/golang.org/x/tools@v0.1.0/go.mod"},
+		{"/home/user/project/node_modules/react/index.js", "<node_modules>/react/index.js"},
+		{"/home/user/project/node_modules/react/node_modules/scheduler/index.js", "<node_modules>/react/node_modules/scheduler/index.js"},
+		{"/home/user/project/web/node_modules/react/index.js", "web/node_modules/react/index.js"},
+		{"/usr/lib/python3/site-packages/requests/api.py", "/usr/lib/python3/site-packages/requests/api.py"},
+		{"/opt/unlabelled/lib.go", "/opt/unlabelled/lib.go"},
+		{"/tmp/other.go", "/tmp/other.go"},
+	}
+	for _, tt := range displayTests {
+		if got := DisplayPath(tt.path); got != tt.expected {
+			t.Errorf("DisplayPath(%q) = %q, expected %q", tt.path, got, tt.expected)
+		}
+	}
+
+	// Every path shown resolves back to the same file
+	for _, tt := range displayTests {
+		if got := ResolvePath(DisplayPath(tt.path)); got != tt.path {
+			t.Errorf("ResolvePath(DisplayPath(%q)) = %q", tt.path, got)
+		}
+	}
+
+	// Paths stay absolute unless relative output is enabled
+	SetWorkspacePaths("/home/user/project", false, nil)
+	if got := DisplayPath("/home/user/project/main.go"); got != "/home/user/project/main.go" {
+		t.Errorf("Expected an absolute path, got %q", got)
+	}
+	if got := ResolvePath("main.go"); got != "/home/user/project/main.go" {
+		t.Errorf("Expected relative input to be resolved, got %q", got)
+	}
+}
+
+func TestMatchPathGlob(t *testing.T) {
+	SetWorkspacePaths("/home/user/project", false, nil)
+	defer SetWorkspacePaths("", false, nil)
+
+	tests := []struct {
+		pattern  string
+		path     string
+		expected bool
+	}{
+		{"*_test.go", "/home/user/project/pkg/util_test.go", true},
+		{"*_test.go", "/home/user/project/pkg/util.go", false},
+		{"vendor/", "/home/user/project/vendor/lib/lib.go", true},
+		{"vendor/", "/home/user/project/pkg/vendor", false},
+		{"vendor", "/home/user/project/pkg/vendor", true},
+		{"pkg/**/*.go", "/home/user/project/pkg/sub/util.go", true},
+		{"pkg", "/home/user/project/pkg/util.go", true},
+		{"internal/tools", "/home/user/project/internal/tools/hover.go", true},
+		{"./internal/tools", "/home/user/project/internal/tools/hover.go", true},
+		{"internal/tools", "/home/user/project/cmd/internal/tools/main.go", false},
+		{"/usr/local/go/**", "/usr/local/go/src/fmt/print.go", true},
+		{"/home/user/project/pkg/*.go", "/home/user/project/pkg/util.go", true},
+	}
+	for _, tt := range tests {
+		got, err := MatchPathGlob(tt.pattern, tt.path)
+		if err != nil {
+			t.Errorf("MatchPathGlob(%q, %q) failed: %v", tt.pattern, tt.path, err)
+			continue
+		}
+		if got != tt.expected {
+			t.Errorf("MatchPathGlob(%q, %q) = %v, expected %v", tt.pattern, tt.path, got, tt.expected)
+		}
+	}
+
+	if _, err := MatchPathGlob("[", "/home/user/project/main.go"); err == nil {
+		t.Error("Expected an error for an invalid glob")
+	}
+}
+
+func TestIsExternalPath(t *testing.T) {
+	SetWorkspacePaths("/home/user/project", false, nil)
+	defer SetWorkspacePaths("", false, nil)
+
+	tests := []struct {
+		path     string
+		expected bool
+	}{
+		{"/home/user/project/main.go", false},
+		{"/home/user/project/pkg/util.go", false},
+		{"/usr/local/go/src/fmt/print.go", true},
+		{"/home/user/go/pkg/mod/golang.org/x/tools@v0.1.0/go.mod", true},
+		{"/home/user/project/vendor/github.com/lib/lib.go", true},
+		{"/home/user/project/web/node_modules/react/index.js", true},
+		{"/home/user/project/.venv/lib/python3.12/site-packages/requests/api.py", true},
+	}
+	for _, tt := range tests {
+		if got := IsExternalPath(tt.path); got != tt.expected {
+			t.Errorf("IsExternalPath(%q) = %v, expected %v", tt.path, got, tt.expected)
+		}
+	}
+}
+
+func TestWorkspaceRelativePath(t *testing.T) {
+	SetWorkspacePaths("/home/user/project", false, nil)
+	defer SetWorkspacePaths("", false, nil)
+
+	tests := map[string]string{
+		"/home/user/project/pkg/util_test.go": "pkg/util_test.go",
+		"/home/user/project":                  ".",
+		"/usr/local/go/src/fmt/print.go":      "/usr/local/go/src/fmt/print.go",
+	}
+	for path, expected := range tests {
+		if got := WorkspaceRelativePath(path); got != expected {
+			t.Errorf("WorkspaceRelativePath(%q) = %q, expected %q", path, got, expected)
+		}
+	}
+}
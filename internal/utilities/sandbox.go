@@ -0,0 +1,150 @@
+package utilities
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Sandbox confines the files tools may read and write. Files in the
+// workspace can be read and written, files in the read-only roots can only
+// be read, and everything else is rejected.
+type Sandbox struct {
+	workspace     string
+	readOnlyRoots []string
+}
+
+// sandbox is the active sandbox. File access is unrestricted until
+// SetSandbox is called.
+var sandbox struct {
+	sync.RWMutex
+	current *Sandbox
+}
+
+// NewSandbox creates a sandbox for the workspace and the extra read-only
+// roots. Roots are resolved once so that symlinked roots still match.
+func NewSandbox(workspaceDir string, readOnlyRoots []string) (*Sandbox, error) {
+	if !filepath.IsAbs(workspaceDir) {
+		return nil, fmt.Errorf("workspace %s is not an absolute path", workspaceDir)
+	}
+	workspace, err := resolvePath(workspaceDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve workspace %s: %w", workspaceDir, err)
+	}
+
+	s := &Sandbox{workspace: workspace}
+	for _, root := range readOnlyRoots {
+		if !filepath.IsAbs(root) {
+			return nil, fmt.Errorf("read-only root %s is not an absolute path", root)
+		}
+		resolved, err := resolvePath(root)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve read-only root %s: %w", root, err)
+		}
+		s.readOnlyRoots = append(s.readOnlyRoots, resolved)
+	}
+	return s, nil
+}
+
+// SetSandbox makes s the sandbox every file access is checked against. A nil
+// sandbox lifts all restrictions.
+func SetSandbox(s *Sandbox) {
+	sandbox.Lock()
+	defer sandbox.Unlock()
+	sandbox.current = s
+}
+
+func currentSandbox() *Sandbox {
+	sandbox.RLock()
+	defer sandbox.RUnlock()
+	return sandbox.current
+}
+
+// CheckReadAccess returns an error unless path may be read under the active
+// sandbox
+func CheckReadAccess(path string) error {
+	s := currentSandbox()
+	if s == nil {
+		return nil
+	}
+	return s.CheckRead(path)
+}
+
+// CheckWriteAccess returns an error unless path may be modified under the
+// active sandbox
+func CheckWriteAccess(path string) error {
+	s := currentSandbox()
+	if s == nil {
+		return nil
+	}
+	return s.CheckWrite(path)
+}
+
+// CheckRead returns an error unless path is in the workspace or one of the
+// read-only roots once symlinks and ".." elements are resolved
+func (s *Sandbox) CheckRead(path string) error {
+	resolved, err := s.resolve(path)
+	if err != nil {
+		return err
+	}
+	if isWithin(s.workspace, resolved) {
+		return nil
+	}
+	for _, root := range s.readOnlyRoots {
+		if isWithin(root, resolved) {
+			return nil
+		}
+	}
+
+	if len(s.readOnlyRoots) == 0 {
+		return fmt.Errorf("access denied: %s is outside the workspace %s", describeResolved(path, resolved), s.workspace)
+	}
+	return fmt.Errorf("access denied: %s is outside the workspace %s and the read-only roots %s",
+		describeResolved(path, resolved), s.workspace, strings.Join(s.readOnlyRoots, ", "))
+}
+
+// CheckWrite returns an error unless path is in the workspace once symlinks
+// and ".." elements are resolved
+func (s *Sandbox) CheckWrite(path string) error {
+	resolved, err := s.resolve(path)
+	if err != nil {
+		return err
+	}
+	if isWithin(s.workspace, resolved) {
+		return nil
+	}
+	for _, root := range s.readOnlyRoots {
+		if isWithin(root, resolved) {
+			return fmt.Errorf("access denied: %s is in the read-only root %s, only files in the workspace %s can be modified",
+				describeResolved(path, resolved), root, s.workspace)
+		}
+	}
+	return fmt.Errorf("access denied: %s is outside the workspace %s", describeResolved(path, resolved), s.workspace)
+}
+
+func (s *Sandbox) resolve(path string) (string, error) {
+	if !filepath.IsAbs(path) {
+		return "", fmt.Errorf("access denied: %s is not an absolute file path", path)
+	}
+	resolved, err := resolvePath(path)
+	if err != nil {
+		return "", fmt.Errorf("access denied: failed to resolve %s: %w", path, err)
+	}
+	return resolved, nil
+}
+
+// isWithin reports whether the resolved path is root or inside it
+func isWithin(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// describeResolved names a path in errors, mentioning where it leads when
+// symlinks or ".." elements made the difference
+func describeResolved(path, resolved string) string {
+	if resolved != filepath.Clean(path) {
+		return fmt.Sprintf("%s (resolves to %s)", path, resolved)
+	}
+	return path
+}
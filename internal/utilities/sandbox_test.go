@@ -0,0 +1,111 @@
+package utilities
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+func TestSandbox(t *testing.T) {
+	workspace := t.TempDir()
+	readOnly := t.TempDir()
+	outside := t.TempDir()
+
+	for _, dir := range []string{filepath.Join(workspace, "pkg"), filepath.Join(outside, "dir")} {
+		if err := os.Mkdir(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(filepath.Join(outside, "dir"), filepath.Join(workspace, "escape")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(readOnly, filepath.Join(workspace, "deps")); err != nil {
+		t.Fatal(err)
+	}
+
+	sandbox, err := NewSandbox(workspace, []string{readOnly})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		path        string
+		readDenied  bool
+		writeDenied bool
+	}{
+		{"Workspace file", filepath.Join(workspace, "main.go"), false, false},
+		{"Cleaned parent element", workspace + "/pkg/../main.go", false, false},
+		{"Read-only root", filepath.Join(readOnly, "lib.go"), false, true},
+		{"Symlink to read-only root", filepath.Join(workspace, "deps", "lib.go"), false, true},
+		{"Outside", "/etc/passwd", true, true},
+		{"Symlink out of workspace", filepath.Join(workspace, "escape", "file.go"), true, true},
+		// The filesystem resolves ".." against the symlink target, not the link
+		{"Parent of symlink target", workspace + "/escape/../secret.txt", true, true},
+		{"Relative path", "main.go", true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := sandbox.CheckRead(tt.path); (err != nil) != tt.readDenied {
+				t.Errorf("CheckRead(%s) = %v, expected denied: %v", tt.path, err, tt.readDenied)
+			}
+			if err := sandbox.CheckWrite(tt.path); (err != nil) != tt.writeDenied {
+				t.Errorf("CheckWrite(%s) = %v, expected denied: %v", tt.path, err, tt.writeDenied)
+			}
+		})
+	}
+
+	err = sandbox.CheckWrite(filepath.Join(readOnly, "lib.go"))
+	if err == nil || !strings.Contains(err.Error(), "read-only root") {
+		t.Errorf("Expected a read-only root error, got %v", err)
+	}
+}
+
+func TestApplyWorkspaceEditOutsideSandbox(t *testing.T) {
+	workspace := t.TempDir()
+	outside := t.TempDir()
+
+	inside := filepath.Join(workspace, "main.go")
+	target := filepath.Join(outside, "main.go")
+	for _, path := range []string{inside, target} {
+		if err := os.WriteFile(path, []byte("package main\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sandbox, err := NewSandbox(workspace, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetSandbox(sandbox)
+	defer SetSandbox(nil)
+
+	replace := []protocol.TextEdit{{
+		Range:   protocol.Range{Start: protocol.Position{Line: 0, Character: 8}, End: protocol.Position{Line: 0, Character: 12}},
+		NewText: "other",
+	}}
+	err = ApplyWorkspaceEdit(protocol.WorkspaceEdit{
+		Changes: map[protocol.DocumentUri][]protocol.TextEdit{
+			protocol.DocumentUri("file://" + inside): replace,
+			protocol.DocumentUri("file://" + target): replace,
+		},
+	}, "test")
+	if err == nil || !strings.Contains(err.Error(), "access denied") {
+		t.Fatalf("Expected access denied, got %v", err)
+	}
+
+	// Neither file is changed
+	for _, path := range []string{inside, target} {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(content) != "package main\n" {
+			t.Errorf("%s was modified: %q", path, content)
+		}
+	}
+}
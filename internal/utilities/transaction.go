@@ -0,0 +1,377 @@
+package utilities
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// workspaceEditMu serializes workspace edits so that one edit never stages
+// or rolls back on top of another
+var workspaceEditMu sync.Mutex
+
+// stagedFile is the state of a file before and after a workspace edit
+type stagedFile struct {
+	original       []byte
+	originalExists bool
+	mode           os.FileMode
+
+	content []byte
+	exists  bool
+}
+
+// changed reports whether the edit leaves the file different from before
+func (f *stagedFile) changed() bool {
+	return f.exists != f.originalExists || !bytes.Equal(f.content, f.original)
+}
+
+// directoryChange is a rename or recursive delete of a directory, which is
+// applied with a single rename and undone by renaming it back
+type directoryChange struct {
+	oldPath string
+	newPath string
+	// deleted directories are renamed aside and only removed once every
+	// other change has been applied
+	deleted bool
+}
+
+// workspaceTransaction applies a workspace edit to every file or to none of
+// them. Changes are first applied in memory against the current content, so
+// invalid edits are rejected before anything is written. The new content is
+// then staged in temporary files next to their targets and renamed into
+// place, and the original content is restored if any step fails.
+//
+// Files are keyed by where they end up. Directory changes are only applied
+// on commit, so a path named after a directory rename is read from where the
+// file is before the edit, and files staged inside a renamed directory move
+// with it.
+type workspaceTransaction struct {
+	files       map[string]*stagedFile
+	directories []directoryChange
+}
+
+// ApplyWorkspaceEdit applies the given WorkspaceEdit to the filesystem. The
+// edit is atomic: if any change fails, no file is left modified. Applied
+// edits are recorded in the edit history under the given description.
+func ApplyWorkspaceEdit(edit protocol.WorkspaceEdit, description string) error {
+	workspaceEditMu.Lock()
+	defer workspaceEditMu.Unlock()
+
+	tx := &workspaceTransaction{files: make(map[string]*stagedFile)}
+
+	// Handle Changes field
+	uris := make([]string, 0, len(edit.Changes))
+	for uri := range edit.Changes {
+		uris = append(uris, string(uri))
+	}
+	sort.Strings(uris)
+	for _, uri := range uris {
+		if err := tx.editFile(protocol.DocumentUri(uri), edit.Changes[protocol.DocumentUri(uri)]); err != nil {
+			return fmt.Errorf("failed to apply text edits: %w", err)
+		}
+	}
+
+	// Handle DocumentChanges field
+	for _, change := range edit.DocumentChanges {
+		if err := tx.documentChange(change); err != nil {
+			return fmt.Errorf("failed to apply document change: %w", err)
+		}
+	}
+
+	if err := tx.commit(); err != nil {
+		return err
+	}
+	recordEdit(description, tx)
+	return nil
+}
+
+// load returns the staged state of a file, reading it on first use. Files
+// the sandbox does not allow to be modified are rejected.
+func (tx *workspaceTransaction) load(path string) (*stagedFile, error) {
+	if file, ok := tx.files[path]; ok {
+		return file, nil
+	}
+	if err := CheckWriteAccess(path); err != nil {
+		return nil, err
+	}
+
+	file := &stagedFile{mode: 0644}
+	if source, ok := tx.sourcePath(path); ok {
+		content, err := osReadFile(source)
+		switch {
+		case err == nil:
+			file.original = content
+			file.originalExists = true
+			if info, err := osStat(source); err == nil && info.Mode().Perm() != 0 {
+				file.mode = info.Mode().Perm()
+			}
+		case !errors.Is(err, os.ErrNotExist):
+			return nil, fmt.Errorf("failed to read file: %w", err)
+		}
+	}
+	file.content = file.original
+	file.exists = file.originalExists
+
+	tx.files[path] = file
+	return file, nil
+}
+
+// sourcePath returns where the file at path is before the edit, undoing the
+// directory changes staged so far. It reports false for paths inside a
+// deleted directory, which no longer exist.
+func (tx *workspaceTransaction) sourcePath(path string) (string, bool) {
+	for i := len(tx.directories) - 1; i >= 0; i-- {
+		dir := tx.directories[i]
+		if dir.deleted {
+			if isWithin(dir.oldPath, path) {
+				return "", false
+			}
+		} else if isWithin(dir.newPath, path) {
+			rel, _ := filepath.Rel(dir.newPath, path)
+			path = filepath.Join(dir.oldPath, rel)
+		}
+	}
+	return path, true
+}
+
+// moveDirectory stages a directory change, moving the files staged inside
+// the directory along with it
+func (tx *workspaceTransaction) moveDirectory(change directoryChange) {
+	moved := make(map[string]*stagedFile)
+	for path, file := range tx.files {
+		if isWithin(change.oldPath, path) {
+			rel, _ := filepath.Rel(change.oldPath, path)
+			moved[filepath.Join(change.newPath, rel)] = file
+			delete(tx.files, path)
+		}
+	}
+	for path, file := range moved {
+		tx.files[path] = file
+	}
+	tx.directories = append(tx.directories, change)
+}
+
+// exists reports whether a path exists, taking staged changes into account
+func (tx *workspaceTransaction) exists(path string) bool {
+	if file, ok := tx.files[path]; ok {
+		return file.exists
+	}
+	source, ok := tx.sourcePath(path)
+	if !ok {
+		return false
+	}
+	_, err := osStat(source)
+	return err == nil
+}
+
+// isDir reports whether a path is an existing directory, taking staged
+// directory changes into account
+func (tx *workspaceTransaction) isDir(path string) bool {
+	source, ok := tx.sourcePath(path)
+	if !ok {
+		return false
+	}
+	info, err := osStat(source)
+	return err == nil && info.IsDir()
+}
+
+func (tx *workspaceTransaction) editFile(uri protocol.DocumentUri, edits []protocol.TextEdit) error {
+	path := strings.TrimPrefix(string(uri), "file://")
+	file, err := tx.load(path)
+	if err != nil {
+		return err
+	}
+	if !file.exists {
+		return fmt.Errorf("failed to read file: %s: %w", path, os.ErrNotExist)
+	}
+
+	content, err := applyTextEditsToContent(file.content, edits)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	file.content = content
+	return nil
+}
+
+func (tx *workspaceTransaction) documentChange(change protocol.DocumentChange) error {
+	if change.CreateFile != nil {
+		path := strings.TrimPrefix(string(change.CreateFile.URI), "file://")
+		if options := change.CreateFile.Options; options != nil && !options.Overwrite && options.IgnoreIfExists && tx.exists(path) {
+			return nil // File exists and we're ignoring it
+		}
+		file, err := tx.load(path)
+		if err != nil {
+			return fmt.Errorf("failed to create file: %w", err)
+		}
+		file.content = []byte("")
+		file.exists = true
+	}
+
+	if change.DeleteFile != nil {
+		path := strings.TrimPrefix(string(change.DeleteFile.URI), "file://")
+		recursive := change.DeleteFile.Options != nil && change.DeleteFile.Options.Recursive
+		if recursive && tx.isDir(path) {
+			if err := CheckWriteAccess(path); err != nil {
+				return fmt.Errorf("failed to delete directory: %w", err)
+			}
+			tx.moveDirectory(directoryChange{oldPath: path, newPath: path + ".mcp-edit-deleted", deleted: true})
+		} else {
+			file, err := tx.load(path)
+			if err != nil {
+				return fmt.Errorf("failed to delete file: %w", err)
+			}
+			if !file.exists {
+				return fmt.Errorf("failed to delete file: %s: %w", path, os.ErrNotExist)
+			}
+			file.content = nil
+			file.exists = false
+		}
+	}
+
+	if change.RenameFile != nil {
+		oldPath := strings.TrimPrefix(string(change.RenameFile.OldURI), "file://")
+		newPath := strings.TrimPrefix(string(change.RenameFile.NewURI), "file://")
+		if change.RenameFile.Options != nil && !change.RenameFile.Options.Overwrite && tx.exists(newPath) {
+			return fmt.Errorf("target file already exists and overwrite is not allowed: %s", newPath)
+		}
+
+		if tx.isDir(oldPath) {
+			for _, path := range []string{oldPath, newPath} {
+				if err := CheckWriteAccess(path); err != nil {
+					return fmt.Errorf("failed to rename directory: %w", err)
+				}
+			}
+			tx.moveDirectory(directoryChange{oldPath: oldPath, newPath: newPath})
+		} else {
+			oldFile, err := tx.load(oldPath)
+			if err != nil {
+				return fmt.Errorf("failed to rename file: %w", err)
+			}
+			if !oldFile.exists {
+				return fmt.Errorf("failed to rename file: %s: %w", oldPath, os.ErrNotExist)
+			}
+			newFile, err := tx.load(newPath)
+			if err != nil {
+				return fmt.Errorf("failed to rename file: %w", err)
+			}
+			newFile.content = oldFile.content
+			newFile.exists = true
+			newFile.mode = oldFile.mode
+			oldFile.content = nil
+			oldFile.exists = false
+		}
+	}
+
+	if change.TextDocumentEdit != nil {
+		textEdits := make([]protocol.TextEdit, len(change.TextDocumentEdit.Edits))
+		for i, edit := range change.TextDocumentEdit.Edits {
+			var err error
+			textEdits[i], err = edit.AsTextEdit()
+			if err != nil {
+				return fmt.Errorf("invalid edit type: %w", err)
+			}
+		}
+		return tx.editFile(change.TextDocumentEdit.TextDocument.URI, textEdits)
+	}
+
+	return nil
+}
+
+// stagingPath returns the temporary file new content for path is written to
+// before it is renamed into place
+func stagingPath(path string) string {
+	return filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".mcp-edit.tmp")
+}
+
+// commit writes the staged changes to disk, restoring every file it already
+// changed if a later step fails
+func (tx *workspaceTransaction) commit() error {
+	paths := make([]string, 0, len(tx.files))
+	for path, file := range tx.files {
+		if file.changed() {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	var staged []string
+	removeStaged := func() {
+		for _, path := range staged {
+			_ = osRemove(stagingPath(path))
+		}
+	}
+	var undo []func() error
+	rollback := func(cause error) error {
+		removeStaged()
+		var failures []string
+		for i := len(undo) - 1; i >= 0; i-- {
+			if err := undo[i](); err != nil {
+				failures = append(failures, err.Error())
+			}
+		}
+		if len(failures) > 0 {
+			coreLogger.Error("Failed to roll back workspace edit: %s", strings.Join(failures, "; "))
+			return fmt.Errorf("%w (rollback failed: %s)", cause, strings.Join(failures, "; "))
+		}
+		return fmt.Errorf("%w (all changes were rolled back)", cause)
+	}
+
+	// Directories are moved first, since staged files are keyed by where
+	// they end up
+	for _, dir := range tx.directories {
+		if err := osRename(dir.oldPath, dir.newPath); err != nil {
+			return rollback(fmt.Errorf("failed to rename %s: %w", dir.oldPath, err))
+		}
+		undo = append(undo, func() error { return osRename(dir.newPath, dir.oldPath) })
+	}
+
+	// Stage all new content before touching any file
+	for _, path := range paths {
+		file := tx.files[path]
+		if !file.exists {
+			continue
+		}
+		if err := osWriteFile(stagingPath(path), file.content, file.mode); err != nil {
+			return rollback(fmt.Errorf("failed to write file: %s: %w", path, err))
+		}
+		staged = append(staged, path)
+	}
+
+	for _, path := range paths {
+		file := tx.files[path]
+		var err error
+		if file.exists {
+			err = osRename(stagingPath(path), path)
+		} else {
+			err = osRemove(path)
+		}
+		if err != nil {
+			return rollback(fmt.Errorf("failed to write file: %s: %w", path, err))
+		}
+
+		undo = append(undo, func() error {
+			if file.originalExists {
+				return osWriteFile(path, file.original, file.mode)
+			}
+			return osRemove(path)
+		})
+	}
+
+	// Deleted directories can only be removed once nothing can be rolled back
+	for _, dir := range tx.directories {
+		if dir.deleted {
+			if err := osRemoveAll(dir.newPath); err != nil {
+				coreLogger.Warn("Failed to remove deleted directory %s: %v", dir.newPath, err)
+			}
+		}
+	}
+
+	return nil
+}
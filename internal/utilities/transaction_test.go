@@ -0,0 +1,119 @@
+package utilities
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+)
+
+// textDocumentEdit replaces the first line of the file at path
+func textDocumentEdit(path, text string) protocol.DocumentChange {
+	return protocol.DocumentChange{TextDocumentEdit: &protocol.TextDocumentEdit{
+		TextDocument: protocol.OptionalVersionedTextDocumentIdentifier{
+			TextDocumentIdentifier: protocol.TextDocumentIdentifier{URI: protocol.URIFromPath(path)},
+		},
+		Edits: []protocol.Or_TextDocumentEdit_edits_Elem{{Value: protocol.TextEdit{
+			Range:   protocol.Range{End: protocol.Position{Line: 1}},
+			NewText: text,
+		}}},
+	}}
+}
+
+func renameFile(oldPath, newPath string) protocol.DocumentChange {
+	return protocol.DocumentChange{RenameFile: &protocol.RenameFile{
+		OldURI: protocol.URIFromPath(oldPath),
+		NewURI: protocol.URIFromPath(newPath),
+	}}
+}
+
+func TestApplyWorkspaceEditDirectoryRename(t *testing.T) {
+	editHistory.records = nil
+
+	tests := []struct {
+		name    string
+		changes func(oldDir, newDir string) []protocol.DocumentChange
+	}{
+		{
+			name: "edit after the rename",
+			changes: func(oldDir, newDir string) []protocol.DocumentChange {
+				return []protocol.DocumentChange{
+					renameFile(oldDir, newDir),
+					textDocumentEdit(filepath.Join(newDir, "a.go"), "package b\n"),
+				}
+			},
+		},
+		{
+			name: "edit before the rename",
+			changes: func(oldDir, newDir string) []protocol.DocumentChange {
+				return []protocol.DocumentChange{
+					textDocumentEdit(filepath.Join(oldDir, "a.go"), "package b\n"),
+					renameFile(oldDir, newDir),
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			oldDir := filepath.Join(root, "a")
+			newDir := filepath.Join(root, "b")
+			if err := os.MkdirAll(filepath.Join(oldDir, "sub"), 0755); err != nil {
+				t.Fatal(err)
+			}
+			for name, content := range map[string]string{"a.go": "package a\n", "sub/c.go": "package sub\n"} {
+				if err := os.WriteFile(filepath.Join(oldDir, name), []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			changes := append(tt.changes(oldDir, newDir),
+				textDocumentEdit(filepath.Join(newDir, "sub", "c.go"), "package renamed\n"))
+			if err := ApplyWorkspaceEdit(protocol.WorkspaceEdit{DocumentChanges: changes}, "test"); err != nil {
+				t.Fatalf("ApplyWorkspaceEdit failed: %v", err)
+			}
+
+			if _, err := os.Stat(oldDir); !os.IsNotExist(err) {
+				t.Errorf("Old directory still exists: %v", err)
+			}
+			for name, want := range map[string]string{"a.go": "package b\n", "sub/c.go": "package renamed\n"} {
+				content, err := os.ReadFile(filepath.Join(newDir, name))
+				if err != nil {
+					t.Errorf("Renamed file %s not found: %v", name, err)
+				} else if string(content) != want {
+					t.Errorf("Content of %s = %q, want %q", name, content, want)
+				}
+			}
+		})
+	}
+}
+
+func TestApplyWorkspaceEditDirectoryRenameRollback(t *testing.T) {
+	root := t.TempDir()
+	oldDir := filepath.Join(root, "a")
+	newDir := filepath.Join(root, "b")
+	if err := os.Mkdir(oldDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(oldDir, "a.go"), []byte("package a\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The edit to the missing file fails after the rename was staged
+	err := ApplyWorkspaceEdit(protocol.WorkspaceEdit{DocumentChanges: []protocol.DocumentChange{
+		renameFile(oldDir, newDir),
+		textDocumentEdit(filepath.Join(newDir, "a.go"), "package b\n"),
+		textDocumentEdit(filepath.Join(newDir, "missing.go"), "package b\n"),
+	}}, "test")
+	if err == nil {
+		t.Fatal("Expected an error for the missing file")
+	}
+
+	if _, err := os.Stat(newDir); !os.IsNotExist(err) {
+		t.Errorf("Directory was renamed despite the failed edit: %v", err)
+	}
+	if content, _ := os.ReadFile(filepath.Join(oldDir, "a.go")); string(content) != "package a\n" {
+		t.Errorf("File was modified despite the failed edit: %q", content)
+	}
+}
@@ -33,8 +33,16 @@ type WorkspaceWatcher struct {
 
 	// Gitignore matcher
 	gitignore *GitignoreMatcher
+
+	// Handler notified about file events in the workspace
+	fileEventHandler   FileEventHandler
+	fileEventHandlerMu sync.RWMutex
 }
 
+// FileEventHandler is called for every create, change or delete of a file in
+// the workspace that is not excluded, whether or not the server watches it
+type FileEventHandler func(path string, changeType protocol.FileChangeType)
+
 // NewWorkspaceWatcher creates a new workspace watcher with default configuration
 func NewWorkspaceWatcher(client LSPClient) *WorkspaceWatcher {
 	return NewWorkspaceWatcherWithConfig(client, DefaultWatcherConfig())
@@ -50,6 +58,13 @@ func NewWorkspaceWatcherWithConfig(client LSPClient, config *WatcherConfig) *Wor
 	}
 }
 
+// RegisterFileEventHandler registers a handler for file events in the workspace
+func (w *WorkspaceWatcher) RegisterFileEventHandler(handler FileEventHandler) {
+	w.fileEventHandlerMu.Lock()
+	defer w.fileEventHandlerMu.Unlock()
+	w.fileEventHandler = handler
+}
+
 // AddRegistrations adds file watchers to track
 func (w *WorkspaceWatcher) AddRegistrations(ctx context.Context, id string, watchers []protocol.FileSystemWatcher) {
 	w.registrationMu.Lock()
@@ -273,6 +288,8 @@ func (w *WorkspaceWatcher) WatchWorkspace(ctx context.Context, workspacePath str
 				continue
 			}
 
+			w.notifyFileEventHandler(event, isFile)
+
 			// Check if this path should be watched according to server registrations
 			if watched, watchKind := w.isPathWatched(event.Name); watched {
 				switch {
@@ -314,6 +331,25 @@ func (w *WorkspaceWatcher) WatchWorkspace(ctx context.Context, workspacePath str
 	}
 }
 
+// notifyFileEventHandler passes a file event to the registered handler, if any
+func (w *WorkspaceWatcher) notifyFileEventHandler(event fsnotify.Event, isFile bool) {
+	w.fileEventHandlerMu.RLock()
+	handler := w.fileEventHandler
+	w.fileEventHandlerMu.RUnlock()
+	if handler == nil {
+		return
+	}
+
+	switch {
+	case event.Op&fsnotify.Write != 0 && isFile:
+		handler(event.Name, protocol.FileChangeType(protocol.Changed))
+	case event.Op&fsnotify.Create != 0 && isFile:
+		handler(event.Name, protocol.FileChangeType(protocol.Created))
+	case event.Op&(fsnotify.Remove|fsnotify.Rename) != 0:
+		handler(event.Name, protocol.FileChangeType(protocol.Deleted))
+	}
+}
+
 // isPathWatched checks if a path should be watched based on server registrations
 func (w *WorkspaceWatcher) isPathWatched(path string) (bool, protocol.WatchKind) {
 	w.registrationMu.RLock()
@@ -2,8 +2,11 @@ package main
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"flag"
 	"fmt"
+	"net/http"
 	"os"
 	"os/exec"
 	"os/signal"
@@ -15,6 +18,7 @@ import (
 	"github.com/bmatcuk/doublestar/v4"
 	"github.com/isaacphi/mcp-language-server/internal/logging"
 	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
 	"github.com/isaacphi/mcp-language-server/internal/watcher"
 	"github.com/mark3labs/mcp-go/server"
 )
@@ -32,6 +36,29 @@ type config struct {
 	host      string // network interface for network transports
 	port      int    // network port for network transports
 	endpoint  string // HTTP endpoint path for http transport
+	// Network transport security
+	authTokenFile  string          // file holding the bearer token, overrides MCP_AUTH_TOKEN
+	authToken      string          // bearer token required on every request, empty to disable
+	tlsCert        string          // TLS certificate file
+	tlsKey         string          // TLS private key file
+	tlsClientCA    string          // CA bundle used to require and verify client certificates
+	allowedOrigins StringArrayFlag // Origin header values allowed to connect
+	// Only expose tools that do not modify the workspace
+	readOnly bool
+	// Directory the edit history is kept in
+	editHistoryDir string
+	// How edits requested by the language server are handled
+	applyEditPolicy string
+	// Directories outside the workspace that tools may read but not modify
+	readOnlyRoots       StringArrayFlag
+	detectReadOnlyRoots bool
+	// Show paths in tool output relative to the workspace
+	relativePaths bool
+	// Default output budget of each tool call, 0 for no limit
+	maxOutputTokens int
+	// Test commands by language, for servers without test lenses
+	testCommandFlags StringArrayFlag
+	testCommands     map[string]string
 }
 
 type mcpServer struct {
@@ -41,6 +68,8 @@ type mcpServer struct {
 	ctx              context.Context
 	cancelFunc       context.CancelFunc
 	workspaceWatcher *watcher.WorkspaceWatcher
+	subscriptions    *resourceSubscriptions
+	sessions         *clientSessions
 }
 
 // StringArrayFlag is a custom flag type to handle an array of strings
@@ -67,6 +96,20 @@ func parseConfig() (*config, error) {
 	flag.StringVar(&cfg.host, "host", "localhost", "Host address for network transports")
 	flag.IntVar(&cfg.port, "port", 8080, "Port for network transports")
 	flag.StringVar(&cfg.endpoint, "endpoint", "/mcp", "HTTP endpoint path for http transport")
+	// Security flags
+	flag.StringVar(&cfg.authTokenFile, "auth-token-file", "", "File containing the bearer token required by network transports (default: MCP_AUTH_TOKEN environment variable)")
+	flag.StringVar(&cfg.tlsCert, "tls-cert", "", "TLS certificate file for network transports")
+	flag.StringVar(&cfg.tlsKey, "tls-key", "", "TLS private key file for network transports")
+	flag.StringVar(&cfg.tlsClientCA, "tls-client-ca", "", "CA bundle for verifying client certificates (enables mTLS)")
+	flag.Var(&cfg.allowedOrigins, "allowed-origin", "Origin allowed to connect to network transports (can specify more than once)")
+	flag.BoolVar(&cfg.readOnly, "read-only", false, "Only expose tools that do not modify files")
+	flag.StringVar(&cfg.applyEditPolicy, "apply-edit-policy", string(lsp.ApplyEditWorkspace), "How edits requested by the language server are handled: allow, deny, workspace (only inside the workspace) or queue (wait for approval with the pending_edits tool)")
+	flag.StringVar(&cfg.editHistoryDir, "edit-history-dir", "", "Directory to keep the undo history of edits in (default: a per-workspace directory in the user cache directory)")
+	flag.Var(&cfg.readOnlyRoots, "read-only-root", "Directory outside the workspace that tools may read but not modify, such as a dependency cache (can specify more than once)")
+	flag.BoolVar(&cfg.detectReadOnlyRoots, "detect-read-only-roots", true, "Add GOROOT, the Go module cache and the Rust toolchain and registry directories to the read-only roots")
+	flag.BoolVar(&cfg.relativePaths, "relative-paths", false, "Show paths in tool output relative to the workspace, and dependency paths under labels such as $GOROOT or <node_modules>")
+	flag.IntVar(&cfg.maxOutputTokens, "max-output-tokens", 10000, "Default budget for the output of each tool call, estimated at 4 characters per token. Longer output is split into pages or truncated. 0 disables the limit")
+	flag.Var(&cfg.testCommandFlags, "test-command", "Command run_tests uses for a language the server has no test lens for, as language=command, such as python='pytest -x {ids}'. Placeholders: {file}, {dir}, {tests}, {ids} and {pattern} (can specify more than once)")
 	flag.Parse()
 
 	// Get remaining args after -- as LSP arguments
@@ -114,15 +157,57 @@ func parseConfig() (*config, error) {
 		}
 	}
 
+	if err := validateSecurityConfig(cfg); err != nil {
+		return nil, err
+	}
+
+	if _, err := lsp.ParseApplyEditPolicy(cfg.applyEditPolicy); err != nil {
+		return nil, err
+	}
+
+	if cfg.maxOutputTokens < 0 {
+		return nil, fmt.Errorf("invalid max-output-tokens: %d (must be 0 or more)", cfg.maxOutputTokens)
+	}
+
+	cfg.testCommands = make(map[string]string)
+	for _, value := range cfg.testCommandFlags {
+		language, command, ok := strings.Cut(value, "=")
+		language = strings.TrimPrefix(strings.TrimSpace(language), ".")
+		if !ok || language == "" {
+			return nil, fmt.Errorf("invalid test-command: %s (must be language=command)", value)
+		}
+		cfg.testCommands[language] = command
+	}
+
+	for i, root := range cfg.readOnlyRoots {
+		absRoot, err := filepath.Abs(root)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get absolute path for read-only root: %v", err)
+		}
+		cfg.readOnlyRoots[i] = absRoot
+	}
+
+	if cfg.editHistoryDir == "" {
+		cacheDir, err := os.UserCacheDir()
+		if err != nil {
+			coreLogger.Warn("No cache directory for the edit history, it will not survive restarts: %v", err)
+		} else {
+			sum := sha256.Sum256([]byte(cfg.workspaceDir))
+			cfg.editHistoryDir = filepath.Join(cacheDir, "mcp-language-server", "history", hex.EncodeToString(sum[:8]))
+		}
+	}
+
 	return cfg, nil
 }
 
 func newServer(config *config) (*mcpServer, error) {
 	ctx, cancel := context.WithCancel(context.Background())
 	return &mcpServer{
-		config:     *config,
-		ctx:        ctx,
-		cancelFunc: cancel,
+		config:        *config,
+		ctx:           ctx,
+		cancelFunc:    cancel,
+		subscriptions: newResourceSubscriptions(),
+		sessions:      newClientSessions(),
 	}, nil
 }
 
@@ -137,6 +222,7 @@ func (s *mcpServer) initializeLSP() error {
 	}
 	s.lspClient = client
 	s.workspaceWatcher = watcher.NewWorkspaceWatcher(client)
+	client.SetApplyEditPolicy(lsp.ApplyEditPolicy(s.config.applyEditPolicy), s.config.workspaceDir)
 
 	initResult, err := client.InitializeLSPClient(s.ctx, s.config.workspaceDir)
 	if err != nil {
@@ -184,22 +270,54 @@ func (s *mcpServer) openInitialFiles() {
 }
 
 func (s *mcpServer) start() error {
-	if err := s.initializeLSP(); err != nil {
-		return err
+	if err := s.setupWorkspacePaths(); err != nil {
+		return fmt.Errorf("failed to set up the workspace sandbox: %v", err)
+	}
+
+	if s.config.editHistoryDir != "" {
+		if err := utilities.OpenEditHistory(s.config.editHistoryDir); err != nil {
+			coreLogger.Warn("Edit history will only be kept in memory: %v", err)
+		}
+	}
+
+	hooks := &server.Hooks{}
+	s.addSessionHooks(hooks)
+	s.addSubscriptionHooks(hooks)
+
+	serverOptions := []server.ServerOption{
+		server.WithLogging(),
+		server.WithRecovery(),
+		server.WithResourceCapabilities(true, false),
+		server.WithPromptCapabilities(false),
+		server.WithHooks(hooks),
+		server.WithToolHandlerMiddleware(s.progressMiddleware),
+	}
+	if s.config.readOnly {
+		coreLogger.Info("Read-only mode: tools that modify files are disabled")
+		serverOptions = append(serverOptions, server.WithToolFilter(readOnlyToolFilter))
 	}
 
 	s.mcpServer = server.NewMCPServer(
 		"MCP Language Server",
 		"v0.0.2",
-		server.WithLogging(),
-		server.WithRecovery(),
+		serverOptions...,
 	)
 
+	// Forward server messages and progress from the moment the server starts
+	s.registerNotificationForwarding()
+
+	if err := s.initializeLSP(); err != nil {
+		return err
+	}
+
 	err := s.registerTools()
 	if err != nil {
 		return fmt.Errorf("tool registration failed: %v", err)
 	}
 
+	s.registerResources()
+	s.registerPrompts()
+
 	// Start the appropriate transport
 	switch s.config.transport {
 	case "stdio", "":
@@ -208,15 +326,20 @@ func (s *mcpServer) start() error {
 	case "sse":
 		addr := fmt.Sprintf("%s:%d", s.config.host, s.config.port)
 		coreLogger.Info("Starting MCP server with SSE transport on %s", addr)
-		sseServer := server.NewSSEServer(s.mcpServer)
-		return sseServer.Start(addr)
+		sseServer := server.NewSSEServer(s.mcpServer,
+			server.WithSSECORS(server.WithCORSAllowedOrigins(s.config.allowedOrigins...)),
+		)
+		return s.serveHTTP(addr, sseServer)
 	case "http":
 		addr := fmt.Sprintf("%s:%d", s.config.host, s.config.port)
 		coreLogger.Info("Starting MCP server with StreamableHTTP transport on %s%s", addr, s.config.endpoint)
 		httpServer := server.NewStreamableHTTPServer(s.mcpServer,
 			server.WithEndpointPath(s.config.endpoint),
+			server.WithStreamableHTTPCORS(server.WithCORSAllowedOrigins(s.config.allowedOrigins...)),
 		)
-		return httpServer.Start(addr)
+		mux := http.NewServeMux()
+		mux.Handle(s.config.endpoint, httpServer)
+		return s.serveHTTP(addr, mux)
 	default:
 		return fmt.Errorf("unsupported transport type: %s", s.config.transport)
 	}
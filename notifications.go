@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// progressCall is a running tool call that asked for progress notifications
+type progressCall struct {
+	sessionID string
+	// prefix starts the work done tokens of the requests the call sends
+	prefix string
+	token  mcp.ProgressToken
+	// progress must increase with every notification sent for the token
+	progress float64
+}
+
+// clientSessions tracks the connected MCP sessions and the tool calls that
+// receive language server progress
+type clientSessions struct {
+	mu       sync.Mutex
+	sessions map[string]struct{}
+	calls    map[*progressCall]struct{}
+	// started counts calls, to give each a unique token prefix
+	started int
+}
+
+func newClientSessions() *clientSessions {
+	return &clientSessions{
+		sessions: make(map[string]struct{}),
+		calls:    make(map[*progressCall]struct{}),
+	}
+}
+
+func (c *clientSessions) add(sessionID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sessions[sessionID] = struct{}{}
+}
+
+func (c *clientSessions) remove(sessionID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.sessions, sessionID)
+	for call := range c.calls {
+		if call.sessionID == sessionID {
+			delete(c.calls, call)
+		}
+	}
+}
+
+// ids returns the IDs of the connected sessions
+func (c *clientSessions) ids() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ids := make([]string, 0, len(c.sessions))
+	for id := range c.sessions {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func (c *clientSessions) startCall(sessionID string, token mcp.ProgressToken) *progressCall {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.started++
+	call := &progressCall{sessionID: sessionID, prefix: fmt.Sprintf("mcp-call-%d/", c.started), token: token}
+	c.calls[call] = struct{}{}
+	return call
+}
+
+func (c *clientSessions) finishCall(call *progressCall) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.calls, call)
+}
+
+// nextProgress advances the progress of the calls that progress on token is
+// for and returns a snapshot to send notifications for. Progress on a token
+// sent with a call's request goes to that call only. Progress the server
+// starts by itself, such as loading packages after startup, is not caused by
+// any one request but holds up all of them, so it goes to every running call.
+func (c *clientSessions) nextProgress(token string) []progressCall {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var calls []progressCall
+	for call := range c.calls {
+		if strings.HasPrefix(token, call.prefix) {
+			call.progress++
+			return []progressCall{*call}
+		}
+	}
+	if strings.HasPrefix(token, "mcp-call-") {
+		// The call that sent the request has finished
+		return nil
+	}
+	for call := range c.calls {
+		call.progress++
+		calls = append(calls, *call)
+	}
+	return calls
+}
+
+// progressCallKey is the context key of the progressCall of a tool call
+type progressCallKey struct{}
+
+// callContext returns the context a tool call's work runs in. It is the
+// server context, so shutdown cancels the work, and tags the requests the
+// call sends so their progress reaches that call only.
+func (s *mcpServer) callContext(ctx context.Context) context.Context {
+	if call, ok := ctx.Value(progressCallKey{}).(*progressCall); ok {
+		return lsp.WithProgressPrefix(s.ctx, call.prefix)
+	}
+	return s.ctx
+}
+
+// addSessionHooks keeps clientSessions in sync with connecting and disconnecting clients
+func (s *mcpServer) addSessionHooks(hooks *server.Hooks) {
+	hooks.AddOnRegisterSession(func(ctx context.Context, session server.ClientSession) {
+		s.sessions.add(session.SessionID())
+	})
+	hooks.AddOnUnregisterSession(func(ctx context.Context, session server.ClientSession) {
+		s.sessions.remove(session.SessionID())
+	})
+}
+
+// progressMiddleware makes a tool call that carries a progress token receive
+// language server progress while it runs
+func (s *mcpServer) progressMiddleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		session := server.ClientSessionFromContext(ctx)
+		if session == nil || request.Params.Meta == nil || request.Params.Meta.ProgressToken == nil {
+			return next(ctx, request)
+		}
+
+		call := s.sessions.startCall(session.SessionID(), request.Params.Meta.ProgressToken)
+		defer s.sessions.finishCall(call)
+		return next(context.WithValue(ctx, progressCallKey{}, call), request)
+	}
+}
+
+// registerNotificationForwarding passes language server messages and progress
+// on to MCP clients as logging and progress notifications
+func (s *mcpServer) registerNotificationForwarding() {
+	serverName := filepath.Base(s.config.lspCommand)
+
+	lsp.RegisterMessageHandler(func(msgType protocol.MessageType, message string) {
+		notification := mcp.NewLoggingMessageNotification(loggingLevel(msgType), serverName, message)
+		for _, sessionID := range s.sessions.ids() {
+			// Fails for sessions that do not support logging, which is fine
+			if err := s.mcpServer.SendLogMessageToSpecificClient(sessionID, notification); err != nil {
+				coreLogger.Debug("Not forwarding server message to session %s: %v", sessionID, err)
+			}
+		}
+	})
+
+	lsp.RegisterProgressHandler(func(progress lsp.WorkDoneProgress, done bool) {
+		message := formatProgress(serverName, progress, done)
+		for _, call := range s.sessions.nextProgress(progress.Token) {
+			err := s.mcpServer.SendNotificationToSpecificClient(call.sessionID, string(mcp.MethodNotificationProgress), map[string]any{
+				"progressToken": call.token,
+				"progress":      call.progress,
+				"message":       message,
+			})
+			if err != nil {
+				coreLogger.Warn("Failed to send progress to session %s: %v", call.sessionID, err)
+			}
+		}
+	})
+}
+
+// formatProgress renders work done progress as a single line, such as
+// "gopls: Loading packages 40%"
+func formatProgress(serverName string, progress lsp.WorkDoneProgress, done bool) string {
+	parts := []string{serverName + ":"}
+	if progress.Title != "" {
+		parts = append(parts, progress.Title)
+	}
+	if progress.Message != "" {
+		parts = append(parts, progress.Message)
+	}
+	if done {
+		parts = append(parts, "(done)")
+	} else if progress.Percentage != nil {
+		parts = append(parts, fmt.Sprintf("%d%%", *progress.Percentage))
+	}
+	return strings.Join(parts, " ")
+}
+
+// loggingLevel maps an LSP message type to the MCP logging level
+func loggingLevel(msgType protocol.MessageType) mcp.LoggingLevel {
+	switch msgType {
+	case protocol.Error:
+		return mcp.LoggingLevelError
+	case protocol.Warning:
+		return mcp.LoggingLevelWarning
+	case protocol.Info:
+		return mcp.LoggingLevelInfo
+	default:
+		return mcp.LoggingLevelDebug
+	}
+}
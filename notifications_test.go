@@ -0,0 +1,94 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestFormatProgress(t *testing.T) {
+	percentage := uint32(40)
+	tests := []struct {
+		name     string
+		progress lsp.WorkDoneProgress
+		done     bool
+		want     string
+	}{
+		{"title only", lsp.WorkDoneProgress{Title: "Loading packages"}, false, "gopls: Loading packages"},
+		{"percentage", lsp.WorkDoneProgress{Title: "Loading packages", Percentage: &percentage}, false, "gopls: Loading packages 40%"},
+		{"message", lsp.WorkDoneProgress{Title: "Indexing", Message: "3/7 files"}, false, "gopls: Indexing 3/7 files"},
+		{"done", lsp.WorkDoneProgress{Title: "Loading packages", Message: "finished", Percentage: &percentage}, true, "gopls: Loading packages finished (done)"},
+		{"empty", lsp.WorkDoneProgress{}, false, "gopls:"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatProgress("gopls", tt.progress, tt.done); got != tt.want {
+				t.Errorf("formatProgress() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoggingLevel(t *testing.T) {
+	tests := []struct {
+		msgType protocol.MessageType
+		want    mcp.LoggingLevel
+	}{
+		{protocol.Error, mcp.LoggingLevelError},
+		{protocol.Warning, mcp.LoggingLevelWarning},
+		{protocol.Info, mcp.LoggingLevelInfo},
+		{protocol.Log, mcp.LoggingLevelDebug},
+		{protocol.MessageType(42), mcp.LoggingLevelDebug},
+	}
+	for _, tt := range tests {
+		if got := loggingLevel(tt.msgType); got != tt.want {
+			t.Errorf("loggingLevel(%v) = %q, want %q", tt.msgType, got, tt.want)
+		}
+	}
+}
+
+func TestProgressCalls(t *testing.T) {
+	sessions := newClientSessions()
+	sessions.add("a")
+	sessions.add("b")
+
+	first := sessions.startCall("a", "first-token")
+	second := sessions.startCall("b", "second-token")
+	if first.prefix == second.prefix {
+		t.Fatalf("calls share the token prefix %q", first.prefix)
+	}
+
+	// Progress on a request's token reaches only the call that sent it
+	calls := sessions.nextProgress(second.prefix + "7")
+	if len(calls) != 1 || calls[0].token != "second-token" || calls[0].progress != 1 {
+		t.Errorf("progress for the second call went to %+v", calls)
+	}
+
+	// Progress the server started by itself reaches every call
+	calls = sessions.nextProgress("server-token")
+	if len(calls) != 2 {
+		t.Fatalf("server progress went to %d calls, want 2", len(calls))
+	}
+	for _, call := range calls {
+		want := map[mcp.ProgressToken]float64{"first-token": 1, "second-token": 2}[call.token]
+		if call.progress != want {
+			t.Errorf("progress of %v = %v, want %v", call.token, call.progress, want)
+		}
+	}
+
+	sessions.finishCall(second)
+	if calls := sessions.nextProgress(second.prefix + "8"); len(calls) != 0 {
+		t.Errorf("progress for a finished call went to %+v", calls)
+	}
+	calls = sessions.nextProgress("server-token")
+	if len(calls) != 1 || calls[0].token != "first-token" || calls[0].progress != 2 {
+		t.Errorf("server progress after the second call finished went to %+v", calls)
+	}
+
+	sessions.remove("a")
+	if calls := sessions.nextProgress("server-token"); len(calls) != 0 {
+		t.Errorf("progress went to calls of a removed session: %+v", calls)
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/tools"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// promptSection runs a tool and renders its output under a heading. Tool
+// failures are included in the prompt instead of failing it, since the other
+// sections are still useful.
+func promptSection(output *strings.Builder, heading string, run func() (string, error)) {
+	output.WriteString(fmt.Sprintf("## %s\n\n", heading))
+	text, err := run()
+	if err != nil {
+		coreLogger.Warn("Prompt section %q failed: %v", heading, err)
+		output.WriteString(fmt.Sprintf("Not available: %v\n\n", err))
+		return
+	}
+	output.WriteString(strings.TrimRight(text, "\n"))
+	output.WriteString("\n\n")
+}
+
+// requirePromptArgument returns a prompt argument or an error if it is missing
+func requirePromptArgument(request mcp.GetPromptRequest, name string) (string, error) {
+	value := strings.TrimSpace(request.Params.Arguments[name])
+	if value == "" {
+		return "", fmt.Errorf("required argument %q not found", name)
+	}
+	return value, nil
+}
+
+func (s *mcpServer) registerPrompts() {
+	coreLogger.Debug("Registering MCP prompts")
+
+	explainSymbolPrompt := mcp.NewPrompt("explain_symbol",
+		mcp.WithPromptDescription("Explain what a symbol does and how it is used, based on its definition, hover information, references and callers."),
+		mcp.WithArgument("symbolName",
+			mcp.RequiredArgument(),
+			mcp.ArgumentDescription("The name of the symbol to explain (e.g. 'mypackage.MyFunction', 'MyType.MyMethod')"),
+		),
+	)
+	s.mcpServer.AddPrompt(explainSymbolPrompt, func(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+		symbolName, err := requirePromptArgument(request, "symbolName")
+		if err != nil {
+			return nil, err
+		}
+
+		coreLogger.Debug("Building explain_symbol prompt for: %s", symbolName)
+
+		var output strings.Builder
+		output.WriteString(fmt.Sprintf("Explain the symbol `%s`: what it does, how it is used across the codebase, and anything surprising about it. "+
+			"Use the language server context below, and the definition, references, hover and callers tools if you need more.\n\n", symbolName))
+		promptSection(&output, "Definition", func() (string, error) {
+			return tools.ReadDefinition(ctx, s.lspClient, symbolName)
+		})
+		promptSection(&output, "Hover", func() (string, error) {
+			return tools.GetSymbolHover(ctx, s.lspClient, symbolName)
+		})
+		promptSection(&output, "References", func() (string, error) {
+			return tools.FindReferences(ctx, s.lspClient, symbolName)
+		})
+		promptSection(&output, "Callers", func() (string, error) {
+			return tools.GetCallers(ctx, s.lspClient, symbolName, 1)
+		})
+
+		return mcp.NewGetPromptResult(
+			fmt.Sprintf("Explain %s", symbolName),
+			[]mcp.PromptMessage{mcp.NewPromptMessage(mcp.RoleUser, mcp.NewTextContent(output.String()))},
+		), nil
+	})
+
+	fixDiagnosticsPrompt := mcp.NewPrompt("fix_diagnostics",
+		mcp.WithPromptDescription("Fix the errors and warnings the language server reports for a file."),
+		mcp.WithArgument("filePath",
+			mcp.RequiredArgument(),
+			mcp.ArgumentDescription("The path to the file to fix, absolute or relative to the workspace"),
+		),
+	)
+	s.mcpServer.AddPrompt(fixDiagnosticsPrompt, func(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+		filePath, err := requirePromptArgument(request, "filePath")
+		if err != nil {
+			return nil, err
+		}
+
+		coreLogger.Debug("Building fix_diagnostics prompt for: %s", filePath)
+
+		var output strings.Builder
+		output.WriteString(fmt.Sprintf("Fix the diagnostics reported for %s. "+
+			"Address the root cause of each one rather than silencing it, make the changes with the edit_file tool, "+
+			"then run the diagnostics tool again to confirm the file is clean.\n\n", filePath))
+		promptSection(&output, "Diagnostics", func() (string, error) {
+			return tools.GetDiagnosticsForFile(ctx, s.lspClient, filePath, 5, true)
+		})
+
+		return mcp.NewGetPromptResult(
+			fmt.Sprintf("Fix diagnostics in %s", filePath),
+			[]mcp.PromptMessage{mcp.NewPromptMessage(mcp.RoleUser, mcp.NewTextContent(output.String()))},
+		), nil
+	})
+
+	safeRenamePrompt := mcp.NewPrompt("safe_rename",
+		mcp.WithPromptDescription("Rename a symbol across the project, checking for conflicts first and verifying the result."),
+		mcp.WithArgument("symbolName",
+			mcp.RequiredArgument(),
+			mcp.ArgumentDescription("The name of the symbol to rename (e.g. 'mypackage.MyFunction', 'MyType.MyMethod')"),
+		),
+		mcp.WithArgument("newName",
+			mcp.RequiredArgument(),
+			mcp.ArgumentDescription("The new name for the symbol"),
+		),
+	)
+	s.mcpServer.AddPrompt(safeRenamePrompt, func(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+		symbolName, err := requirePromptArgument(request, "symbolName")
+		if err != nil {
+			return nil, err
+		}
+		newName, err := requirePromptArgument(request, "newName")
+		if err != nil {
+			return nil, err
+		}
+
+		coreLogger.Debug("Building safe_rename prompt for: %s -> %s", symbolName, newName)
+
+		var output strings.Builder
+		output.WriteString(fmt.Sprintf("Rename `%s` to `%s` safely:\n"+
+			"1. Check that `%s` does not already exist in the same scope, using the definition tool. Stop and report if it does.\n"+
+			"2. Use the rename_symbol tool at the position of the definition below, so that every reference is updated by the language server.\n"+
+			"3. Mentions the language server cannot see, such as strings, comments or documentation, are not renamed. Review and update them with edit_file where appropriate.\n"+
+			"4. Run the diagnostics tool on each file that changed and fix anything the rename broke.\n\n",
+			symbolName, newName, newName))
+		promptSection(&output, "Definition", func() (string, error) {
+			return tools.ReadDefinition(ctx, s.lspClient, symbolName)
+		})
+		promptSection(&output, "References", func() (string, error) {
+			return tools.FindReferences(ctx, s.lspClient, symbolName)
+		})
+
+		return mcp.NewGetPromptResult(
+			fmt.Sprintf("Rename %s to %s", symbolName, newName),
+			[]mcp.PromptMessage{mcp.NewPromptMessage(mcp.RoleUser, mcp.NewTextContent(output.String()))},
+		), nil
+	})
+
+	coreLogger.Info("Successfully registered all MCP prompts")
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/isaacphi/mcp-language-server/internal/lsp"
+	"github.com/isaacphi/mcp-language-server/internal/protocol"
+	"github.com/isaacphi/mcp-language-server/internal/tools"
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Resource URI schemes served by this server
+const (
+	fileScheme        = "file"
+	diagnosticsScheme = "diagnostics"
+	symbolsScheme     = "symbols"
+)
+
+// resourceSubscriptions tracks which sessions subscribed to which resource URIs
+type resourceSubscriptions struct {
+	mu sync.Mutex
+	// subscribed URI -> session IDs
+	sessions map[string]map[string]struct{}
+}
+
+func newResourceSubscriptions() *resourceSubscriptions {
+	return &resourceSubscriptions{sessions: make(map[string]map[string]struct{})}
+}
+
+func (r *resourceSubscriptions) subscribe(uri, sessionID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.sessions[uri] == nil {
+		r.sessions[uri] = make(map[string]struct{})
+	}
+	r.sessions[uri][sessionID] = struct{}{}
+}
+
+func (r *resourceSubscriptions) unsubscribe(uri, sessionID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.sessions[uri], sessionID)
+	if len(r.sessions[uri]) == 0 {
+		delete(r.sessions, uri)
+	}
+}
+
+func (r *resourceSubscriptions) removeSession(sessionID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for uri, sessions := range r.sessions {
+		delete(sessions, sessionID)
+		if len(sessions) == 0 {
+			delete(r.sessions, uri)
+		}
+	}
+}
+
+// matching returns the subscribed URIs, with their sessions, that refer to
+// the given scheme and path
+func (r *resourceSubscriptions) matching(scheme, path string, workspaceDir string) map[string][]string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	matches := make(map[string][]string)
+	for uri, sessions := range r.sessions {
+		uriScheme, uriPath, err := parseResourceURI(uri, workspaceDir)
+		if err != nil || uriScheme != scheme || uriPath != path {
+			continue
+		}
+		for sessionID := range sessions {
+			matches[uri] = append(matches[uri], sessionID)
+		}
+	}
+	return matches
+}
+
+// addSubscriptionHooks keeps resourceSubscriptions in sync with client
+// requests. The subscribe hooks need mcp-go 0.58 or later, which in turn needs
+// Go 1.25.5.
+func (s *mcpServer) addSubscriptionHooks(hooks *server.Hooks) {
+	hooks.AddAfterSubscribe(func(ctx context.Context, id any, message *mcp.SubscribeRequest, result *mcp.EmptyResult) {
+		if session := server.ClientSessionFromContext(ctx); session != nil {
+			coreLogger.Debug("Session %s subscribed to %s", session.SessionID(), message.Params.URI)
+			s.subscriptions.subscribe(message.Params.URI, session.SessionID())
+		}
+	})
+	hooks.AddAfterUnsubscribe(func(ctx context.Context, id any, message *mcp.UnsubscribeRequest, result *mcp.EmptyResult) {
+		if session := server.ClientSessionFromContext(ctx); session != nil {
+			s.subscriptions.unsubscribe(message.Params.URI, session.SessionID())
+		}
+	})
+	hooks.AddOnUnregisterSession(func(ctx context.Context, session server.ClientSession) {
+		s.subscriptions.removeSession(session.SessionID())
+	})
+}
+
+func (s *mcpServer) registerResources() {
+	coreLogger.Debug("Registering MCP resources")
+
+	s.mcpServer.AddResourceTemplate(
+		mcp.NewResourceTemplate("file://{+path}", "Workspace file",
+			mcp.WithTemplateDescription("Contents of a file in the workspace. The path may be absolute or relative to the workspace root."),
+			mcp.WithTemplateMIMEType("text/plain"),
+		),
+		func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+			filePath, err := s.resourcePath(request.Params.URI, fileScheme)
+			if err != nil {
+				return nil, err
+			}
+			coreLogger.Debug("Reading file resource %s", filePath)
+
+			content, err := os.ReadFile(filePath)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read file: %v", err)
+			}
+			return []mcp.ResourceContents{mcp.TextResourceContents{
+				URI:      request.Params.URI,
+				MIMEType: "text/plain",
+				Text:     string(content),
+			}}, nil
+		},
+	)
+
+	s.mcpServer.AddResourceTemplate(
+		mcp.NewResourceTemplate("diagnostics://{+path}", "File diagnostics",
+			mcp.WithTemplateDescription("Diagnostics the language server reports for a file. Subscribe to be notified when they change."),
+			mcp.WithTemplateMIMEType("text/plain"),
+		),
+		func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+			filePath, err := s.resourcePath(request.Params.URI, diagnosticsScheme)
+			if err != nil {
+				return nil, err
+			}
+			coreLogger.Debug("Reading diagnostics resource for %s", filePath)
+
+			text, err := tools.GetDiagnosticsForFile(ctx, s.lspClient, filePath, 5, true)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get diagnostics: %v", err)
+			}
+			return []mcp.ResourceContents{mcp.TextResourceContents{
+				URI:      request.Params.URI,
+				MIMEType: "text/plain",
+				Text:     text,
+			}}, nil
+		},
+	)
+
+	s.mcpServer.AddResourceTemplate(
+		mcp.NewResourceTemplate("symbols://{+path}", "File symbols",
+			mcp.WithTemplateDescription("Outline of the symbols declared in a file, with their line ranges."),
+			mcp.WithTemplateMIMEType("text/plain"),
+		),
+		func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+			filePath, err := s.resourcePath(request.Params.URI, symbolsScheme)
+			if err != nil {
+				return nil, err
+			}
+			coreLogger.Debug("Reading symbols resource for %s", filePath)
+
+			text, err := tools.GetDocumentSymbols(ctx, s.lspClient, filePath)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get document symbols: %v", err)
+			}
+			return []mcp.ResourceContents{mcp.TextResourceContents{
+				URI:      request.Params.URI,
+				MIMEType: "text/plain",
+				Text:     text,
+			}}, nil
+		},
+	)
+
+	// Notify subscribers when the underlying data changes
+	lsp.RegisterDiagnosticsHandler(func(uri protocol.DocumentUri, diagnostics []protocol.Diagnostic) {
+		s.notifyResourceUpdated(diagnosticsScheme, uri.Path())
+	})
+	s.workspaceWatcher.RegisterFileEventHandler(func(path string, changeType protocol.FileChangeType) {
+		s.notifyResourceUpdated(fileScheme, path)
+		s.notifyResourceUpdated(symbolsScheme, path)
+	})
+}
+
+// resourcePath returns the workspace file a resource URI refers to
+func (s *mcpServer) resourcePath(uri string, scheme string) (string, error) {
+	uriScheme, filePath, err := parseResourceURI(uri, s.config.workspaceDir)
+	if err != nil {
+		return "", err
+	}
+	if uriScheme != scheme {
+		return "", fmt.Errorf("unexpected resource scheme %q in %s", uriScheme, uri)
+	}
+
+	rel, err := filepath.Rel(s.config.workspaceDir, filePath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("resource %s is outside the workspace", uri)
+	}
+	// Symlinks in the workspace may still lead out of it
+	if err := utilities.CheckReadAccess(filePath); err != nil {
+		return "", err
+	}
+	return filePath, nil
+}
+
+// notifyResourceUpdated sends notifications/resources/updated to every
+// session subscribed to the resource for the given scheme and path
+func (s *mcpServer) notifyResourceUpdated(scheme, path string) {
+	for uri, sessions := range s.subscriptions.matching(scheme, path, s.config.workspaceDir) {
+		for _, sessionID := range sessions {
+			err := s.mcpServer.SendNotificationToSpecificClient(sessionID, mcp.MethodNotificationResourceUpdated, map[string]any{
+				"uri": uri,
+			})
+			if err != nil {
+				coreLogger.Warn("Failed to notify session %s about %s: %v", sessionID, uri, err)
+			}
+		}
+	}
+}
+
+// parseResourceURI splits a resource URI into its scheme and an absolute,
+// cleaned file path. Relative paths are resolved against the workspace.
+func parseResourceURI(uri string, workspaceDir string) (string, string, error) {
+	scheme, rest, ok := strings.Cut(uri, "://")
+	if !ok || rest == "" {
+		return "", "", fmt.Errorf("invalid resource URI: %s", uri)
+	}
+
+	path, err := url.PathUnescape(rest)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid resource URI %s: %v", uri, err)
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(workspaceDir, path)
+	}
+	return scheme, filepath.Clean(path), nil
+}
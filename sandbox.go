@@ -0,0 +1,76 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/isaacphi/mcp-language-server/internal/utilities"
+)
+
+// detectReadOnlyRoots returns the directories dependencies are usually read
+// from, so that definitions in the standard library or third-party modules
+// can still be read: GOROOT and the Go module cache, and the Cargo registry
+// and Rust toolchains. Only directories that exist are returned, labelled
+// with the variable they are configured by.
+func detectReadOnlyRoots() []utilities.PathRoot {
+	var candidates []utilities.PathRoot
+	if goBin, err := exec.LookPath("go"); err == nil {
+		out, err := exec.Command(goBin, "env", "GOROOT", "GOMODCACHE").Output()
+		if err != nil {
+			coreLogger.Warn("Failed to detect Go read-only roots: %v", err)
+		} else if dirs := strings.Split(strings.TrimSpace(string(out)), "\n"); len(dirs) == 2 {
+			candidates = append(candidates,
+				utilities.PathRoot{Label: "$GOROOT", Dir: strings.TrimSpace(dirs[0])},
+				utilities.PathRoot{Label: "$GOMODCACHE", Dir: strings.TrimSpace(dirs[1])})
+		}
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		cargoHome := os.Getenv("CARGO_HOME")
+		if cargoHome == "" {
+			cargoHome = filepath.Join(home, ".cargo")
+		}
+		rustupHome := os.Getenv("RUSTUP_HOME")
+		if rustupHome == "" {
+			rustupHome = filepath.Join(home, ".rustup")
+		}
+		candidates = append(candidates,
+			utilities.PathRoot{Label: "$CARGO_HOME/registry", Dir: filepath.Join(cargoHome, "registry")},
+			utilities.PathRoot{Label: "$RUSTUP_HOME/toolchains", Dir: filepath.Join(rustupHome, "toolchains")})
+	}
+
+	var roots []utilities.PathRoot
+	for _, root := range candidates {
+		if info, err := os.Stat(root.Dir); err == nil && info.IsDir() {
+			roots = append(roots, root)
+		}
+	}
+	return roots
+}
+
+// setupWorkspacePaths confines the files tools can access to the workspace
+// and the configured read-only roots, and sets how paths in tool input and
+// output relate to them
+func (s *mcpServer) setupWorkspacePaths() error {
+	var roots []utilities.PathRoot
+	for _, dir := range s.config.readOnlyRoots {
+		roots = append(roots, utilities.PathRoot{Dir: dir})
+	}
+	if s.config.detectReadOnlyRoots {
+		roots = append(roots, detectReadOnlyRoots()...)
+	}
+
+	dirs := make([]string, len(roots))
+	for i, root := range roots {
+		dirs[i] = root.Dir
+	}
+	sandbox, err := utilities.NewSandbox(s.config.workspaceDir, dirs)
+	if err != nil {
+		return err
+	}
+	utilities.SetSandbox(sandbox)
+	utilities.SetWorkspacePaths(s.config.workspaceDir, s.config.relativePaths, roots)
+	coreLogger.Info("File access is limited to %s and read-only roots %v", s.config.workspaceDir, dirs)
+	return nil
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// validateSecurityConfig checks the authentication, TLS and origin options
+// and loads the bearer token
+func validateSecurityConfig(cfg *config) error {
+	if cfg.authTokenFile != "" {
+		token, err := os.ReadFile(cfg.authTokenFile)
+		if err != nil {
+			return fmt.Errorf("failed to read auth token file: %v", err)
+		}
+		cfg.authToken = strings.TrimSpace(string(token))
+		if cfg.authToken == "" {
+			return fmt.Errorf("auth token file is empty: %s", cfg.authTokenFile)
+		}
+	} else {
+		cfg.authToken = os.Getenv("MCP_AUTH_TOKEN")
+	}
+
+	if (cfg.tlsCert == "") != (cfg.tlsKey == "") {
+		return fmt.Errorf("--tls-cert and --tls-key must be used together")
+	}
+	if cfg.tlsClientCA != "" && cfg.tlsCert == "" {
+		return fmt.Errorf("--tls-client-ca requires --tls-cert and --tls-key")
+	}
+
+	if cfg.transport == "stdio" {
+		if cfg.authTokenFile != "" || cfg.tlsCert != "" || len(cfg.allowedOrigins) > 0 {
+			return fmt.Errorf("authentication, TLS and origin options only apply to the sse and http transports")
+		}
+		return nil
+	}
+
+	if cfg.authToken == "" && cfg.tlsClientCA == "" {
+		if !isLoopbackHost(cfg.host) {
+			return fmt.Errorf("network transport on %s requires authentication, set MCP_AUTH_TOKEN, --auth-token-file or --tls-client-ca", cfg.host)
+		}
+		coreLogger.Warn("Network transport has no authentication, anyone who can reach %s:%d can use every tool. Set MCP_AUTH_TOKEN, --auth-token-file or --tls-client-ca.", cfg.host, cfg.port)
+	}
+	if cfg.tlsCert == "" && !isLoopbackHost(cfg.host) {
+		coreLogger.Warn("Network transport on %s is not using TLS, requests and tokens are sent in the clear", cfg.host)
+	}
+
+	return nil
+}
+
+// isLoopbackHost reports whether a host name or address only accepts local connections
+func isLoopbackHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// tlsConfig returns the TLS configuration for network transports, or nil
+// when TLS is not configured. Client certificates are required and verified
+// when a client CA is configured.
+func (c *config) tlsConfig() (*tls.Config, error) {
+	if c.tlsCert == "" {
+		return nil, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(c.tlsCert, c.tlsKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS certificate: %v", err)
+	}
+
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if c.tlsClientCA != "" {
+		caPEM, err := os.ReadFile(c.tlsClientCA)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client CA: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("no certificates found in client CA: %s", c.tlsClientCA)
+		}
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return tlsConfig, nil
+}
+
+// secureHandler enforces the origin allowlist and bearer token before a
+// request reaches the MCP transport. Client certificates are checked by the
+// TLS handshake before this runs.
+func (s *mcpServer) secureHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin != "" && len(s.config.allowedOrigins) > 0 && !slices.Contains(s.config.allowedOrigins, origin) {
+			coreLogger.Warn("Rejected request from origin %s", origin)
+			http.Error(w, "Forbidden: origin not allowed", http.StatusForbidden)
+			return
+		}
+
+		// CORS preflight requests never carry credentials
+		if r.Method == http.MethodOptions && origin != "" && len(s.config.allowedOrigins) > 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if s.config.authToken != "" {
+			token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.config.authToken)) != 1 {
+				coreLogger.Warn("Rejected unauthenticated request from %s", r.RemoteAddr)
+				w.Header().Set("WWW-Authenticate", `Bearer realm="mcp-language-server"`)
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// serveHTTP serves an MCP transport handler with the configured security
+func (s *mcpServer) serveHTTP(addr string, handler http.Handler) error {
+	tlsConfig, err := s.config.tlsConfig()
+	if err != nil {
+		return err
+	}
+
+	httpServer := &http.Server{
+		Addr:      addr,
+		Handler:   s.secureHandler(handler),
+		TLSConfig: tlsConfig,
+	}
+
+	if tlsConfig != nil {
+		// The certificate is already loaded into the TLS config
+		return httpServer.ListenAndServeTLS("", "")
+	}
+	return httpServer.ListenAndServe()
+}
+
+// readOnlyToolFilter hides and blocks every tool that is not marked read-only
+func readOnlyToolFilter(ctx context.Context, tools []mcp.Tool) []mcp.Tool {
+	readOnly := make([]mcp.Tool, 0, len(tools))
+	for _, tool := range tools {
+		if tool.Annotations.ReadOnlyHint != nil && *tool.Annotations.ReadOnlyHint {
+			readOnly = append(readOnly, tool)
+		}
+	}
+	return readOnly
+}
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// securedServer serves a handler that answers 200 behind secureHandler
+func securedServer(t *testing.T, cfg config) *httptest.Server {
+	t.Helper()
+	s := &mcpServer{config: cfg}
+	ts := httptest.NewServer(s.secureHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func statusOf(t *testing.T, ts *httptest.Server, method string, headers map[string]string) int {
+	t.Helper()
+	req, err := http.NewRequest(method, ts.URL+"/mcp", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestSecureHandlerToken(t *testing.T) {
+	ts := securedServer(t, config{authToken: "secret"})
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    int
+	}{
+		{"missing token", nil, http.StatusUnauthorized},
+		{"wrong token", map[string]string{"Authorization": "Bearer wrong"}, http.StatusUnauthorized},
+		{"not a bearer token", map[string]string{"Authorization": "Basic secret"}, http.StatusUnauthorized},
+		{"right token", map[string]string{"Authorization": "Bearer secret"}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := statusOf(t, ts, http.MethodPost, tt.headers); got != tt.want {
+				t.Errorf("status = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSecureHandlerOrigins(t *testing.T) {
+	ts := securedServer(t, config{authToken: "secret", allowedOrigins: []string{"https://app.example"}})
+	auth := "Bearer secret"
+
+	tests := []struct {
+		name    string
+		method  string
+		headers map[string]string
+		want    int
+	}{
+		{"origin not allowed", http.MethodPost, map[string]string{"Origin": "https://evil.example", "Authorization": auth}, http.StatusForbidden},
+		{"preflight from origin not allowed", http.MethodOptions, map[string]string{"Origin": "https://evil.example"}, http.StatusForbidden},
+		{"allowed origin", http.MethodPost, map[string]string{"Origin": "https://app.example", "Authorization": auth}, http.StatusOK},
+		{"allowed origin without token", http.MethodPost, map[string]string{"Origin": "https://app.example"}, http.StatusUnauthorized},
+		{"preflight without token", http.MethodOptions, map[string]string{"Origin": "https://app.example"}, http.StatusOK},
+		{"no origin", http.MethodPost, map[string]string{"Authorization": auth}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := statusOf(t, ts, tt.method, tt.headers); got != tt.want {
+				t.Errorf("status = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+// writeCert writes a certificate and its key as PEM files in dir and
+// returns them parsed
+func writeCert(t *testing.T, dir, name string, template *x509.Certificate, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	if err := os.WriteFile(filepath.Join(dir, name+".pem"), certPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name+".key"), keyPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+func TestTLSConfigRequiresClientCertificate(t *testing.T) {
+	dir := t.TempDir()
+	notAfter := time.Now().Add(time.Hour)
+	ca, caKey := writeCert(t, dir, "ca", &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotAfter:              notAfter,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, nil, nil)
+	writeCert(t, dir, "server", &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "server"},
+		NotAfter:     notAfter,
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, ca, caKey)
+	writeCert(t, dir, "client", &x509.Certificate{
+		SerialNumber: big.NewInt(3),
+		Subject:      pkix.Name{CommonName: "client"},
+		NotAfter:     notAfter,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, ca, caKey)
+
+	cfg := config{
+		tlsCert:     filepath.Join(dir, "server.pem"),
+		tlsKey:      filepath.Join(dir, "server.key"),
+		tlsClientCA: filepath.Join(dir, "ca.pem"),
+	}
+	tlsConfig, err := cfg.tlsConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &mcpServer{config: cfg}
+	ts := httptest.NewUnstartedServer(s.secureHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})))
+	ts.TLS = tlsConfig
+	ts.StartTLS()
+	defer ts.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+	get := func(certificates []tls.Certificate) error {
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots, Certificates: certificates}}}
+		resp, err := client.Get(ts.URL)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		return nil
+	}
+
+	if err := get(nil); err == nil {
+		t.Error("client without a certificate was accepted")
+	}
+
+	clientCert, err := tls.LoadX509KeyPair(filepath.Join(dir, "client.pem"), filepath.Join(dir, "client.key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := get([]tls.Certificate{clientCert}); err != nil {
+		t.Errorf("client with a certificate was refused: %v", err)
+	}
+}
+
+func TestReadOnlyToolFilter(t *testing.T) {
+	s := &mcpServer{
+		config:    config{maxOutputTokens: 10000},
+		mcpServer: server.NewMCPServer("test", "test", server.WithToolCapabilities(true)),
+	}
+	if err := s.registerTools(); err != nil {
+		t.Fatal(err)
+	}
+	var all []string
+	var registered []mcp.Tool
+	for name, tool := range s.mcpServer.ListTools() {
+		all = append(all, name)
+		registered = append(registered, tool.Tool)
+	}
+
+	var visible []string
+	for _, tool := range readOnlyToolFilter(t.Context(), registered) {
+		if tool.Annotations.ReadOnlyHint == nil || !*tool.Annotations.ReadOnlyHint {
+			t.Errorf("%s is not marked read-only but was kept", tool.Name)
+		}
+		visible = append(visible, tool.Name)
+	}
+
+	for _, name := range []string{"edit_file", "rename_symbol", "run_tests", "execute_command", "execute_codelens"} {
+		if !slices.Contains(all, name) {
+			t.Errorf("%s is not registered", name)
+		}
+		if slices.Contains(visible, name) {
+			t.Errorf("%s is visible in read-only mode", name)
+		}
+	}
+	for _, name := range []string{"definition", "references", "hover"} {
+		if !slices.Contains(visible, name) {
+			t.Errorf("read-only tool %s was hidden", name)
+		}
+	}
+}
+
+func TestValidateSecurityConfig(t *testing.T) {
+	t.Setenv("MCP_AUTH_TOKEN", "")
+
+	tests := []struct {
+		name    string
+		cfg     config
+		wantErr bool
+	}{
+		{"stdio", config{transport: "stdio"}, false},
+		{"loopback without token", config{transport: "http", host: "localhost"}, false},
+		{"loopback address without token", config{transport: "http", host: "127.0.0.1"}, false},
+		{"all interfaces without token", config{transport: "http", host: "0.0.0.0"}, true},
+		{"external host without token", config{transport: "sse", host: "192.0.2.1"}, true},
+		{"external host with client CA", config{transport: "http", host: "0.0.0.0", tlsCert: "c", tlsKey: "k", tlsClientCA: "ca"}, false},
+		{"cert without key", config{transport: "http", host: "localhost", tlsCert: "c"}, true},
+		{"client CA without cert", config{transport: "http", host: "localhost", tlsClientCA: "ca"}, true},
+		{"TLS with stdio", config{transport: "stdio", tlsCert: "c", tlsKey: "k"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateSecurityConfig(&tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateSecurityConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	t.Run("external host with token", func(t *testing.T) {
+		t.Setenv("MCP_AUTH_TOKEN", "secret")
+		cfg := config{transport: "http", host: "0.0.0.0"}
+		if err := validateSecurityConfig(&cfg); err != nil {
+			t.Errorf("validateSecurityConfig() error = %v", err)
+		}
+		if cfg.authToken != "secret" {
+			t.Errorf("authToken = %q, want the MCP_AUTH_TOKEN value", cfg.authToken)
+		}
+	})
+
+	t.Run("token file", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "token")
+		if err := os.WriteFile(file, []byte("from-file\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		cfg := config{transport: "http", host: "0.0.0.0", authTokenFile: file}
+		if err := validateSecurityConfig(&cfg); err != nil || cfg.authToken != "from-file" {
+			t.Errorf("validateSecurityConfig() = %v, authToken %q", err, cfg.authToken)
+		}
+	})
+}
@@ -2,17 +2,21 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/isaacphi/mcp-language-server/internal/tools"
 	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
 )
 
 func (s *mcpServer) registerTools() error {
 	coreLogger.Debug("Registering MCP tools")
 
 	applyTextEditTool := mcp.NewTool("edit_file",
-		mcp.WithDescription("Apply multiple text edits to a file."),
+		mcp.WithDescription("Apply multiple text edits to a file. Each edit either replaces a line range (startLine/endLine), replaces an exact string (oldText), replaces regular expression matches (pattern), or inserts lines before or after a symbol's declaration (symbol/position)."),
+		mcp.WithOutputSchema[tools.TextEditsResult](),
 		mcp.WithArray("edits",
 			mcp.Required(),
 			mcp.Description("List of edits to apply"),
@@ -29,19 +33,46 @@ func (s *mcpServer) registerTools() error {
 					},
 					"newText": map[string]any{
 						"type":        "string",
-						"description": "Replacement text. Replace with the new text. Leave blank to remove lines.",
+						"description": "Replacement text. Replace with the new text. Leave blank to remove lines. With pattern, $1 or ${name} refer to capture groups.",
+					},
+					"expectedText": map[string]any{
+						"type":        "string",
+						"description": "Optional current text of lines startLine to endLine. The edits are rejected with a conflict report if the file no longer matches.",
+					},
+					"oldText": map[string]any{
+						"type":        "string",
+						"description": "Exact text to replace with newText instead of a line range. Must occur exactly once unless replaceAll is set.",
+					},
+					"pattern": map[string]any{
+						"type":        "string",
+						"description": "Regular expression (Go RE2 syntax) to replace with newText instead of a line range. Must match exactly once unless replaceAll is set.",
+					},
+					"replaceAll": map[string]any{
+						"type":        "boolean",
+						"description": "Replace every occurrence of oldText or pattern",
+					},
+					"symbol": map[string]any{
+						"type":        "string",
+						"description": "Symbol declared in the file to insert newText next to, such as 'MyFunction' or 'MyType.MyMethod'",
+					},
+					"position": map[string]any{
+						"type":        "string",
+						"enum":        []string{"before", "after"},
+						"description": "Insert newText before the symbol's declaration and doc comment, or after its end",
 					},
 				},
-				"required": []string{"startLine", "endLine"},
 			}),
 		),
 		mcp.WithString("filePath",
 			mcp.Required(),
-			mcp.Description("Path to the file to edit"),
+			mcp.Description("Path to the file to edit, absolute or relative to the workspace"),
+		),
+		mcp.WithString("expectedHash",
+			mcp.Description("Optional SHA-256 of the file content the edits are based on, as returned by a previous edit_file call. The edits are rejected if the file has changed."),
 		),
 	)
 
-	s.mcpServer.AddTool(applyTextEditTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	s.addTool(applyTextEditTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		// Extract arguments
 		filePath, err := request.RequireString("filePath")
 		if err != nil {
@@ -67,87 +98,139 @@ func (s *mcpServer) registerTools() error {
 				return mcp.NewToolResultError("each edit must be an object"), nil
 			}
 
-			startLine, ok := editMap["startLine"].(float64)
-			if !ok {
-				return mcp.NewToolResultError("startLine must be a number"), nil
+			newText, _ := editMap["newText"].(string) // newText can be empty
+			oldText, _ := editMap["oldText"].(string)
+			pattern, _ := editMap["pattern"].(string)
+			replaceAll, _ := editMap["replaceAll"].(bool)
+			symbol, _ := editMap["symbol"].(string)
+			position, _ := editMap["position"].(string)
+
+			edit := tools.TextEdit{
+				NewText:    newText,
+				OldText:    oldText,
+				Pattern:    pattern,
+				ReplaceAll: replaceAll,
+				Symbol:     symbol,
+				Position:   position,
 			}
 
-			endLine, ok := editMap["endLine"].(float64)
-			if !ok {
-				return mcp.NewToolResultError("endLine must be a number"), nil
-			}
+			// Line ranges are only required when no other mode is used
+			if oldText == "" && pattern == "" && symbol == "" {
+				startLine, ok := editMap["startLine"].(float64)
+				if !ok {
+					return mcp.NewToolResultError("startLine must be a number"), nil
+				}
 
-			newText, _ := editMap["newText"].(string) // newText can be empty
+				endLine, ok := editMap["endLine"].(float64)
+				if !ok {
+					return mcp.NewToolResultError("endLine must be a number"), nil
+				}
 
-			edits = append(edits, tools.TextEdit{
-				StartLine: int(startLine),
-				EndLine:   int(endLine),
-				NewText:   newText,
-			})
+				edit.StartLine = int(startLine)
+				edit.EndLine = int(endLine)
+			}
+			if expectedText, ok := editMap["expectedText"].(string); ok {
+				edit.ExpectedText = &expectedText
+			}
+			edits = append(edits, edit)
 		}
 
+		expectedHash := request.GetString("expectedHash", "")
+
 		coreLogger.Debug("Executing edit_file for file: %s", filePath)
-		response, err := tools.ApplyTextEdits(s.ctx, s.lspClient, filePath, edits)
+		result, err := tools.ApplyTextEditsResult(s.callContext(ctx), s.lspClient, filePath, edits, expectedHash)
 		if err != nil {
 			coreLogger.Error("Failed to apply edits: %v", err)
 			return mcp.NewToolResultError(fmt.Sprintf("failed to apply edits: %v", err)), nil
 		}
-		return mcp.NewToolResultText(response), nil
+		return s.toolResult(request, result), nil
 	})
 
 	readDefinitionTool := mcp.NewTool("definition",
 		mcp.WithDescription("Read the source code definition of a symbol (function, type, constant, etc.) from the codebase. Returns the complete implementation code where the symbol is defined."),
+		mcp.WithOutputSchema[tools.DefinitionResult](),
+		withCursor(),
+		mcp.WithReadOnlyHintAnnotation(true),
 		mcp.WithString("symbolName",
 			mcp.Required(),
-			mcp.Description("The name of the symbol whose definition you want to find (e.g. 'mypackage.MyFunction', 'MyType.MyMethod')"),
+			mcp.Description("The name of the symbol whose definition you want to find, qualified as much as needed (e.g. 'mypackage.MyFunction', 'MyType.MyMethod', 'crate::module::Type::method', 'ns::Class::method')"),
 		),
+		withSymbolFilters(),
 	)
 
-	s.mcpServer.AddTool(readDefinitionTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	s.addTool(readDefinitionTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		// Extract arguments
-		symbolName, err := request.RequireString("symbolName")
+		query, err := symbolQuery(request)
 		if err != nil {
 			return mcp.NewToolResultError(err.Error()), nil
 		}
 
-		coreLogger.Debug("Executing definition for symbol: %s", symbolName)
-		text, err := tools.ReadDefinition(s.ctx, s.lspClient, symbolName)
+		coreLogger.Debug("Executing definition for symbol: %s", query.Name)
+		result, err := tools.ReadDefinitionResult(s.callContext(ctx), s.lspClient, query)
 		if err != nil {
 			coreLogger.Error("Failed to get definition: %v", err)
 			return mcp.NewToolResultError(fmt.Sprintf("failed to get definition: %v", err)), nil
 		}
-		return mcp.NewToolResultText(text), nil
+		return s.toolResult(request, result), nil
 	})
 
 	findReferencesTool := mcp.NewTool("references",
-		mcp.WithDescription("Find all usages and references of a symbol throughout the codebase. Returns a list of all files and locations where the symbol appears."),
+		mcp.WithDescription("Find all usages and references of a symbol throughout the codebase. Returns a list of all files and locations where the symbol appears, with each reference classified as a read, write, call, import or type-use."),
+		mcp.WithOutputSchema[tools.ReferencesResult](),
+		withCursor(),
+		mcp.WithReadOnlyHintAnnotation(true),
 		mcp.WithString("symbolName",
 			mcp.Required(),
-			mcp.Description("The name of the symbol to search for (e.g. 'mypackage.MyFunction', 'MyType')"),
+			mcp.Description("The name of the symbol to search for, qualified as much as needed (e.g. 'mypackage.MyFunction', 'MyType', 'crate::module::Type::method', 'ns::Class::method')"),
+		),
+		withSymbolFilters(),
+		mcp.WithBoolean("includeDeclaration",
+			mcp.Description("Also list the declaration of the symbol"),
+		),
+		mcp.WithArray("include",
+			mcp.Description("Only list references in files matching one of these globs, relative to the workspace (e.g. 'internal/**', '*.go')"),
+			mcp.WithStringItems(),
+		),
+		mcp.WithArray("exclude",
+			mcp.Description("Skip references in files matching any of these globs (e.g. '*_test.go', 'vendor/')"),
+			mcp.WithStringItems(),
+		),
+		mcp.WithArray("kinds",
+			mcp.Description("Only list references of these kinds"),
+			mcp.WithStringEnumItems(tools.ReferenceKinds),
 		),
 	)
 
-	s.mcpServer.AddTool(findReferencesTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	s.addTool(findReferencesTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		// Extract arguments
-		symbolName, err := request.RequireString("symbolName")
+		query, err := symbolQuery(request)
 		if err != nil {
 			return mcp.NewToolResultError(err.Error()), nil
 		}
 
-		coreLogger.Debug("Executing references for symbol: %s", symbolName)
-		text, err := tools.FindReferences(s.ctx, s.lspClient, symbolName)
+		coreLogger.Debug("Executing references for symbol: %s", query.Name)
+		options := tools.ReferencesOptions{
+			IncludeDeclaration: request.GetBool("includeDeclaration", false),
+			Include:            request.GetStringSlice("include", nil),
+			Exclude:            request.GetStringSlice("exclude", nil),
+			Classify:           true,
+			Kinds:              request.GetStringSlice("kinds", nil),
+		}
+		result, err := tools.FindReferencesResult(s.callContext(ctx), s.lspClient, query, options)
 		if err != nil {
 			coreLogger.Error("Failed to find references: %v", err)
 			return mcp.NewToolResultError(fmt.Sprintf("failed to find references: %v", err)), nil
 		}
-		return mcp.NewToolResultText(text), nil
+		return s.toolResult(request, result), nil
 	})
 
 	getDiagnosticsTool := mcp.NewTool("diagnostics",
 		mcp.WithDescription("Get diagnostic information for a specific file from the language server."),
+		mcp.WithOutputSchema[tools.DiagnosticsResult](),
+		mcp.WithReadOnlyHintAnnotation(true),
 		mcp.WithString("filePath",
 			mcp.Required(),
-			mcp.Description("The path to the file to get diagnostics for"),
+			mcp.Description("The path to the file to get diagnostics for, absolute or relative to the workspace"),
 		),
 		mcp.WithBoolean("contextLines",
 			mcp.Description("Lines to include around each diagnostic."),
@@ -159,7 +242,7 @@ func (s *mcpServer) registerTools() error {
 		),
 	)
 
-	s.mcpServer.AddTool(getDiagnosticsTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	s.addTool(getDiagnosticsTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		// Extract arguments
 		filePath, err := request.RequireString("filePath")
 		if err != nil {
@@ -170,84 +253,136 @@ func (s *mcpServer) registerTools() error {
 		showLineNumbers := request.GetBool("showLineNumbers", true)
 
 		coreLogger.Debug("Executing diagnostics for file: %s", filePath)
-		text, err := tools.GetDiagnosticsForFile(ctx, s.lspClient, filePath, contextLines, showLineNumbers)
+		result, err := tools.GetDiagnosticsForFileResult(s.callContext(ctx), s.lspClient, filePath, contextLines, showLineNumbers)
 		if err != nil {
 			coreLogger.Error("Failed to get diagnostics: %v", err)
 			return mcp.NewToolResultError(fmt.Sprintf("failed to get diagnostics: %v", err)), nil
 		}
-		return mcp.NewToolResultText(text), nil
+		return s.toolResult(request, result), nil
+	})
+
+	getCodeLensTool := mcp.NewTool("get_codelens",
+		mcp.WithDescription("Get code lens hints for a given file from the language server. Each lens has an ID that can be passed to execute_codelens."),
+		mcp.WithOutputSchema[tools.CodeLensResult](),
+		withCursor(),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithString("filePath",
+			mcp.Required(),
+			mcp.Description("The path to the file to get code lens information for, absolute or relative to the workspace"),
+		),
+	)
+
+	s.addTool(getCodeLensTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract arguments
+		filePath, err := request.RequireString("filePath")
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		coreLogger.Debug("Executing get_codelens for file: %s", filePath)
+		result, err := tools.GetCodeLensResult(s.callContext(ctx), s.lspClient, filePath)
+		if err != nil {
+			coreLogger.Error("Failed to get code lens: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to get code lens: %v", err)), nil
+		}
+		return s.toolResult(request, result), nil
+	})
+
+	executeCodeLensTool := mcp.NewTool("execute_codelens",
+		mcp.WithDescription("Execute a code lens command for a given file and lens ID. Returns any workspace edits and messages the server sent while running it."),
+		mcp.WithOutputSchema[tools.CodeLensExecution](),
+		mcp.WithString("filePath",
+			mcp.Required(),
+			mcp.Description("The path to the file containing the code lens to execute, absolute or relative to the workspace"),
+		),
+		mcp.WithString("lensId",
+			mcp.Required(),
+			mcp.Description("The ID of the code lens to execute, from get_codelens output"),
+		),
+	)
+
+	s.addTool(executeCodeLensTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract arguments
+		filePath, err := request.RequireString("filePath")
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		lensID, err := request.RequireString("lensId")
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		coreLogger.Debug("Executing execute_codelens for file: %s lens: %s", filePath, lensID)
+		result, err := tools.ExecuteCodeLensResult(s.callContext(ctx), s.lspClient, filePath, lensID)
+		if err != nil {
+			coreLogger.Error("Failed to execute code lens: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to execute code lens: %v", err)), nil
+		}
+		return s.toolResult(request, result), nil
 	})
 
-	// Uncomment to add codelens tools
-	//
-	// getCodeLensTool := mcp.NewTool("get_codelens",
-	// 	mcp.WithDescription("Get code lens hints for a given file from the language server."),
-	// 	mcp.WithString("filePath",
-	// 		mcp.Required(),
-	// 		mcp.Description("The path to the file to get code lens information for"),
-	// 	),
-	// )
-	//
-	// s.mcpServer.AddTool(getCodeLensTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	// 	// Extract arguments
-	// 	filePath, ok := request.Params.Arguments["filePath"].(string)
-	// 	if !ok {
-	// 		return mcp.NewToolResultError("filePath must be a string"), nil
-	// 	}
-	//
-	// 	coreLogger.Debug("Executing get_codelens for file: %s", filePath)
-	// 	text, err := tools.GetCodeLens(s.ctx, s.lspClient, filePath)
-	// 	if err != nil {
-	// 		coreLogger.Error("Failed to get code lens: %v", err)
-	// 		return mcp.NewToolResultError(fmt.Sprintf("failed to get code lens: %v", err)), nil
-	// 	}
-	// 	return mcp.NewToolResultText(text), nil
-	// })
-	//
-	// executeCodeLensTool := mcp.NewTool("execute_codelens",
-	// 	mcp.WithDescription("Execute a code lens command for a given file and lens index."),
-	// 	mcp.WithString("filePath",
-	// 		mcp.Required(),
-	// 		mcp.Description("The path to the file containing the code lens to execute"),
-	// 	),
-	// 	mcp.WithNumber("index",
-	// 		mcp.Required(),
-	// 		mcp.Description("The index of the code lens to execute (from get_codelens output), 1 indexed"),
-	// 	),
-	// )
-	//
-	// s.mcpServer.AddTool(executeCodeLensTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	// 	// Extract arguments
-	// 	filePath, ok := request.Params.Arguments["filePath"].(string)
-	// 	if !ok {
-	// 		return mcp.NewToolResultError("filePath must be a string"), nil
-	// 	}
-	//
-	// 	// Handle both float64 and int for index due to JSON parsing
-	// 	var index int
-	// 	switch v := request.Params.Arguments["index"].(type) {
-	// 	case float64:
-	// 		index = int(v)
-	// 	case int:
-	// 		index = v
-	// 	default:
-	// 		return mcp.NewToolResultError("index must be a number"), nil
-	// 	}
-	//
-	// 	coreLogger.Debug("Executing execute_codelens for file: %s index: %d", filePath, index)
-	// 	text, err := tools.ExecuteCodeLens(s.ctx, s.lspClient, filePath, index)
-	// 	if err != nil {
-	// 		coreLogger.Error("Failed to execute code lens: %v", err)
-	// 		return mcp.NewToolResultError(fmt.Sprintf("failed to execute code lens: %v", err)), nil
-	// 	}
-	// 	return mcp.NewToolResultText(text), nil
-	// })
+	runTestsTool := mcp.NewTool("run_tests",
+		mcp.WithDescription("Run the tests of a file, or of a symbol, and report which passed and which failed, with each failure message at the file and line it was reported. Tests are run the way the test code lenses of the language server run them, and otherwise with the test command configured for the language. For a symbol that is not a test, the tests calling it are run."),
+		mcp.WithOutputSchema[tools.RunTestsResult](),
+		mcp.WithString("filePath",
+			mcp.Description("The file whose tests to run, absolute or relative to the workspace. Required unless symbolName is set"),
+		),
+		mcp.WithString("symbolName",
+			mcp.Description("The test to run, or the symbol whose tests to run, qualified as much as needed (e.g. 'mypackage.TestParse', 'tests::parses', 'MyType.MyMethod')"),
+		),
+		withSymbolFilters(),
+		mcp.WithNumber("depth",
+			mcp.Description(fmt.Sprintf("How many levels of callers to follow to find the tests of a symbol that is not a test, up to %d", tools.MaxCallHierarchyDepth)),
+			mcp.DefaultNumber(5),
+		),
+		mcp.WithNumber("timeout",
+			mcp.Description("How many seconds the tests may take altogether, 0 for no limit"),
+			mcp.DefaultNumber(300),
+		),
+	)
+
+	s.addTool(runTestsTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract arguments
+		filePath := request.GetString("filePath", "")
+		var query tools.SymbolQuery
+		if request.GetString("symbolName", "") != "" {
+			var err error
+			query, err = symbolQuery(request)
+			if err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+		} else if filePath == "" {
+			return mcp.NewToolResultError("either filePath or symbolName is required"), nil
+		}
+
+		timeout := request.GetInt("timeout", 300)
+		if timeout < 0 {
+			return mcp.NewToolResultError(fmt.Sprintf("invalid timeout: %d (must be 0 or more)", timeout)), nil
+		}
+
+		options := tools.RunTestsOptions{
+			Commands: s.config.testCommands,
+			Timeout:  time.Duration(timeout) * time.Second,
+			Depth:    request.GetInt("depth", 5),
+		}
+
+		coreLogger.Debug("Executing run_tests for file: %s symbol: %s", filePath, query.Name)
+		result, err := tools.RunTestsResultFor(s.callContext(ctx), s.lspClient, filePath, query, options)
+		if err != nil {
+			coreLogger.Error("Failed to run tests: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to run tests: %v", err)), nil
+		}
+		return s.toolResult(request, result), nil
+	})
 
 	hoverTool := mcp.NewTool("hover",
 		mcp.WithDescription("Get hover information (type, documentation) for a symbol at the specified position."),
+		mcp.WithOutputSchema[tools.HoverResult](),
+		mcp.WithReadOnlyHintAnnotation(true),
 		mcp.WithString("filePath",
 			mcp.Required(),
-			mcp.Description("The path to the file to get hover information for"),
+			mcp.Description("The path to the file to get hover information for, absolute or relative to the workspace"),
 		),
 		mcp.WithNumber("line",
 			mcp.Required(),
@@ -259,7 +394,7 @@ func (s *mcpServer) registerTools() error {
 		),
 	)
 
-	s.mcpServer.AddTool(hoverTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	s.addTool(hoverTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		// Extract arguments
 		filePath, err := request.RequireString("filePath")
 		if err != nil {
@@ -277,19 +412,64 @@ func (s *mcpServer) registerTools() error {
 		}
 
 		coreLogger.Debug("Executing hover for file: %s line: %d column: %d", filePath, line, column)
-		text, err := tools.GetHoverInfo(s.ctx, s.lspClient, filePath, line, column)
+		result, err := tools.GetHoverInfoResult(s.callContext(ctx), s.lspClient, filePath, line, column)
 		if err != nil {
 			coreLogger.Error("Failed to get hover information: %v", err)
 			return mcp.NewToolResultError(fmt.Sprintf("failed to get hover information: %v", err)), nil
 		}
-		return mcp.NewToolResultText(text), nil
+		return s.toolResult(request, result), nil
+	})
+
+	highlightsTool := mcp.NewTool("highlights",
+		mcp.WithDescription("Show where the symbol at a position occurs within its file, such as every place a local variable is read or written in a function. Returns the surrounding code with each occurrence marked as text, read or write."),
+		mcp.WithOutputSchema[tools.HighlightsResult](),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithString("filePath",
+			mcp.Required(),
+			mcp.Description("The path to the file containing the symbol, absolute or relative to the workspace"),
+		),
+		mcp.WithNumber("line",
+			mcp.Required(),
+			mcp.Description("The line number where the symbol is located (1-indexed)"),
+		),
+		mcp.WithNumber("column",
+			mcp.Required(),
+			mcp.Description("The column number where the symbol is located (1-indexed)"),
+		),
+	)
+
+	s.addTool(highlightsTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract arguments
+		filePath, err := request.RequireString("filePath")
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		line, err := request.RequireInt("line")
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		column, err := request.RequireInt("column")
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		coreLogger.Debug("Executing highlights for file: %s line: %d column: %d", filePath, line, column)
+		result, err := tools.GetHighlightsResult(s.callContext(ctx), s.lspClient, filePath, line, column)
+		if err != nil {
+			coreLogger.Error("Failed to get highlights: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to get highlights: %v", err)), nil
+		}
+		return s.toolResult(request, result), nil
 	})
 
 	renameSymbolTool := mcp.NewTool("rename_symbol",
 		mcp.WithDescription("Rename a symbol (variable, function, class, etc.) at the specified position and update all references throughout the codebase."),
+		mcp.WithOutputSchema[tools.RenameResult](),
 		mcp.WithString("filePath",
 			mcp.Required(),
-			mcp.Description("The path to the file containing the symbol to rename"),
+			mcp.Description("The path to the file containing the symbol to rename, absolute or relative to the workspace"),
 		),
 		mcp.WithNumber("line",
 			mcp.Required(),
@@ -305,7 +485,7 @@ func (s *mcpServer) registerTools() error {
 		),
 	)
 
-	s.mcpServer.AddTool(renameSymbolTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	s.addTool(renameSymbolTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		// Extract arguments
 		filePath, err := request.RequireString("filePath")
 		if err != nil {
@@ -328,65 +508,251 @@ func (s *mcpServer) registerTools() error {
 		}
 
 		coreLogger.Debug("Executing rename_symbol for file: %s line: %d column: %d newName: %s", filePath, line, column, newName)
-		text, err := tools.RenameSymbol(s.ctx, s.lspClient, filePath, line, column, newName)
+		result, err := tools.RenameSymbolResult(s.callContext(ctx), s.lspClient, filePath, line, column, newName)
 		if err != nil {
 			coreLogger.Error("Failed to rename symbol: %v", err)
 			return mcp.NewToolResultError(fmt.Sprintf("failed to rename symbol: %v", err)), nil
 		}
-		return mcp.NewToolResultText(text), nil
+		return s.toolResult(request, result), nil
+	})
+
+	replaceSymbolTool := mcp.NewTool("replace_symbol",
+		mcp.WithDescription("Replace the whole definition of a symbol (function, method, type, etc.) with new source code. The symbol must resolve to exactly one location. If the new source starts with a comment, it also replaces the doc comment above the definition."),
+		mcp.WithOutputSchema[tools.SymbolReplacement](),
+		mcp.WithString("symbolName",
+			mcp.Required(),
+			mcp.Description("The name of the symbol to replace (e.g. 'MyFunction', 'MyType.MyMethod')"),
+		),
+		mcp.WithString("newText",
+			mcp.Required(),
+			mcp.Description("The complete new source of the definition"),
+		),
+		mcp.WithString("filePath",
+			mcp.Description("Optional path to the file declaring the symbol, absolute or relative to the workspace. Without it the symbol is looked up across the workspace."),
+		),
+		withSymbolID(),
+	)
+
+	s.addTool(replaceSymbolTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract arguments
+		query, err := symbolQuery(request)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		newText, err := request.RequireString("newText")
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		filePath := request.GetString("filePath", "")
+
+		coreLogger.Debug("Executing replace_symbol for symbol: %s", query.Name)
+		result, err := tools.ReplaceSymbolResult(s.callContext(ctx), s.lspClient, query, filePath, newText)
+		if err != nil {
+			coreLogger.Error("Failed to replace symbol: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to replace symbol: %v", err)), nil
+		}
+		return s.toolResult(request, result), nil
+	})
+
+	listEditHistoryTool := mcp.NewTool("list_edit_history",
+		mcp.WithDescription("List the edits made through this server (edit_file, rename_symbol, replace_symbol and edits requested by the language server), newest first, with the files each one changed."),
+		mcp.WithOutputSchema[tools.EditHistoryResult](),
+		withCursor(),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithNumber("limit",
+			mcp.Description("Maximum number of edits to list. Defaults to 20, 0 lists all."),
+		),
+	)
+
+	s.addTool(listEditHistoryTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		limit := request.GetInt("limit", 20)
+
+		coreLogger.Debug("Executing list_edit_history with limit: %d", limit)
+		return s.toolResult(request, tools.ListEditHistoryResult(limit)), nil
+	})
+
+	undoLastEditTool := mcp.NewTool("undo_last_edit",
+		mcp.WithDescription("Undo the most recent edit made through this server, restoring every file it changed. Refuses if any of those files changed since, or if the edit renamed or deleted a directory. Call repeatedly to undo further back."),
+		mcp.WithOutputSchema[tools.RestoredEditResult](),
+	)
+
+	s.addTool(undoLastEditTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		coreLogger.Debug("Executing undo_last_edit")
+		result, err := tools.UndoLastEditResult()
+		if err != nil {
+			coreLogger.Error("Failed to undo edit: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to undo edit: %v", err)), nil
+		}
+		return s.toolResult(request, result), nil
+	})
+
+	redoLastEditTool := mcp.NewTool("redo_last_edit",
+		mcp.WithDescription("Apply the most recently undone edit again. Undone edits can no longer be redone once a new edit is made."),
+		mcp.WithOutputSchema[tools.RestoredEditResult](),
+	)
+
+	s.addTool(redoLastEditTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		coreLogger.Debug("Executing redo_last_edit")
+		result, err := tools.RedoLastEditResult()
+		if err != nil {
+			coreLogger.Error("Failed to redo edit: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to redo edit: %v", err)), nil
+		}
+		return s.toolResult(request, result), nil
+	})
+
+	pendingEditsTool := mcp.NewTool("pending_edits",
+		mcp.WithDescription("List, approve or reject workspace edits the language server requested (for example from a code lens or command) that are waiting for approval. Edits are only queued when the server runs with --apply-edit-policy queue."),
+		mcp.WithOutputSchema[tools.PendingEditsReport](),
+		withCursor(),
+		mcp.WithString("action",
+			mcp.Description("list (default), approve or reject"),
+			mcp.Enum("list", "approve", "reject"),
+		),
+		mcp.WithNumber("id",
+			mcp.Description("ID of the pending edit to approve or reject"),
+		),
+	)
+
+	s.addTool(pendingEditsTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		action := request.GetString("action", "list")
+		id := request.GetInt("id", 0)
+		if (action == "approve" || action == "reject") && id == 0 {
+			return mcp.NewToolResultError("id is required to approve or reject an edit"), nil
+		}
+
+		coreLogger.Debug("Executing pending_edits with action: %s id: %d", action, id)
+		result, err := tools.PendingEditsResult(s.lspClient, action, id)
+		if err != nil {
+			coreLogger.Error("Failed to handle pending edits: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to %s pending edit: %v", action, err)), nil
+		}
+		return s.toolResult(request, result), nil
 	})
 
 	callersTool := mcp.NewTool("callers",
 		mcp.WithDescription("Determine which functions call the given symbol. Returns a list of the calling functions and the locations of the call sites."),
+		mcp.WithOutputSchema[tools.CallHierarchyResult](),
+		mcp.WithReadOnlyHintAnnotation(true),
 		mcp.WithString("symbolName",
 			mcp.Required(),
-			mcp.Description("The name of the symbol whose callers you want to find (e.g. 'mypackage.MyFunction', 'MyType.MyMethod')"),
+			mcp.Description("The name of the symbol whose callers you want to find, qualified as much as needed (e.g. 'mypackage.MyFunction', 'MyType.MyMethod', 'crate::module::Type::method', 'ns::Class::method')"),
 		),
+		withSymbolFilters(),
+		withCallHierarchyOptions(),
 	)
-	s.mcpServer.AddTool(callersTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	s.addTool(callersTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		// Extract arguments
-		symbolName, err := request.RequireString("symbolName")
+		query, err := symbolQuery(request)
 		if err != nil {
 			return mcp.NewToolResultError(err.Error()), nil
 		}
 
-		coreLogger.Debug("Executing callers for symbol: %s", symbolName)
-		text, err := tools.GetCallers(s.ctx, s.lspClient, symbolName, 1)
+		coreLogger.Debug("Executing callers for symbol: %s", query.Name)
+		result, err := tools.GetCallersResult(s.callContext(ctx), s.lspClient, query, callHierarchyOptions(request))
 		if err != nil {
 			coreLogger.Error("Failed to find callers: %v", err)
 			return mcp.NewToolResultError(fmt.Sprintf("failed to find callers: %v", err)), nil
 		}
-		return mcp.NewToolResultText(text), nil
+		return s.toolResult(request, result), nil
 	})
 
 	calleesTool := mcp.NewTool("callees",
 		mcp.WithDescription("Resolve which functions a given symbol calls. Returns a list of the called functions and their locations."),
+		mcp.WithOutputSchema[tools.CallHierarchyResult](),
+		mcp.WithReadOnlyHintAnnotation(true),
 		mcp.WithString("symbolName",
 			mcp.Required(),
-			mcp.Description("The name of the symbol whose callees you want to find (e.g. 'mypackage.MyFunction', 'MyType.MyMethod')"),
+			mcp.Description("The name of the symbol whose callees you want to find, qualified as much as needed (e.g. 'mypackage.MyFunction', 'MyType.MyMethod', 'crate::module::Type::method', 'ns::Class::method')"),
 		),
+		withSymbolFilters(),
+		withCallHierarchyOptions(),
 	)
-	s.mcpServer.AddTool(calleesTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	s.addTool(calleesTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		// Extract arguments
-		symbolName, err := request.RequireString("symbolName")
+		query, err := symbolQuery(request)
 		if err != nil {
 			return mcp.NewToolResultError(err.Error()), nil
 		}
 
-		coreLogger.Debug("Executing callees for symbol: %s", symbolName)
-		text, err := tools.GetCallees(s.ctx, s.lspClient, symbolName, 1)
+		coreLogger.Debug("Executing callees for symbol: %s", query.Name)
+		result, err := tools.GetCalleesResult(s.callContext(ctx), s.lspClient, query, callHierarchyOptions(request))
 		if err != nil {
 			coreLogger.Error("Failed to find callees: %v", err)
 			return mcp.NewToolResultError(fmt.Sprintf("failed to find callees: %v", err)), nil
 		}
-		return mcp.NewToolResultText(text), nil
+		return s.toolResult(request, result), nil
+	})
+
+	impactAnalysisTool := mcp.NewTool("impact_analysis",
+		mcp.WithDescription("Find what a change to a symbol may break before making it. Combines the references, callers over several levels, implementations and subtypes of the symbol into the affected files, packages and test files, grouped by their distance from the symbol."),
+		mcp.WithOutputSchema[tools.ImpactResult](),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithString("symbolName",
+			mcp.Required(),
+			mcp.Description("The name of the symbol you plan to change, qualified as much as needed (e.g. 'mypackage.MyFunction', 'MyType.MyMethod', 'crate::module::Type::method', 'ns::Class::method')"),
+		),
+		withSymbolFilters(),
+		mcp.WithNumber("depth",
+			mcp.Description(fmt.Sprintf("How many levels of callers and subtypes to follow, up to %d", tools.MaxCallHierarchyDepth)),
+			mcp.DefaultNumber(3),
+		),
+	)
+	s.addTool(impactAnalysisTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract arguments
+		query, err := symbolQuery(request)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		coreLogger.Debug("Executing impact_analysis for symbol: %s", query.Name)
+		result, err := tools.AnalyzeImpactResult(s.callContext(ctx), s.lspClient, query, request.GetInt("depth", 3))
+		if err != nil {
+			coreLogger.Error("Failed to analyze impact: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to analyze impact: %v", err)), nil
+		}
+		return s.toolResult(request, result), nil
+	})
+
+	relatedTestsTool := mcp.NewTool("related_tests",
+		mcp.WithDescription("Find the tests that exercise a symbol by following its callers until they reach test functions (Go TestXxx, pytest test_*, Rust #[test], Jest and Vitest it/test blocks). Returns each test, the chain of calls leading to the symbol, and the commands that run exactly those tests."),
+		mcp.WithOutputSchema[tools.RelatedTestsResult](),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithString("symbolName",
+			mcp.Required(),
+			mcp.Description("The name of the symbol whose tests you want to find, qualified as much as needed (e.g. 'mypackage.MyFunction', 'MyType.MyMethod', 'crate::module::Type::method', 'ns::Class::method')"),
+		),
+		withSymbolFilters(),
+		mcp.WithNumber("depth",
+			mcp.Description(fmt.Sprintf("How many levels of callers to follow, up to %d", tools.MaxCallHierarchyDepth)),
+			mcp.DefaultNumber(5),
+		),
+	)
+	s.addTool(relatedTestsTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract arguments
+		query, err := symbolQuery(request)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		coreLogger.Debug("Executing related_tests for symbol: %s", query.Name)
+		result, err := tools.FindRelatedTestsResult(s.callContext(ctx), s.lspClient, query, request.GetInt("depth", 5))
+		if err != nil {
+			coreLogger.Error("Failed to find related tests: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to find related tests: %v", err)), nil
+		}
+		return s.toolResult(request, result), nil
 	})
 
 	contentTool := mcp.NewTool("content",
 		mcp.WithDescription("Read the source code definition of a symbol (function, type, constant, etc.) at the specified location."),
+		mcp.WithOutputSchema[tools.ContentResult](),
+		mcp.WithReadOnlyHintAnnotation(true),
 		mcp.WithString("filePath",
 			mcp.Required(),
-			mcp.Description("The path to the file"),
+			mcp.Description("The path to the file, absolute or relative to the workspace"),
 		),
 		mcp.WithNumber("line",
 			mcp.Required(),
@@ -398,7 +764,7 @@ func (s *mcpServer) registerTools() error {
 		),
 	)
 
-	s.mcpServer.AddTool(contentTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	s.addTool(contentTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		// Extract arguments
 		filePath, err := request.RequireString("filePath")
 		if err != nil {
@@ -416,14 +782,198 @@ func (s *mcpServer) registerTools() error {
 		}
 
 		coreLogger.Debug("Executing content for file: %s line: %d column: %d", filePath, line, column)
-		text, err := tools.GetContentInfo(s.ctx, s.lspClient, filePath, line, column)
+		result, err := tools.GetContentInfoResult(s.callContext(ctx), s.lspClient, filePath, line, column)
 		if err != nil {
 			coreLogger.Error("Failed to get content information: %v", err)
 			return mcp.NewToolResultError(fmt.Sprintf("failed to get content: %v", err)), nil
 		}
-		return mcp.NewToolResultText(text), nil
+		return s.toolResult(request, result), nil
+	})
+
+	listCommandsTool := mcp.NewTool("list_commands",
+		mcp.WithDescription("List the commands the language server can run through execute_command (e.g. 'gopls.tidy', 'rust-analyzer.expandMacro')."),
+		mcp.WithOutputSchema[tools.CommandsResult](),
+		withCursor(),
+		mcp.WithReadOnlyHintAnnotation(true),
+	)
+
+	s.addTool(listCommandsTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		coreLogger.Debug("Executing list_commands")
+		return s.toolResult(request, tools.ListCommandsResult(s.lspClient)), nil
+	})
+
+	executeCommandTool := mcp.NewTool("execute_command",
+		mcp.WithDescription("Run a language server command (see list_commands). Returns the command result along with any workspace edits and messages the server sent while running it."),
+		mcp.WithOutputSchema[tools.CommandResult](),
+		mcp.WithString("command",
+			mcp.Required(),
+			mcp.Description("The command to execute, as listed by list_commands"),
+		),
+		mcp.WithArray("arguments",
+			mcp.Description("JSON arguments to pass to the command"),
+		),
+	)
+
+	s.addTool(executeCommandTool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract arguments
+		command, err := request.RequireString("command")
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		var arguments []json.RawMessage
+		if argsArg, ok := request.GetArguments()["arguments"]; ok && argsArg != nil {
+			argsArray, ok := argsArg.([]any)
+			if !ok {
+				return mcp.NewToolResultError("arguments must be an array"), nil
+			}
+			for _, arg := range argsArray {
+				raw, err := json.Marshal(arg)
+				if err != nil {
+					return mcp.NewToolResultError(fmt.Sprintf("invalid argument: %v", err)), nil
+				}
+				arguments = append(arguments, raw)
+			}
+		}
+
+		coreLogger.Debug("Executing execute_command for command: %s", command)
+		result, err := tools.ExecuteCommandResult(s.callContext(ctx), s.lspClient, command, arguments)
+		if err != nil {
+			coreLogger.Error("Failed to execute command: %v", err)
+			return mcp.NewToolResultError(fmt.Sprintf("failed to execute command: %v", err)), nil
+		}
+		return s.toolResult(request, result), nil
 	})
 
 	coreLogger.Info("Successfully registered all MCP tools")
 	return nil
 }
+
+// addTool registers a tool that returns a structured result. Every such tool
+// takes an outputFormat and an output budget, which are checked before the
+// handler runs so that a bad value never leaves an edit half reported.
+func (s *mcpServer) addTool(tool mcp.Tool, handler server.ToolHandlerFunc) {
+	mcp.WithString("outputFormat",
+		mcp.Enum("text", "json"),
+		mcp.Description("Format of the text content: 'text' (default) for readable output, or 'json' for the same structured result that is returned as structured content"),
+	)(&tool)
+	mcp.WithNumber("maxTokens",
+		mcp.Description(fmt.Sprintf("Budget for the output in tokens, estimated at 4 characters each (default %d, 0 for no limit). Longer output is split into pages or truncated", s.config.maxOutputTokens)),
+	)(&tool)
+
+	s.mcpServer.AddTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		switch format := request.GetString("outputFormat", "text"); format {
+		case "text", "json":
+		default:
+			return mcp.NewToolResultError(fmt.Sprintf("invalid outputFormat: %s (must be text or json)", format)), nil
+		}
+		if maxTokens := request.GetInt("maxTokens", 0); maxTokens < 0 {
+			return mcp.NewToolResultError(fmt.Sprintf("invalid maxTokens: %d (must be 0 or more)", maxTokens)), nil
+		}
+		if _, err := tools.ParseCursor(request.GetString("cursor", "")); err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		return handler(ctx, request)
+	})
+}
+
+// withCursor adds the cursor argument to tools whose results are split into pages
+func withCursor() mcp.ToolOption {
+	return mcp.WithString("cursor",
+		mcp.Description("The nextCursor of a previous response, to get the next page of a result that did not fit in the output budget"),
+	)
+}
+
+// withSymbolFilters adds the parameters that narrow down which symbols a
+// symbolName matches, and how to pick among them when it is ambiguous
+func withSymbolFilters() mcp.ToolOption {
+	return func(tool *mcp.Tool) {
+		mcp.WithString("kind",
+			mcp.Description("Only match symbols of this kind, such as 'function', 'method', 'struct' or 'class'"),
+		)(tool)
+		mcp.WithString("path",
+			mcp.Description("Only match symbols declared in this file or directory, absolute or relative to the workspace"),
+		)(tool)
+		withSymbolID()(tool)
+		mcp.WithBoolean("all",
+			mcp.Description("Use every symbol the name matches instead of listing the candidates when it is ambiguous"),
+		)(tool)
+	}
+}
+
+func withSymbolID() mcp.ToolOption {
+	return mcp.WithString("symbolId",
+		mcp.Description("The ID of one of the candidates listed by a previous call where symbolName was ambiguous"),
+	)
+}
+
+// symbolQuery parses the symbolName of a request along with its filters
+func symbolQuery(request mcp.CallToolRequest) (tools.SymbolQuery, error) {
+	symbolName, err := request.RequireString("symbolName")
+	if err != nil {
+		return tools.SymbolQuery{}, err
+	}
+	query := tools.ParseSymbolQuery(symbolName)
+	query.Kind = request.GetString("kind", "")
+	query.Path = request.GetString("path", "")
+	query.ID = request.GetString("symbolId", "")
+	query.All = request.GetBool("all", false)
+	return query, nil
+}
+
+func withCallHierarchyOptions() mcp.ToolOption {
+	return func(tool *mcp.Tool) {
+		mcp.WithNumber("depth",
+			mcp.Description(fmt.Sprintf("How many levels of calls to follow, up to %d. Functions already listed are not followed again.", tools.MaxCallHierarchyDepth)),
+			mcp.DefaultNumber(1),
+		)(tool)
+		mcp.WithBoolean("excludeExternal",
+			mcp.Description("Leave out functions in the standard library and in third-party dependencies"),
+		)(tool)
+		mcp.WithArray("exclude",
+			mcp.Description("Leave out functions in files matching any of these globs (e.g. '*_test.go', 'internal/generated/')"),
+			mcp.WithStringItems(),
+		)(tool)
+		mcp.WithBoolean("callSites",
+			mcp.Description("Show the code around each call, such as the arguments passed and how errors are handled"),
+			mcp.DefaultBool(true),
+		)(tool)
+		mcp.WithNumber("contextLines",
+			mcp.Description("Lines of code to show around each call site"),
+			mcp.DefaultNumber(2),
+		)(tool)
+		mcp.WithString("graphFormat",
+			mcp.Description("How to render the calls: an indented tree, a Graphviz DOT digraph, a Mermaid flowchart, or a JSON adjacency list"),
+			mcp.Enum(tools.CallGraphFormats...),
+			mcp.DefaultString(tools.CallGraphTree),
+		)(tool)
+	}
+}
+
+// callHierarchyOptions reads the options of the callers and callees tools
+func callHierarchyOptions(request mcp.CallToolRequest) tools.CallHierarchyOptions {
+	return tools.CallHierarchyOptions{
+		MaxDepth:        request.GetInt("depth", 1),
+		Exclude:         request.GetStringSlice("exclude", nil),
+		ExcludeExternal: request.GetBool("excludeExternal", false),
+		CallSites:       request.GetBool("callSites", true),
+		ContextLines:    max(request.GetInt("contextLines", 2), 0),
+		Format:          request.GetString("graphFormat", tools.CallGraphTree),
+	}
+}
+
+// toolResult returns a result with structured content, and text content that
+// is either the rendered result or its JSON, depending on outputFormat. Both
+// are limited to the output budget of the call.
+func (s *mcpServer) toolResult(request mcp.CallToolRequest, result tools.Result) *mcp.CallToolResult {
+	maxTokens := s.config.maxOutputTokens
+	if _, ok := request.GetArguments()["maxTokens"]; ok {
+		maxTokens = request.GetInt("maxTokens", maxTokens)
+	}
+
+	page, text, err := tools.Paginate(result, request.GetString("cursor", ""), maxTokens, request.GetString("outputFormat", "text"))
+	if err != nil {
+		return mcp.NewToolResultError(err.Error())
+	}
+	return mcp.NewToolResultStructured(page, text)
+}